@@ -0,0 +1,19 @@
+package cache
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func DeclareCacheCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Custom package cache commands",
+	}
+
+	cmd.AddCommand(
+		cacheListCommand(),
+		cachePruneCommand(),
+	)
+
+	return cmd
+}
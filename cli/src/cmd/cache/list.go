@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"cli/core/cache"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+)
+
+func cacheListCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List the cached custom packages",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			packageCache, err := cache.Default()
+			if err != nil {
+				return err
+			}
+
+			entries, err := packageCache.List()
+			if err != nil {
+				return err
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "KEY\tKIND\tSOURCE\tREVISION\tSIZE\tFETCHED")
+			for _, entry := range entries {
+				revision := entry.Revision
+				if entry.Commit != "" {
+					revision = entry.Commit[:12]
+				}
+
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s ago\n",
+					entry.Key[:12],
+					entry.Kind,
+					entry.Source,
+					revision,
+					units.HumanSize(float64(entry.Size)),
+					units.HumanDuration(time.Since(entry.FetchedAt)),
+				)
+			}
+
+			err = tw.Flush()
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
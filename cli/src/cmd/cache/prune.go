@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"fmt"
+
+	"cli/core/cache"
+
+	"github.com/spf13/cobra"
+)
+
+func cachePruneCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove cached custom packages",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			olderThan, err := cmd.Flags().GetDuration("older-than")
+			if err != nil {
+				return err
+			}
+
+			packageCache, err := cache.Default()
+			if err != nil {
+				return err
+			}
+
+			pruned, err := packageCache.Prune(olderThan)
+			if err != nil {
+				return err
+			}
+
+			for _, entry := range pruned {
+				fmt.Println("> Removed", entry.Kind, entry.Source)
+			}
+			fmt.Println("> Pruned", len(pruned), "cached custom package(s)")
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Duration("older-than", 0, "Only remove entries that have not been fetched within this duration, eg. 720h (default removes everything)")
+
+	return cmd
+}
@@ -1,10 +1,14 @@
 package commands
 
 import (
+	"cli/cmd/cache"
 	"cli/cmd/completion"
+	"cli/cmd/config"
+	"cli/cmd/env"
 	"cli/cmd/pkg"
 	"cli/cmd/project"
 	"cli/cmd/version"
+	"cli/cmd/workspace"
 
 	"github.com/spf13/cobra"
 )
@@ -13,6 +17,10 @@ func AddCommands(cmd *cobra.Command) {
 	cmd.AddCommand(
 		pkg.DeclarePackageCommand(),
 		project.DeclareProjectCommand(),
+		workspace.DeclareWorkspaceCommand(),
+		cache.DeclareCacheCommand(),
+		config.DeclareConfigCommand(),
+		env.DeclareEnvCommand(),
 		completion.GenCompletionCommand(),
 		version.VersionCommand(),
 	)
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"cli/core/parse"
+	"cli/util/docker"
 
 	"github.com/luno/jettison/log"
 	"github.com/spf13/cobra"
@@ -46,6 +47,31 @@ func FlagCompletion(cmd *cobra.Command) {
 	})
 }
 
+// ServiceFlagCompletion completes the service flag with the services deployed in the stack of the
+// package selected with the name flag
+func ServiceFlagCompletion(cmd *cobra.Command) {
+	cmd.RegisterFlagCompletionFunc("service", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		name, err := cmd.Flags().GetString("name")
+		if err != nil || name == "" {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		cli, err := docker.NewDockerClient()
+		if err != nil {
+			log.Error(context.Background(), err)
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		defer cli.Close()
+
+		services, err := docker.ListStackServices(context.Background(), cli, name, false)
+		if err != nil {
+			log.Error(context.Background(), err)
+		}
+
+		return docker.ServiceNames(services, name), cobra.ShellCompDirectiveNoFileComp
+	})
+}
+
 func GenCompletionCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "completion",
@@ -1,7 +1,6 @@
 package completion
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -10,7 +9,6 @@ import (
 	execShell "cli/util/exec"
 	fileUtil "cli/util/file"
 
-	"github.com/luno/jettison/log"
 	"github.com/spf13/cobra"
 )
 
@@ -18,61 +16,55 @@ func genZshCompletionCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "zsh",
 		Short: "Generate the autocompletion script for zsh",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			var filename, binaryName string
 			switch runtime.GOOS {
 			case "linux":
 				output, err := execShell.Exec("zsh", "-c", "echo ${fpath[1]}")
 				if err != nil {
-					log.Error(context.Background(), err)
-					panic(err)
+					return err
 				}
 				filename = output + "/_instant-linux"
 				binaryName = "instant-linux"
 
 				err = os.Remove(filename)
 				if err != nil && !os.IsNotExist(err) {
-					log.Error(context.Background(), err)
-					panic(err)
+					return err
 				}
 			case "darwin":
 				output, err := execShell.Exec("zsh", "-c", "echo $(brew --prefix)")
 				if err != nil {
-					log.Error(context.Background(), err)
-					panic(err)
+					return err
 				}
 				filename = output + "/_instant-macos"
 				binaryName = "instant-macos"
 
 				err = os.Remove(filename)
 				if err != nil && !os.IsNotExist(err) {
-					log.Error(context.Background(), err)
-					panic(err)
+					return err
 				}
 			case "windows":
-				log.Error(context.Background(), errors.New("autocomplete not supported for windows powershell"))
-				panic("")
+				return errors.New("autocomplete not supported for windows powershell")
 			}
 
 			file, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR, 0777)
 			if err != nil {
-				log.Error(context.Background(), err)
-				panic(err)
+				return err
 			}
 
 			err = cmd.GenZshCompletion(file)
 			if err != nil {
-				log.Error(context.Background(), err)
-				panic(err)
+				return err
 			}
 
 			err = fileUtil.Sed(filename, "zsh", binaryName)
 			if err != nil {
-				log.Error(context.Background(), err)
-				panic(err)
+				return err
 			}
 
 			fmt.Println("Reload your shell session to begin using autocomplete!")
+
+			return nil
 		},
 	}
 
@@ -0,0 +1,19 @@
+package config
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func DeclareConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Config file commands",
+	}
+
+	cmd.AddCommand(
+		configValidateCommand(),
+		configShowCommand(),
+	)
+
+	return cmd
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"cli/cmd/flags"
+	"cli/core/parse"
+
+	"github.com/spf13/cobra"
+)
+
+func configShowCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "List the config files that are merged, or print the merged config with --resolved",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			resolved, err := cmd.Flags().GetBool("resolved")
+			if err != nil {
+				return err
+			}
+
+			document, err := parse.LoadConfigDocument(cmd)
+			if err != nil {
+				return err
+			}
+
+			if !resolved {
+				fmt.Println("Config files, in the order they are merged:")
+				for i, file := range document.Files {
+					fmt.Printf("  %d. %s\n", i+1, file)
+				}
+				return nil
+			}
+
+			data, err := document.Marshal()
+			if err != nil {
+				return err
+			}
+
+			_, err = os.Stdout.Write(data)
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	flags.SetConfigFlags(cmd)
+	cmd.Flags().StringSlice("env-file", nil, "Env file(s) to read the variables of the config file from")
+	cmd.Flags().Bool("resolved", false, "Print the config document resulting from merging the config files")
+
+	return cmd
+}
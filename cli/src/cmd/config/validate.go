@@ -0,0 +1,33 @@
+package config
+
+import (
+	"fmt"
+
+	"cli/cmd/flags"
+	"cli/core/parse"
+
+	"github.com/spf13/cobra"
+)
+
+func configValidateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check the config file for unknown keys, wrong types, duplicates and undefined profile packages",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := parse.ValidateConfigFile(cmd)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("> Config file is valid")
+
+			return nil
+		},
+	}
+
+	flags.SetConfigFlags(cmd)
+	cmd.Flags().StringSlice("env-file", nil, "Env file(s) to read the variables of the config file from")
+
+	return cmd
+}
@@ -0,0 +1,18 @@
+package env
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func DeclareEnvCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "Environment variable commands",
+	}
+
+	cmd.AddCommand(
+		envExplainCommand(),
+	)
+
+	return cmd
+}
@@ -0,0 +1,48 @@
+package env
+
+import (
+	"os"
+
+	"cli/cmd/completion"
+	"cli/cmd/flags"
+	"cli/core/env"
+	"cli/core/parse"
+
+	"github.com/spf13/cobra"
+)
+
+func envExplainCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain",
+		Short: "List the env vars passed to the deployment container, with the source each value comes from and the values it overrides",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := cmd.Flags().GetString("format")
+			if err != nil {
+				return err
+			}
+
+			config, err := parse.GetValidConfigFromParams(cmd)
+			if err != nil {
+				return err
+			}
+
+			envVars, err := parse.EnvVars(cmd, *config)
+			if err != nil {
+				return err
+			}
+
+			return env.Render(os.Stdout, envVars.Variables(), format)
+		},
+	}
+
+	cmdFlags := cmd.Flags()
+	cmdFlags.StringSliceP("profile", "p", nil, "The profile name(s) to load env vars from (defined in config.yml), later profiles taking precedence")
+	cmdFlags.StringSlice("env-file", nil, "env file")
+	cmdFlags.StringSliceP("env-var", "e", nil, "Env var(s) to set or overwrite")
+	cmdFlags.String("format", env.FormatTable, "The output format: table or json")
+	flags.SetConfigFlags(cmd)
+	completion.FlagCompletion(cmd)
+
+	return cmd
+}
@@ -1,8 +1,6 @@
 package flags
 
 import (
-	"cli/core/state"
-
 	"github.com/spf13/cobra"
 )
 
@@ -12,7 +10,7 @@ func SetPackageActionFlags(cmd *cobra.Command) {
 	setCommonActionFlags(cmd)
 
 	flags.StringSliceP("name", "n", nil, "The name(s) of the package(s)")
-	flags.StringP("profile", "p", "", "The profile name to load parameters from (defined in config.yml)")
+	flags.StringSliceP("profile", "p", nil, "The profile name(s) to load parameters from (defined in config.yml), later profiles taking precedence")
 }
 
 // disables certain flags for project level commands, but allows for usage
@@ -25,20 +23,57 @@ func SetProjectActionFlags(cmd *cobra.Command) {
 
 	// disabled flags
 	flags.StringSlice("name", nil, "")
-	flags.String("profile", "", "")
+	flags.StringSlice("profile", nil, "")
 
 	cmd.Flags().MarkHidden("name")
 	cmd.Flags().MarkHidden("profile")
 }
 
+// HideLaunchFlags hides the flags that only affect launching the deployment container, for
+// commands that only read the config file and package selection
+func HideLaunchFlags(cmd *cobra.Command) {
+	for _, name := range []string{"custom-path", "dev", "only", "env-var", "concurrency", "pull", "dry-run", "strict-env", "resolve"} {
+		cmd.Flags().MarkHidden(name)
+	}
+}
+
+// SetWorkspaceActionFlags adds the flags of the workspace commands running a project command for
+// each project, which take their config files from the workspace file
+func SetWorkspaceActionFlags(cmd *cobra.Command) {
+	setLaunchFlags(cmd)
+	SetWorkspaceFlags(cmd)
+	cmd.Flags().Bool("continue-on-error", false, "Run the command for the remaining projects when it fails for one (default is continueOnError of the workspace file)")
+}
+
+// SetWorkspaceFlags adds the flags selecting the workspace file and its projects
+func SetWorkspaceFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+
+	flags.String("workspace", "", "Workspace file (default is $INSTANT_WORKSPACE, or the nearest workspace.yaml or workspace.yml)")
+	flags.StringSlice("project", nil, "The name(s) of the workspace project(s) to run the command for (default all)")
+}
+
 func setCommonActionFlags(cmd *cobra.Command) {
+	setLaunchFlags(cmd)
+	SetConfigFlags(cmd)
+}
+
+func setLaunchFlags(cmd *cobra.Command) {
 	flags := cmd.Flags()
 
 	flags.StringSliceP("custom-path", "c", nil, "Path(s) to custom package(s)")
 	flags.BoolP("dev", "d", false, "For development related functionality (Passes `dev` as the second argument to your swarm file)")
 	flags.BoolP("only", "o", false, "Ignore package dependencies")
-	flags.StringSliceVar(&state.EnvFiles, "env-file", nil, "env file")
-	flags.StringVar(&state.ConfigFile, "config", "", "config file (default is $WORKING_DIR/config.yaml)")
+	flags.StringSlice("env-file", nil, "env file")
 	flags.StringSliceP("env-var", "e", nil, "Env var(s) to set or overwrite")
-	flags.StringP("concurrency", "", "", "The concurrency level to use for executing actions on packages (default 5)")
+	flags.StringP("concurrency", "", "", "The concurrency level to use for fetching custom packages and executing actions on packages (default 5)")
+	flags.String("pull", "missing", "When to pull the config image: always, missing or never")
+	flags.Bool("dry-run", false, "Print the deployment plan without launching the deployment container")
+	flags.Bool("strict-env", false, "Fail instead of warning about env vars that none of the selected packages declares")
+	flags.Bool("resolve", false, "With --dry-run or plan, read the package metadata from the config image and custom packages to print the deployment order")
+}
+
+// SetConfigFlags adds the flag selecting the config files of a command
+func SetConfigFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("config", nil, "Config file(s), merged in order (default is $INSTANT_CONFIG, or the nearest config.yaml, config.yml, config.json or config.toml)")
 }
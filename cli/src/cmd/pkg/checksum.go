@@ -0,0 +1,58 @@
+package pkg
+
+import (
+	"fmt"
+
+	"cli/core"
+	"cli/core/cache"
+	"cli/core/parse"
+	"cli/util/file"
+
+	"github.com/spf13/cobra"
+)
+
+func packageChecksumCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "checksum <archive-url-or-path>",
+		Short: "Compute the sha256 and integrity values of a zip or tar custom package",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			algorithm, err := cmd.Flags().GetString("algorithm")
+			if err != nil {
+				return err
+			}
+
+			archivePath := args[0]
+			if parse.GetCustomPackageSource(core.CustomPackage{Path: args[0]}).Kind == parse.SourceHTTP {
+				packageCache, err := cache.Default()
+				if err != nil {
+					return err
+				}
+
+				archivePath, err = packageCache.FetchHTTP(cmd.Context(), args[0])
+				if err != nil {
+					return err
+				}
+			}
+
+			sha256, err := file.Sha256(archivePath)
+			if err != nil {
+				return err
+			}
+
+			integrity, err := file.Integrity(archivePath, algorithm)
+			if err != nil {
+				return err
+			}
+
+			fmt.Println("sha256:   ", sha256)
+			fmt.Println("integrity:", integrity)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("algorithm", "sha256", "The integrity hash algorithm (sha256, sha384 or sha512)")
+
+	return cmd
+}
@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"os"
+
+	"cli/cmd/completion"
+	"cli/cmd/flags"
+	"cli/core/exitcode"
+	"cli/util/docker"
+
+	"github.com/luno/jettison/errors"
+	"github.com/spf13/cobra"
+)
+
+func packageExecCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "exec -n PACKAGE [--service SERVICE] COMMAND [ARG...]",
+		Short: "Run a command in a running container of a package service",
+		Long: `Run a command in the container of a running task of a package service on the node of the Docker
+daemon, as docker exec does, and exit with its exit code. The service may be omitted if the package
+has a single service. Flags after the command are passed to it.`,
+		Example: `  instant package exec -n database-postgres --service postgres-1 -it psql -U postgres
+  instant package exec -n mongo -- mongosh --eval 'db.stats()'`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			interactive, err := cmd.Flags().GetBool("interactive")
+			if err != nil {
+				return err
+			}
+			tty, err := cmd.Flags().GetBool("tty")
+			if err != nil {
+				return err
+			}
+
+			return execInService(cmd, docker.ExecOptions{Cmd: args, Interactive: interactive, TTY: tty})
+		},
+	}
+
+	setExecFlags(cmd)
+	cmd.Flags().BoolP("interactive", "i", false, "Keep stdin open and attach it to the command")
+	cmd.Flags().BoolP("tty", "t", false, "Allocate a pseudo-TTY")
+	// Everything after the command is passed to it, eg. psql -U postgres
+	cmd.Flags().SetInterspersed(false)
+
+	return cmd
+}
+
+// setExecFlags adds the flags selecting the package service commands are run in
+func setExecFlags(cmd *cobra.Command) {
+	flags.SetConfigFlags(cmd)
+	cmd.Flags().StringP("name", "n", "", "The name of the package")
+	cmd.Flags().StringP("service", "s", "", "The service of the package, with or without the package prefix (default the only service of the package)")
+	cmd.Flags().StringP("user", "u", "", "The user to run the command as, eg. root or 1000:1000")
+	cmd.MarkFlagRequired("name")
+	completion.FlagCompletion(cmd)
+	completion.ServiceFlagCompletion(cmd)
+}
+
+// execInService runs a command in the container of a running task of the package service selected
+// with the name and service flags, terminating the CLI with its exit code if it fails
+func execInService(cmd *cobra.Command, opts docker.ExecOptions) error {
+	name, err := cmd.Flags().GetString("name")
+	if err != nil {
+		return err
+	}
+	serviceName, err := cmd.Flags().GetString("service")
+	if err != nil {
+		return err
+	}
+	opts.User, err = cmd.Flags().GetString("user")
+	if err != nil {
+		return err
+	}
+	opts.Stdin, opts.Stdout, opts.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	service, err := docker.FindService(cmd.Context(), cli, name, serviceName)
+	if err != nil {
+		return err
+	}
+
+	containerID, err := docker.LocalTaskContainer(cmd.Context(), cli, service)
+	if err != nil {
+		return err
+	}
+
+	exitCode, err := docker.Exec(cmd.Context(), cli, containerID, opts)
+	if err != nil {
+		return errors.Wrap(err, "exec in "+service.Spec.Name)
+	}
+	if exitCode != 0 {
+		return exitcode.Exit(exitCode)
+	}
+
+	return nil
+}
@@ -1,14 +1,12 @@
 package pkg
 
 import (
-	"context"
 	"os"
 	"path"
 
 	"cli/core/generate"
 	"cli/core/prompt"
 
-	"github.com/luno/jettison/log"
 	"github.com/spf13/cobra"
 )
 
@@ -17,34 +15,30 @@ func packageGenerateCommand() *cobra.Command {
 		Use:     "generate",
 		Aliases: []string{"g"},
 		Short:   "Generate a new package",
-		Run: func(cmd *cobra.Command, args []string) {
-			ctx := context.Background()
-
+		RunE: func(cmd *cobra.Command, args []string) error {
 			resp, err := prompt.GeneratePackagePrompt()
 			if err != nil {
-				log.Error(ctx, err)
-				panic(err)
+				return err
 			}
 
 			// Get the current working directory
 			cwd, err := os.Getwd()
 			if err != nil {
-				log.Error(ctx, err)
-				panic(err)
+				return err
 			}
 
 			packagePath := path.Join(cwd, resp.Id)
 			err = os.Mkdir(packagePath, os.ModePerm)
 			if err != nil {
-				log.Error(ctx, err)
-				panic(err)
+				return err
 			}
 
 			err = generate.GeneratePackage(packagePath, resp)
 			if err != nil {
-				log.Error(ctx, err)
-				panic(err)
+				return err
 			}
+
+			return nil
 		},
 	}
 
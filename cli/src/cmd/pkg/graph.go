@@ -0,0 +1,79 @@
+package pkg
+
+import (
+	"io"
+	"os"
+
+	"cli/cmd/completion"
+	"cli/cmd/flags"
+	"cli/core/dependency"
+	"cli/core/deploy"
+	"cli/core/exitcode"
+	"cli/core/parse"
+
+	"github.com/luno/jettison/errors"
+	"github.com/spf13/cobra"
+)
+
+func packageGraphCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Render the dependency graph of packages as Graphviz DOT, Mermaid or JSON",
+		Long: `Render the dependency graph of the selected packages and their dependencies, or of every package
+in the config image and custom packages if none are selected. Package metadata is read from the
+local config image, which is pulled according to --pull.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := cmd.Flags().GetString("format")
+			if err != nil {
+				return err
+			}
+			if format != dependency.FormatDot && format != dependency.FormatMermaid && format != dependency.FormatJSON {
+				return exitcode.New(exitcode.ValidationFailed, errors.Wrap(dependency.ErrUnknownFormat, format), dependency.FormatHint)
+			}
+
+			packageSpec, config, err := parse.ParseLaunch(cmd)
+			if err != nil {
+				return err
+			}
+			packageSpec.DeployCommand = "up"
+			packageSpec.IsOnly = false
+
+			// Progress is written to stderr, so that the graph can be piped from stdout
+			err = parse.EnsureImage(cmd, *config, os.Stderr)
+			if err != nil {
+				return err
+			}
+
+			graph, ids, err := deploy.ResolvePackages(cmd.Context(), os.Stderr, packageSpec, config)
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = os.Stdout
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				return err
+			}
+			if output != "" {
+				outputFile, err := os.Create(output)
+				if err != nil {
+					return errors.Wrap(err, "")
+				}
+				defer outputFile.Close()
+				w = outputFile
+			}
+
+			return graph.Render(w, ids, format)
+		},
+	}
+
+	flags.SetPackageActionFlags(cmd)
+	for _, name := range []string{"dev", "only", "env-file", "env-var", "dry-run", "strict-env", "resolve"} {
+		cmd.Flags().MarkHidden(name)
+	}
+	cmd.Flags().String("format", dependency.FormatDot, "The output format: dot, mermaid or json")
+	cmd.Flags().String("output", "", "Write the graph to a file instead of stdout")
+	completion.FlagCompletion(cmd)
+
+	return cmd
+}
@@ -0,0 +1,107 @@
+package pkg
+
+import (
+	"os"
+	"regexp"
+
+	"cli/cmd/completion"
+	"cli/cmd/flags"
+	"cli/core/exitcode"
+	"cli/core/parse"
+	"cli/util/docker"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/luno/jettison/errors"
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+)
+
+func packageLogsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Show the logs of every service of packages",
+		Long: `Show the logs of every swarm service in the stacks of the selected packages, interleaved line by
+line and prefixed with the task they come from, eg. openhim-core_openhim-core.1, and their timestamp.`,
+		Example: "  instant package logs -n interoperability-layer-openhim --follow --since 10m --grep error",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := getLogsOptions(cmd)
+			if err != nil {
+				return err
+			}
+
+			packageSpec, _, err := parse.ParseLaunch(cmd)
+			if err != nil {
+				return err
+			}
+
+			if len(packageSpec.Packages) < 1 {
+				return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrNoPackages, ""), "Select packages with --name, or with a profile using --profile")
+			}
+
+			cli, err := docker.NewDockerClient()
+			if err != nil {
+				return err
+			}
+			defer cli.Close()
+
+			var services []swarm.Service
+			for _, id := range packageSpec.Packages {
+				stackServices, err := docker.ListStackServices(cmd.Context(), cli, id, false)
+				if err != nil {
+					return err
+				}
+				if len(stackServices) == 0 {
+					return exitcode.New(exitcode.ValidationFailed, errors.Wrap(docker.ErrStackNotFound, id), docker.StackNotFoundHint)
+				}
+				services = append(services, stackServices...)
+			}
+
+			return docker.ServiceLogs(cmd.Context(), cli, services, os.Stdout, opts)
+		},
+	}
+
+	flags.SetPackageActionFlags(cmd)
+	flags.HideLaunchFlags(cmd)
+	cmd.Flags().BoolP("follow", "f", false, "Follow the logs")
+	cmd.Flags().String("since", "", "Show logs since a timestamp (eg. 2026-10-01T12:00:00Z) or a relative time (eg. 10m)")
+	cmd.Flags().String("tail", "all", "Number of lines to show from the end of the logs of each service")
+	cmd.Flags().String("grep", "", "Only show the lines matching a regular expression")
+	completion.FlagCompletion(cmd)
+
+	return cmd
+}
+
+func getLogsOptions(cmd *cobra.Command) (docker.LogsOptions, error) {
+	var opts docker.LogsOptions
+	var err error
+
+	opts.Follow, err = cmd.Flags().GetBool("follow")
+	if err != nil {
+		return opts, err
+	}
+	opts.Since, err = cmd.Flags().GetString("since")
+	if err != nil {
+		return opts, err
+	}
+	opts.Tail, err = cmd.Flags().GetString("tail")
+	if err != nil {
+		return opts, err
+	}
+
+	grep, err := cmd.Flags().GetString("grep")
+	if err != nil {
+		return opts, err
+	}
+	if grep != "" {
+		opts.Grep, err = regexp.Compile(grep)
+		if err != nil {
+			return opts, exitcode.New(exitcode.ValidationFailed, errors.Wrap(err, ""), "Pass a regular expression in Go syntax to --grep")
+		}
+	}
+
+	_, isTerminal := term.GetFdInfo(os.Stdout)
+	opts.Colour = isTerminal && os.Getenv("NO_COLOR") == ""
+
+	return opts, nil
+}
@@ -19,6 +19,14 @@ func DeclarePackageCommand() *cobra.Command {
 		packageDownCommand(),
 		packageRemoveCommand(),
 		packageGenerateCommand(),
+		packageChecksumCommand(),
+		packageGraphCommand(),
+		packageStatusCommand(),
+		packageLogsCommand(),
+		packageExecCommand(),
+		packageShellCommand(),
+		packageUpdateCommand(),
+		packageRestartCommand(),
 	)
 
 	return cmd
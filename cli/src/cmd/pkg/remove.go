@@ -1,14 +1,13 @@
 package pkg
 
 import (
-	"context"
-
 	"cli/cmd/completion"
 	"cli/cmd/flags"
 	"cli/core/deploy"
+	"cli/core/exitcode"
 	"cli/core/parse"
 
-	"github.com/luno/jettison/log"
+	"github.com/luno/jettison/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -17,24 +16,23 @@ func packageRemoveCommand() *cobra.Command {
 		Use:     "remove",
 		Aliases: []string{"r", "destroy"},
 		Short:   "Remove everything related to a package (volumes, configs, etc)",
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
 			packageSpec, config, err := parse.ParseAndPrepareLaunch(cmd)
 			if err != nil {
-				log.Error(context.Background(), err)
-				panic(err)
+				return err
 			}
 			packageSpec.DeployCommand = "destroy"
 
 			if len(packageSpec.Packages) < 1 && len(packageSpec.CustomPackages) < 1 {
-				log.Error(context.Background(), ErrNoPackages)
-				panic(err)
+				return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrNoPackages, ""), "Select packages with --name, or with a profile using --profile")
 			}
 
-			err = deploy.LaunchDeploymentContainer(packageSpec, config)
+			err = deploy.Launch(cmd.Context(), packageSpec, config)
 			if err != nil {
-				log.Error(context.Background(), err)
-				panic(err)
+				return err
 			}
+
+			return nil
 		},
 	}
 
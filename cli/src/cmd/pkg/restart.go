@@ -0,0 +1,80 @@
+package pkg
+
+import (
+	"cli/cmd/completion"
+	"cli/core/exitcode"
+	"cli/util/docker"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/luno/jettison/errors"
+	"github.com/spf13/cobra"
+)
+
+func packageRestartCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restart",
+		Short: "Restart the services of a package with a rolling update",
+		Long: `Restart the services of a package, or the ones selected with --service, by forcing a rolling
+update of their tasks, eg. after changing a config or secret they read at startup. The package
+scripts are not run again. Services are restarted one after the other, as described for 'package
+update'.`,
+		Example: "  instant package restart -n interoperability-layer-openhim --service openhim-core",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, err := cmd.Flags().GetString("name")
+			if err != nil {
+				return err
+			}
+			serviceNames, err := cmd.Flags().GetStringSlice("service")
+			if err != nil {
+				return err
+			}
+
+			opts, err := getUpdateOptions(cmd)
+			if err != nil {
+				return err
+			}
+
+			cli, err := docker.NewDockerClient()
+			if err != nil {
+				return err
+			}
+			defer cli.Close()
+
+			var services []swarm.Service
+			if len(serviceNames) == 0 {
+				services, err = docker.ListStackServices(cmd.Context(), cli, name, false)
+				if err != nil {
+					return err
+				}
+				if len(services) == 0 {
+					return exitcode.New(exitcode.ValidationFailed, errors.Wrap(docker.ErrStackNotFound, name), docker.StackNotFoundHint)
+				}
+			}
+			for _, serviceName := range serviceNames {
+				service, err := docker.FindService(cmd.Context(), cli, name, serviceName)
+				if err != nil {
+					return err
+				}
+				services = append(services, service)
+			}
+
+			for _, service := range services {
+				err = docker.UpdateService(cmd.Context(), cli, service, func(spec *swarm.ServiceSpec) {
+					spec.TaskTemplate.ForceUpdate++
+				}, opts)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+
+	setUpdateFlags(cmd)
+	cmd.Flags().StringSliceP("service", "s", nil, "The service(s) of the package to restart, with or without the package prefix (default all)")
+	completion.ServiceFlagCompletion(cmd)
+
+	return cmd
+}
@@ -0,0 +1,44 @@
+package pkg
+
+import (
+	"os"
+
+	"cli/util/docker"
+
+	"github.com/moby/term"
+	"github.com/spf13/cobra"
+)
+
+// defaultShell runs bash if the image has it, and sh otherwise
+var defaultShell = []string{"/bin/sh", "-c", "if command -v bash >/dev/null 2>&1; then exec bash; else exec sh; fi"}
+
+func packageShellCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shell",
+		Short: "Open an interactive shell in a running container of a package service",
+		Long: `Open an interactive shell in the container of a running task of a package service on the node of
+the Docker daemon. The shell is bash if the image has it, and sh otherwise, unless one is passed
+with --shell.`,
+		Example: "  instant package shell -n database-postgres --service postgres-1",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			shell, err := cmd.Flags().GetString("shell")
+			if err != nil {
+				return err
+			}
+
+			opts := docker.ExecOptions{Cmd: defaultShell, Interactive: true}
+			if shell != "" {
+				opts.Cmd = []string{shell}
+			}
+			_, opts.TTY = term.GetFdInfo(os.Stdin)
+
+			return execInService(cmd, opts)
+		},
+	}
+
+	setExecFlags(cmd)
+	cmd.Flags().String("shell", "", "The shell to run, eg. /bin/ash")
+
+	return cmd
+}
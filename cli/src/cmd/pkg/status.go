@@ -0,0 +1,49 @@
+package pkg
+
+import (
+	"os"
+
+	"cli/cmd/completion"
+	"cli/cmd/flags"
+	"cli/core/exitcode"
+	"cli/core/parse"
+	"cli/core/status"
+
+	"github.com/luno/jettison/errors"
+	"github.com/spf13/cobra"
+)
+
+func packageStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the state of the swarm services of packages",
+		Long: `Show the desired and running replicas, image, digest and last update of the swarm services of
+the selected packages, and the tasks that failed or are unhealthy. Packages are deployed as stacks
+named after their id. Exits with code 7 if any package is degraded or not deployed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, err := cmd.Flags().GetString("format")
+			if err != nil {
+				return err
+			}
+
+			packageSpec, _, err := parse.ParseLaunch(cmd)
+			if err != nil {
+				return err
+			}
+
+			if len(packageSpec.Packages) < 1 {
+				return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrNoPackages, ""), "Select packages with --name, or with a profile using --profile")
+			}
+
+			return status.Report(cmd.Context(), os.Stdout, packageSpec.Packages, format)
+		},
+	}
+
+	flags.SetPackageActionFlags(cmd)
+	flags.HideLaunchFlags(cmd)
+	cmd.Flags().String("format", status.FormatTable, "The output format: table or json")
+	completion.FlagCompletion(cmd)
+
+	return cmd
+}
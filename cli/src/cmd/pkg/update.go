@@ -0,0 +1,127 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"cli/cmd/completion"
+	"cli/cmd/flags"
+	"cli/core/exitcode"
+	"cli/util/docker"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/luno/jettison/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ErrNoImages             = errors.New("no images to update services to")
+	ErrInvalidImageOverride = errors.New("invalid image override")
+)
+
+func packageUpdateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update",
+		Short: "Roll package services to new images",
+		Long: `Roll services of a package to new images with a rolling update, without running the package
+scripts again. Swarm rolls out the update following the update_config of each service, and the
+command waits for every service to converge before updating the next. A service whose update is
+paused by failing tasks, or doesn't complete within --timeout, is rolled back to its previous spec
+unless --rollback=false is passed.`,
+		Example: "  instant package update -n database-postgres --image postgres-1=postgres:16.4 --timeout 10m",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, err := cmd.Flags().GetString("name")
+			if err != nil {
+				return err
+			}
+			images, err := cmd.Flags().GetStringSlice("image")
+			if err != nil {
+				return err
+			}
+			if len(images) == 0 {
+				return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrNoImages, ""), "Pass the new image of a service with --image service=repo:tag")
+			}
+
+			opts, err := getUpdateOptions(cmd)
+			if err != nil {
+				return err
+			}
+			opts.ResolveImage = true
+
+			cli, err := docker.NewDockerClient()
+			if err != nil {
+				return err
+			}
+			defer cli.Close()
+
+			type override struct {
+				service swarm.Service
+				image   string
+			}
+			var overrides []override
+			for _, image := range images {
+				serviceName, ref, ok := strings.Cut(image, "=")
+				if !ok || serviceName == "" || ref == "" {
+					return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrInvalidImageOverride, image), "Pass images as --image service=repo:tag")
+				}
+
+				service, err := docker.FindService(cmd.Context(), cli, name, serviceName)
+				if err != nil {
+					return err
+				}
+				overrides = append(overrides, override{service: service, image: ref})
+			}
+
+			for _, o := range overrides {
+				if spec := o.service.Spec.TaskTemplate.ContainerSpec; spec != nil && strings.Split(spec.Image, "@")[0] == o.image {
+					fmt.Printf("> %s already runs %s\n", o.service.Spec.Name, o.image)
+					continue
+				}
+
+				err = docker.UpdateService(cmd.Context(), cli, o.service, func(spec *swarm.ServiceSpec) {
+					spec.TaskTemplate.ContainerSpec.Image = o.image
+				}, opts)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+
+	setUpdateFlags(cmd)
+	cmd.Flags().StringSlice("image", nil, "The new image of a service, as service=repo:tag (eg. postgres-1=postgres:16.4)")
+
+	return cmd
+}
+
+// setUpdateFlags adds the flags selecting the package whose services are updated, and how updates
+// are waited for
+func setUpdateFlags(cmd *cobra.Command) {
+	flags.SetConfigFlags(cmd)
+	cmd.Flags().StringP("name", "n", "", "The name of the package")
+	cmd.Flags().Duration("timeout", 5*time.Minute, "How long to wait for the update of each service to complete")
+	cmd.Flags().Bool("rollback", true, "Roll a service back to its previous spec if its update fails or times out")
+	cmd.MarkFlagRequired("name")
+	completion.FlagCompletion(cmd)
+}
+
+func getUpdateOptions(cmd *cobra.Command) (docker.UpdateOptions, error) {
+	opts := docker.UpdateOptions{Out: os.Stdout}
+	var err error
+
+	opts.Timeout, err = cmd.Flags().GetDuration("timeout")
+	if err != nil {
+		return opts, err
+	}
+	opts.Rollback, err = cmd.Flags().GetBool("rollback")
+	if err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
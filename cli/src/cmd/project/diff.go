@@ -0,0 +1,95 @@
+package project
+
+import (
+	"os"
+
+	pFlags "cli/cmd/flags"
+	"cli/core/dependency"
+	"cli/core/deploy"
+	"cli/core/drift"
+	"cli/core/exitcode"
+	"cli/core/parse"
+	"cli/util/slice"
+
+	"github.com/luno/jettison/errors"
+	"github.com/spf13/cobra"
+)
+
+func projectDiffCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare the compose files of every package in the project with its deployed services",
+		Long: `Compare the image, env vars, replicas, ports, mounts, networks and labels of the services in the
+compose files of every package in the project, interpolated with the env vars of the project, with
+the specs of the deployed services, and print a unified diff for every service that drifted. The
+compose files are layered as the packages deploy them: docker-compose.yml, the --compose-file
+overlays the package has, then docker-compose.dev.yml with --dev. Exits with code 2 if any service
+drifted.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := checkInvalidFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			packageSpec, config, err := parse.ParseLaunch(cmd)
+			if err != nil {
+				return err
+			}
+			packageSpec.CustomPackages = config.CustomPackages
+
+			ids := append([]string{}, config.Packages...)
+			for _, customPackage := range config.CustomPackages {
+				if !slice.SliceContains(ids, customPackage.Id) {
+					ids = append(ids, customPackage.Id)
+				}
+			}
+
+			stagingDir, err := os.MkdirTemp("", "instant-diff-")
+			if err != nil {
+				return errors.Wrap(err, "")
+			}
+			defer os.RemoveAll(stagingDir)
+
+			stagedPackages, err := deploy.StagePackages(cmd.Context(), os.Stderr, packageSpec, config, stagingDir)
+			if err != nil {
+				return err
+			}
+
+			// Custom packages come last to replace image packages with the same id
+			staged := make(map[string]deploy.StagedPackage)
+			for _, pack := range stagedPackages {
+				staged[pack.Metadata.Id] = pack
+			}
+
+			var packages []drift.Package
+			for _, id := range ids {
+				pack, ok := staged[id]
+				if !ok {
+					return exitcode.New(exitcode.ValidationFailed, errors.Wrap(dependency.ErrUnknownPackage, id), dependency.UnknownPackageHint)
+				}
+				packages = append(packages, drift.Package{
+					Id:          id,
+					Dir:         pack.Dir,
+					DeployedDir: pack.DeployedDir,
+					Environment: drift.Environment(pack.Metadata, packageSpec.EnvironmentVariables),
+				})
+			}
+
+			overlays, err := cmd.Flags().GetStringSlice("compose-file")
+			if err != nil {
+				return errors.Wrap(err, "")
+			}
+
+			return drift.Report(cmd.Context(), os.Stdout, packages, drift.Options{Overlays: overlays, Dev: packageSpec.IsDev})
+		},
+	}
+
+	pFlags.SetProjectActionFlags(cmd)
+	cmd.Flags().StringSlice("compose-file", nil, "Compose file(s) the packages that have them layer onto docker-compose.yml, in order, eg. docker-compose.cluster.yml")
+	for _, name := range []string{"only", "pull", "dry-run", "strict-env", "resolve"} {
+		cmd.Flags().MarkHidden(name)
+	}
+
+	return cmd
+}
@@ -1,13 +1,10 @@
 package project
 
 import (
-	"context"
-
 	pFlags "cli/cmd/flags"
 	"cli/core/deploy"
 	"cli/core/parse"
 
-	"github.com/luno/jettison/log"
 	"github.com/spf13/cobra"
 )
 
@@ -16,28 +13,25 @@ func projectDownCommand() *cobra.Command {
 		Use:     "down",
 		Aliases: []string{"d"},
 		Short:   "Down all packages in the project",
-		Run: func(cmd *cobra.Command, args []string) {
-			ctx := context.Background()
-
+		RunE: func(cmd *cobra.Command, args []string) error {
 			err := checkInvalidFlags(cmd)
 			if err != nil {
-				log.Error(ctx, err)
-				panic(err)
+				return err
 			}
 
 			packageSpec, config, err := parse.ParseAndPrepareLaunch(cmd)
 			if err != nil {
-				log.Error(ctx, err)
-				panic(err)
+				return err
 			}
 			packageSpec.Packages = config.Packages
 			packageSpec.CustomPackages = config.CustomPackages
 
-			err = deploy.LaunchDeploymentContainer(packageSpec, config)
+			err = deploy.Launch(cmd.Context(), packageSpec, config)
 			if err != nil {
-				log.Error(ctx, err)
-				panic(err)
+				return err
 			}
+
+			return nil
 		},
 	}
 
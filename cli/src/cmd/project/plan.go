@@ -0,0 +1,50 @@
+package project
+
+import (
+	"os"
+
+	pFlags "cli/cmd/flags"
+	"cli/core/deploy"
+	"cli/core/parse"
+
+	"github.com/spf13/cobra"
+)
+
+func projectPlanCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:       "plan [init|up|down|destroy]",
+		Short:     "Print what a project level command would do without launching it (default up)",
+		Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+		ValidArgs: []string{"init", "up", "down", "destroy"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := checkInvalidFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			packageSpec, config, err := parse.ParseLaunch(cmd)
+			if err != nil {
+				return err
+			}
+			packageSpec.Packages = config.Packages
+			packageSpec.CustomPackages = config.CustomPackages
+
+			packageSpec.DeployCommand = "up"
+			if len(args) > 0 {
+				packageSpec.DeployCommand = args[0]
+			}
+
+			err = deploy.PreviewDeployment(cmd.Context(), os.Stdout, packageSpec, config)
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	pFlags.SetProjectActionFlags(cmd)
+	cmd.Flags().MarkHidden("dry-run")
+
+	return cmd
+}
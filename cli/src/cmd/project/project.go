@@ -1,16 +1,19 @@
 package project
 
 import (
+	"cli/core/exitcode"
+
 	"github.com/luno/jettison/errors"
 	"github.com/spf13/cobra"
 )
 
+var ErrPackageLevelFlag = errors.New("flag is only supported by package level commands")
+
 func checkInvalidFlags(cmd *cobra.Command) error {
-	if cmd.Flag("name").Changed {
-		return errors.Wrap(errors.New("flag accessed but not defined: name"), "")
-	}
-	if cmd.Flag("profile").Changed {
-		return errors.Wrap(errors.New("flag accessed but not defined: profile"), "")
+	for _, name := range []string{"name", "profile"} {
+		if cmd.Flag(name).Changed {
+			return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrPackageLevelFlag, name), "Use the 'package' command to deploy selected packages")
+		}
 	}
 
 	return nil
@@ -27,8 +30,30 @@ func DeclareProjectCommand() *cobra.Command {
 		projectDownCommand(),
 		projectUpCommand(),
 		projectDestroyCommand(),
+		projectPlanCommand(),
 		projectGenerateCommand(),
+		projectStatusCommand(),
+		projectDiffCommand(),
 	)
 
 	return cmd
 }
+
+// ActionCommand returns a new project command for action, one of init, up, down, destroy and
+// status, eg. to run it for each project of a workspace
+func ActionCommand(action string) (*cobra.Command, bool) {
+	switch action {
+	case "init":
+		return projectInitCommand(), true
+	case "up":
+		return projectUpCommand(), true
+	case "down":
+		return projectDownCommand(), true
+	case "destroy":
+		return projectDestroyCommand(), true
+	case "status":
+		return projectStatusCommand(), true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,54 @@
+package project
+
+import (
+	"os"
+
+	pFlags "cli/cmd/flags"
+	"cli/core/parse"
+	"cli/core/status"
+	"cli/util/slice"
+
+	"github.com/spf13/cobra"
+)
+
+func projectStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the state of the swarm services of every package in the project",
+		Long: `Show the desired and running replicas, image, digest and last update of the swarm services of
+every package in the project, and the tasks that failed or are unhealthy. Packages are deployed as
+stacks named after their id. Exits with code 7 if any package is degraded or not deployed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := checkInvalidFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			format, err := cmd.Flags().GetString("format")
+			if err != nil {
+				return err
+			}
+
+			_, config, err := parse.ParseLaunch(cmd)
+			if err != nil {
+				return err
+			}
+
+			ids := append([]string{}, config.Packages...)
+			for _, customPackage := range config.CustomPackages {
+				if !slice.SliceContains(ids, customPackage.Id) {
+					ids = append(ids, customPackage.Id)
+				}
+			}
+
+			return status.Report(cmd.Context(), os.Stdout, ids, format)
+		},
+	}
+
+	pFlags.SetProjectActionFlags(cmd)
+	pFlags.HideLaunchFlags(cmd)
+	cmd.Flags().String("format", status.FormatTable, "The output format: table or json")
+
+	return cmd
+}
@@ -2,29 +2,58 @@ package cmd
 
 import (
 	"context"
+	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/luno/jettison/log"
 	"github.com/spf13/cobra"
 
 	"cli/cmd/commands"
+	"cli/core/exitcode"
 )
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "cli",
 	Short: "A cli to assist with package deployment and management",
+	// Errors are printed with their hint by Execute, and usage only for flag errors
+	SilenceErrors: true,
+	SilenceUsage:  true,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
-func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
-		log.Error(context.Background(), err)
-		panic(err)
+// It returns the exit code of the command.
+func Execute() int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Restore the default signal handling once interrupted, so that a second interrupt
+	// terminates immediately
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	if err == nil {
+		return exitcode.Success
+	}
+
+	exitErr := exitcode.Classify(err)
+	if ctx.Err() != nil {
+		exitErr = exitcode.New(exitcode.Interrupted, err, "")
 	}
+
+	exitcode.Print(os.Stderr, exitErr)
+
+	return exitErr.Code
 }
 
 func init() {
 	commands.AddCommands(rootCmd)
+
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return exitcode.New(exitcode.General, err, "Run '"+cmd.CommandPath()+" --help' for usage")
+	})
 }
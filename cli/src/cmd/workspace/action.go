@@ -0,0 +1,146 @@
+package workspace
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	pFlags "cli/cmd/flags"
+	"cli/cmd/project"
+	"cli/core/exitcode"
+	"cli/core/status"
+	"cli/core/workspace"
+
+	"github.com/luno/jettison/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// workspaceFlags are the flags of the workspace commands that aren't passed on to project commands
+var workspaceFlags = []string{"workspace", "project", "continue-on-error"}
+
+// actionCommand returns the project command run for each project, replaced in tests
+var actionCommand = project.ActionCommand
+
+func workspaceActionCommand(action, short string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   action,
+		Short: short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, projects, err := loadWorkspace(cmd)
+			if err != nil {
+				return err
+			}
+
+			continueOnError := ws.ContinueOnError
+			if cmd.Flags().Changed("continue-on-error") {
+				continueOnError, err = cmd.Flags().GetBool("continue-on-error")
+				if err != nil {
+					return err
+				}
+			}
+
+			// Tear projects down in the reverse order they are brought up in
+			if action == "down" || action == "destroy" {
+				projects = slices.Clone(projects)
+				slices.Reverse(projects)
+			}
+
+			return runProjects(cmd, action, ws, projects, continueOnError)
+		},
+	}
+
+	pFlags.SetWorkspaceActionFlags(cmd)
+
+	return cmd
+}
+
+// runProjects runs the project command for action for each of projects in order. It stops at the
+// first project that fails, unless continueOnError is set, in which case it returns
+// workspace.ErrProjectsFailed listing the projects that failed once it ran for all of them.
+func runProjects(cmd *cobra.Command, action string, ws *workspace.Workspace, projects []workspace.Project, continueOnError bool) error {
+	var failed []string
+	allDegraded := true
+	for _, p := range projects {
+		fmt.Printf("> Project %s: %s\n", p.Name, action)
+
+		err := runProjectCommand(cmd, action, ws, p)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, context.Canceled) || !continueOnError {
+			return errors.Wrap(err, "project "+p.Name)
+		}
+
+		fmt.Printf("> Project %s failed: %v\n", p.Name, err)
+		failed = append(failed, p.Name)
+		allDegraded = allDegraded && errors.Is(err, status.ErrDegraded)
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	fmt.Printf("> %d of %d project(s) failed: %s\n", len(failed), len(projects), strings.Join(failed, ", "))
+	// Keep the exit code of status checks for monitoring
+	if allDegraded {
+		return exitcode.New(exitcode.Degraded, errors.Wrap(status.ErrDegraded, "projects "+strings.Join(failed, ", ")), status.DegradedHint)
+	}
+
+	return exitcode.New(exitcode.DeploymentFailed, errors.Wrap(workspace.ErrProjectsFailed, strings.Join(failed, ", ")), "Check the output of the failed projects above")
+}
+
+// runProjectCommand runs the project command for action with the config files of p, passing on the
+// flags set on the workspace command cmd
+func runProjectCommand(cmd *cobra.Command, action string, ws *workspace.Workspace, p workspace.Project) error {
+	configFiles, err := ws.ConfigFiles(p)
+	if err != nil {
+		return err
+	}
+
+	projectCmd, ok := actionCommand(action)
+	if !ok {
+		return errors.New("no project command for " + action)
+	}
+	projectCmd.SetContext(cmd.Context())
+
+	err = passFlags(cmd, projectCmd, configFiles)
+	if err != nil {
+		return err
+	}
+
+	return projectCmd.RunE(projectCmd, nil)
+}
+
+// passFlags sets the flags of the workspace command cmd that were set on the command line on the
+// project command projectCmd, if it has them, and sets its --config flag to configFiles
+func passFlags(cmd, projectCmd *cobra.Command, configFiles []string) error {
+	var err error
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		projectFlag := projectCmd.Flags().Lookup(flag.Name)
+		if err != nil || projectFlag == nil || slices.Contains(workspaceFlags, flag.Name) {
+			return
+		}
+
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			err = projectFlag.Value.(pflag.SliceValue).Replace(slice.GetSlice())
+		} else {
+			err = projectFlag.Value.Set(flag.Value.String())
+		}
+		projectFlag.Changed = true
+	})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	configFlag := projectCmd.Flags().Lookup("config")
+	err = configFlag.Value.(pflag.SliceValue).Replace(configFiles)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	configFlag.Changed = true
+
+	return nil
+}
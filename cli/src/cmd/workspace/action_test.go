@@ -0,0 +1,95 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	pFlags "cli/cmd/flags"
+	"cli/core/workspace"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+// projectFlags are the flags a project command was run with
+type projectFlags struct {
+	Config         []string
+	EnvFile        []string
+	EnvFileChanged bool
+	EnvVar         []string
+	Dev            bool
+}
+
+func Test_runProjects(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"hie/config.yml", "lab/config.yml", "lab/prod.yml"} {
+		jtest.RequireNil(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0o755))
+		jtest.RequireNil(t, os.WriteFile(filepath.Join(dir, file), nil, 0o644))
+	}
+	ws := &workspace.Workspace{
+		File: filepath.Join(dir, "workspace.yaml"),
+		Projects: []workspace.Project{
+			{Name: "hie", Path: "hie"},
+			{Name: "lab", Path: "lab", Config: []string{"config.yml", "prod.yml"}},
+		},
+	}
+	hieConfig := []string{filepath.Join(dir, "hie", "config.yml")}
+	labConfig := []string{filepath.Join(dir, "lab", "config.yml"), filepath.Join(dir, "lab", "prod.yml")}
+
+	received := make(map[string]projectFlags)
+	defer func(original func(string) (*cobra.Command, bool)) { actionCommand = original }(actionCommand)
+	actionCommand = func(action string) (*cobra.Command, bool) {
+		cmd := &cobra.Command{
+			Use: action,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				var flags projectFlags
+				flags.Config, _ = cmd.Flags().GetStringSlice("config")
+				flags.EnvFile, _ = cmd.Flags().GetStringSlice("env-file")
+				flags.EnvFileChanged = cmd.Flags().Changed("env-file")
+				flags.EnvVar, _ = cmd.Flags().GetStringSlice("env-var")
+				flags.Dev, _ = cmd.Flags().GetBool("dev")
+				received[filepath.Base(filepath.Dir(flags.Config[0]))] = flags
+				return nil
+			},
+		}
+		pFlags.SetProjectActionFlags(cmd)
+		return cmd, true
+	}
+
+	type cases struct {
+		args             []string
+		expectedReceived map[string]projectFlags
+	}
+
+	testCases := []cases{
+		// case: flags set on the workspace command are passed to every project
+		{
+			args: []string{"--env-file", "prod.env,secrets.env", "-e", "POSTGRES_REPLICAS=3", "--dev", "--continue-on-error"},
+			expectedReceived: map[string]projectFlags{
+				"hie": {Config: hieConfig, EnvFile: []string{"prod.env", "secrets.env"}, EnvFileChanged: true, EnvVar: []string{"POSTGRES_REPLICAS=3"}, Dev: true},
+				"lab": {Config: labConfig, EnvFile: []string{"prod.env", "secrets.env"}, EnvFileChanged: true, EnvVar: []string{"POSTGRES_REPLICAS=3"}, Dev: true},
+			},
+		},
+		// case: flags that aren't set are left to the defaults of the project commands
+		{
+			expectedReceived: map[string]projectFlags{
+				"hie": {Config: hieConfig, EnvFile: []string{}, EnvVar: []string{}},
+				"lab": {Config: labConfig, EnvFile: []string{}, EnvVar: []string{}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		clear(received)
+
+		cmd := workspaceActionCommand("up", "Bring up every project")
+		jtest.RequireNil(t, cmd.ParseFlags(tc.args))
+
+		err := runProjects(cmd, "up", ws, ws.Projects, false)
+		jtest.RequireNil(t, err)
+
+		require.Equal(t, tc.expectedReceived, received)
+	}
+}
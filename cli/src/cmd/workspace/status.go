@@ -0,0 +1,32 @@
+package workspace
+
+import (
+	pFlags "cli/cmd/flags"
+	"cli/core/status"
+
+	"github.com/spf13/cobra"
+)
+
+func workspaceStatusCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show the state of the swarm services of every package in each project of the workspace",
+		Long: `Run 'project status' for each project of the workspace, including the projects after one that is
+degraded. Exits with code 7 if any package is degraded or not deployed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ws, projects, err := loadWorkspace(cmd)
+			if err != nil {
+				return err
+			}
+
+			return runProjects(cmd, "status", ws, projects, true)
+		},
+	}
+
+	pFlags.SetWorkspaceFlags(cmd)
+	cmd.Flags().StringSlice("env-file", nil, "Env file(s) to read the variables of the config files from")
+	cmd.Flags().String("format", status.FormatTable, "The output format: table or json")
+
+	return cmd
+}
@@ -0,0 +1,56 @@
+package workspace
+
+import (
+	"cli/core/workspace"
+
+	"github.com/luno/jettison/errors"
+	"github.com/spf13/cobra"
+)
+
+func DeclareWorkspaceCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "workspace",
+		Short: "Commands running project level commands for several projects",
+	}
+
+	cmd.AddCommand(
+		workspaceActionCommand("init", "Initialize all packages in each project of the workspace"),
+		workspaceActionCommand("up", "Up all packages in each project of the workspace"),
+		workspaceActionCommand("down", "Down all packages in each project of the workspace, in reverse order"),
+		workspaceActionCommand("destroy", "Destroy all packages in each project of the workspace, in reverse order"),
+		workspaceStatusCommand(),
+	)
+
+	return cmd
+}
+
+// loadWorkspace reads the workspace file selected by the --workspace flag of cmd, or else the one
+// found by workspace.Find, and returns the projects selected by the --project flag
+func loadWorkspace(cmd *cobra.Command) (*workspace.Workspace, []workspace.Project, error) {
+	file, err := cmd.Flags().GetString("workspace")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "")
+	}
+	if file == "" {
+		file, err = workspace.Find()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	ws, err := workspace.Load(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names, err := cmd.Flags().GetStringSlice("project")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "")
+	}
+	projects, err := ws.Select(names)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ws, projects, nil
+}
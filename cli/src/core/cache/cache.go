@@ -0,0 +1,365 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"cli/util/git"
+
+	"github.com/luno/jettison/errors"
+)
+
+// Kind is the type of source a cache entry was fetched from
+type Kind string
+
+const (
+	KindHTTP Kind = "http"
+	KindGit  Kind = "git"
+
+	entryFileName   = "entry.json"
+	contentFileName = "content"
+)
+
+var ErrUnexpectedStatus = errors.New("unexpected HTTP status code when downloading custom package")
+
+// Entry describes a single cached custom package source
+type Entry struct {
+	Key          string    `json:"key"`
+	Kind         Kind      `json:"kind"`
+	Source       string    `json:"source"`
+	Revision     string    `json:"revision,omitempty"`
+	Commit       string    `json:"commit,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+	Size         int64     `json:"-"`
+}
+
+// Cache is a persistent store of downloaded and cloned custom packages, keyed by source and revision
+type Cache struct {
+	Dir        string
+	HTTPClient *http.Client
+}
+
+func New(dir string) *Cache {
+	return &Cache{
+		Dir:        dir,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Default returns the cache located under the user cache directory
+func Default() (*Cache, error) {
+	userCacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	return New(filepath.Join(userCacheDir, "instant", "custom-packages")), nil
+}
+
+// Key returns the content-addressed key of a source at a revision
+func Key(kind Kind, source, revision string) string {
+	sum := sha256.Sum256([]byte(string(kind) + "\x00" + source + "\x00" + revision))
+	return hex.EncodeToString(sum[:16])
+}
+
+func (c *Cache) entryDir(key string) string {
+	return filepath.Join(c.Dir, key)
+}
+
+// FetchHTTP returns the path of the cached download of url, making a conditional request to only
+// download it again if it changed. A stale copy is used if the request fails. Fetches of the same
+// url are serialized; the download is swapped in whole, so the returned path can be read while
+// url is fetched again.
+func (c *Cache) FetchHTTP(ctx context.Context, url string) (string, error) {
+	key := Key(KindHTTP, url, "")
+	contentPath := filepath.Join(c.entryDir(key), contentFileName)
+
+	unlock, err := c.lock(key)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	entry, err := c.readEntry(key)
+	if err != nil {
+		return "", err
+	}
+	if entry != nil {
+		if _, err := os.Stat(contentPath); err != nil {
+			entry = nil
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		if entry != nil {
+			fmt.Println("> Failed to check", url, "for changes, using cached copy:", err)
+			return contentPath, nil
+		}
+		return "", errors.Wrap(err, "")
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		entry.FetchedAt = time.Now()
+		err = c.writeEntry(entry)
+		if err != nil {
+			return "", err
+		}
+
+		return contentPath, nil
+
+	case resp.StatusCode != http.StatusOK:
+		return "", errors.Wrap(ErrUnexpectedStatus, fmt.Sprintf("%s: %d", url, resp.StatusCode))
+	}
+
+	err = os.MkdirAll(c.entryDir(key), os.ModePerm)
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+
+	// Download next to the cached copy and swap it in once complete, so that an interrupted
+	// download never leaves a partial file behind
+	tmpFile, err := os.CreateTemp(c.entryDir(key), contentFileName+"-*")
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = io.Copy(tmpFile, resp.Body)
+	if err != nil {
+		tmpFile.Close()
+		return "", errors.Wrap(err, "")
+	}
+	err = tmpFile.Close()
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+
+	err = os.Rename(tmpFile.Name(), contentPath)
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+
+	err = c.writeEntry(&Entry{
+		Key:          key,
+		Kind:         KindHTTP,
+		Source:       url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return contentPath, nil
+}
+
+// FetchGit calls use with the path of the cached clone of url checked out at ref, cloning it on
+// first use and incrementally fetching it afterwards. A stale clone is used if fetching fails. The
+// clone is checked out in place, so the entry stays locked until use returns.
+func (c *Cache) FetchGit(url, ref string, auth git.Auth, use func(clonePath string) error) error {
+	key := Key(KindGit, url, ref)
+	contentPath := filepath.Join(c.entryDir(key), contentFileName)
+
+	unlock, err := c.lock(key)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entry, err := c.readEntry(key)
+	if err != nil {
+		return err
+	}
+
+	if entry != nil {
+		err = git.UpdateRepo(contentPath, ref, auth)
+		if err != nil {
+			fmt.Println("> Failed to fetch", url+", using cached clone:", err)
+		}
+	} else {
+		err = os.RemoveAll(c.entryDir(key))
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+
+		err = git.Clone(url, contentPath, ref, auth)
+		if err != nil {
+			os.RemoveAll(c.entryDir(key))
+			return err
+		}
+
+		entry = &Entry{
+			Key:      key,
+			Kind:     KindGit,
+			Source:   url,
+			Revision: ref,
+		}
+	}
+
+	commit, err := git.HeadCommit(contentPath)
+	if err != nil {
+		return err
+	}
+
+	entry.Commit = commit
+	entry.FetchedAt = time.Now()
+	err = c.writeEntry(entry)
+	if err != nil {
+		return err
+	}
+
+	return use(contentPath)
+}
+
+// List returns every entry in the cache, most recently fetched first
+func (c *Cache) List() ([]Entry, error) {
+	dirEntries, err := os.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	var entries []Entry
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() {
+			continue
+		}
+
+		entry, err := c.readEntry(dirEntry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+
+		entry.Size, err = dirSize(c.entryDir(entry.Key))
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, *entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].FetchedAt.After(entries[j].FetchedAt)
+	})
+
+	return entries, nil
+}
+
+// Prune removes the entries that have not been fetched within olderThan, or every entry if
+// olderThan is zero, and returns the removed entries
+func (c *Cache) Prune(olderThan time.Duration) ([]Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []Entry
+	for _, entry := range entries {
+		if olderThan > 0 && time.Since(entry.FetchedAt) < olderThan {
+			continue
+		}
+
+		// Wait for fetches of the entry to finish, leaving its lock file for fetches waiting on it
+		unlock, err := c.lock(entry.Key)
+		if err != nil {
+			return nil, err
+		}
+		err = os.RemoveAll(c.entryDir(entry.Key))
+		unlock()
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+
+		pruned = append(pruned, entry)
+	}
+
+	return pruned, nil
+}
+
+func (c *Cache) readEntry(key string) (*Entry, error) {
+	data, err := os.ReadFile(filepath.Join(c.entryDir(key), entryFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	var entry Entry
+	err = json.Unmarshal(data, &entry)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	return &entry, nil
+}
+
+func (c *Cache) writeEntry(entry *Entry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	err = os.WriteFile(filepath.Join(c.entryDir(entry.Key), entryFileName), data, 0644)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	return nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "")
+	}
+
+	return size, nil
+}
@@ -0,0 +1,283 @@
+package cache
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+
+	gitutil "cli/util/git"
+)
+
+func TestCache_FetchHTTP(t *testing.T) {
+	content := "first version"
+	etag := `"v1"`
+	var notModified int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.zip" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	packageCache := New(t.TempDir())
+	ctx := context.Background()
+
+	type cases struct {
+		url                 string
+		hookFunc            func()
+		expectedContent     string
+		expectedNotModified int
+		expectedErrorString string
+	}
+
+	testCases := []cases{
+		// case: first fetch downloads the archive
+		{
+			url:             server.URL + "/package.zip",
+			expectedContent: "first version",
+		},
+		// case: unchanged archive is revalidated and not downloaded again
+		{
+			url:                 server.URL + "/package.zip",
+			expectedContent:     "first version",
+			expectedNotModified: 1,
+		},
+		// case: changed archive is downloaded again
+		{
+			url: server.URL + "/package.zip",
+			hookFunc: func() {
+				content = "second version"
+				etag = `"v2"`
+			},
+			expectedContent:     "second version",
+			expectedNotModified: 1,
+		},
+		// case: unexpected status code
+		{
+			url:                 server.URL + "/missing.zip",
+			expectedNotModified: 1,
+			expectedErrorString: "404",
+		},
+	}
+
+	for _, tc := range testCases {
+		if tc.hookFunc != nil {
+			tc.hookFunc()
+		}
+
+		path, err := packageCache.FetchHTTP(ctx, tc.url)
+		if tc.expectedErrorString != "" {
+			require.ErrorContains(t, err, tc.expectedErrorString)
+		} else {
+			jtest.RequireNil(t, err)
+
+			data, err := os.ReadFile(path)
+			jtest.RequireNil(t, err)
+			require.Equal(t, tc.expectedContent, string(data))
+		}
+		require.Equal(t, tc.expectedNotModified, notModified)
+	}
+
+	// case: cached copy is used when the server is unreachable
+	server.Close()
+	path, err := packageCache.FetchHTTP(ctx, server.URL+"/package.zip")
+	jtest.RequireNil(t, err)
+
+	data, err := os.ReadFile(path)
+	jtest.RequireNil(t, err)
+	require.Equal(t, "second version", string(data))
+}
+
+func TestCache_FetchGit(t *testing.T) {
+	repoDir := t.TempDir()
+	repo, err := git.PlainInit(repoDir, false)
+	jtest.RequireNil(t, err)
+
+	commitFile := func(content string) string {
+		err := os.WriteFile(filepath.Join(repoDir, "swarm.sh"), []byte(content), 0644)
+		jtest.RequireNil(t, err)
+
+		worktree, err := repo.Worktree()
+		jtest.RequireNil(t, err)
+
+		_, err = worktree.Add("swarm.sh")
+		jtest.RequireNil(t, err)
+
+		hash, err := worktree.Commit(content, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+		})
+		jtest.RequireNil(t, err)
+
+		return hash.String()
+	}
+	firstCommit := commitFile("first version")
+
+	_, err = repo.CreateTag("v1.0.0", plumbing.NewHash(firstCommit), &git.CreateTagOptions{
+		Message: "v1.0.0",
+		Tagger:  &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+	})
+	jtest.RequireNil(t, err)
+
+	// Serve the repository from a bare clone, like a remote would
+	bareDir := t.TempDir()
+	_, err = git.PlainClone(bareDir, true, &git.CloneOptions{URL: repoDir})
+	jtest.RequireNil(t, err)
+
+	packageCache := New(t.TempDir())
+
+	// fetch reads swarm.sh from the clone of ref while the entry is locked
+	fetch := func(ref string) (string, string, error) {
+		var clonePath, content string
+		err := packageCache.FetchGit(bareDir, ref, gitutil.Auth{}, func(path string) error {
+			data, err := os.ReadFile(filepath.Join(path, "swarm.sh"))
+			clonePath, content = path, string(data)
+			return err
+		})
+		return clonePath, content, err
+	}
+
+	clonePath, content, err := fetch("")
+	jtest.RequireNil(t, err)
+	require.Equal(t, "first version", content)
+
+	// Push a new commit to the bare repository and fetch it incrementally
+	secondCommit := commitFile("second version")
+	bareRepo, err := git.PlainOpen(bareDir)
+	jtest.RequireNil(t, err)
+	err = bareRepo.Fetch(&git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"},
+	})
+	jtest.RequireNil(t, err)
+
+	updatedPath, content, err := fetch("")
+	jtest.RequireNil(t, err)
+	require.Equal(t, clonePath, updatedPath)
+	require.Equal(t, "second version", content)
+
+	entries, err := packageCache.List()
+	jtest.RequireNil(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, KindGit, entries[0].Kind)
+	require.Equal(t, bareDir, entries[0].Source)
+	require.Equal(t, secondCommit, entries[0].Commit)
+
+	type cases struct {
+		ref                 string
+		expectedContent     string
+		expectedErrorString string
+	}
+
+	testCases := []cases{
+		// case: tag ref
+		{ref: "v1.0.0", expectedContent: "first version"},
+		// case: branch ref
+		{ref: "master", expectedContent: "second version"},
+		// case: commit ref
+		{ref: firstCommit, expectedContent: "first version"},
+		// case: commit ref already checked out in the cache
+		{ref: firstCommit, expectedContent: "first version"},
+		// case: unknown ref
+		{ref: "v9.9.9", expectedErrorString: gitutil.ErrUnknownRef.Error()},
+	}
+
+	for _, tc := range testCases {
+		refPath, content, err := fetch(tc.ref)
+		if tc.expectedErrorString != "" {
+			require.ErrorContains(t, err, tc.expectedErrorString)
+			continue
+		}
+		jtest.RequireNil(t, err)
+		require.NotEqual(t, clonePath, refPath)
+		require.Equal(t, tc.expectedContent, content)
+	}
+}
+
+func TestCache_FetchHTTP_concurrent(t *testing.T) {
+	var downloads atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		downloads.Add(1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("first version"))
+	}))
+	defer server.Close()
+
+	packageCache := New(t.TempDir())
+
+	// Fetches of the same url wait for each other, so only the first one downloads it
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := packageCache.FetchHTTP(context.Background(), server.URL+"/package.zip")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		jtest.RequireNil(t, err)
+	}
+	require.Equal(t, int32(1), downloads.Load())
+}
+
+func TestCache_Prune(t *testing.T) {
+	packageCache := New(t.TempDir())
+
+	for _, entry := range []Entry{
+		{Key: "old", Kind: KindHTTP, Source: "https://old", FetchedAt: time.Now().Add(-48 * time.Hour)},
+		{Key: "new", Kind: KindHTTP, Source: "https://new", FetchedAt: time.Now()},
+	} {
+		err := os.MkdirAll(packageCache.entryDir(entry.Key), os.ModePerm)
+		jtest.RequireNil(t, err)
+
+		err = packageCache.writeEntry(&entry)
+		jtest.RequireNil(t, err)
+	}
+
+	pruned, err := packageCache.Prune(24 * time.Hour)
+	jtest.RequireNil(t, err)
+	require.Len(t, pruned, 1)
+	require.Equal(t, "https://old", pruned[0].Source)
+
+	pruned, err = packageCache.Prune(0)
+	jtest.RequireNil(t, err)
+	require.Len(t, pruned, 1)
+	require.Equal(t, "https://new", pruned[0].Source)
+
+	entries, err := packageCache.List()
+	jtest.RequireNil(t, err)
+	require.Empty(t, entries)
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/luno/jettison/errors"
+)
+
+// entryLocks holds a mutex per cache key, serializing the goroutines of this process on an entry.
+// File locks serialize separate processes.
+var entryLocks sync.Map
+
+// lock blocks until this goroutine holds the entry of key, both within this process and across
+// processes sharing the cache directory, and returns the func that releases it
+func (c *Cache) lock(key string) (func(), error) {
+	value, _ := entryLocks.LoadOrStore(filepath.Join(c.Dir, key), &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+
+	// Lock files are kept next to, rather than in, entry directories, as those are removed and
+	// replaced while locked
+	err := os.MkdirAll(c.Dir, os.ModePerm)
+	if err != nil {
+		mu.Unlock()
+		return nil, errors.Wrap(err, "")
+	}
+
+	f, err := os.OpenFile(filepath.Join(c.Dir, key+".lock"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		mu.Unlock()
+		return nil, errors.Wrap(err, "")
+	}
+
+	err = lockFile(f)
+	if err != nil {
+		f.Close()
+		mu.Unlock()
+		return nil, errors.Wrap(err, "")
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+		mu.Unlock()
+	}, nil
+}
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockFile(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "entry.lock")
+
+	// Separate opens of the lock file stand in for separate processes
+	first, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	jtest.RequireNil(t, err)
+	defer first.Close()
+	second, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	jtest.RequireNil(t, err)
+	defer second.Close()
+
+	jtest.RequireNil(t, lockFile(first))
+
+	locked := make(chan error)
+	go func() {
+		locked <- lockFile(second)
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("lock file locked twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	jtest.RequireNil(t, unlockFile(first))
+	require.NoError(t, <-locked)
+	jtest.RequireNil(t, unlockFile(second))
+}
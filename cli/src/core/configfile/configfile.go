@@ -0,0 +1,430 @@
+package configfile
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cli/core/cache"
+	"cli/core/exitcode"
+	"cli/core/interpolate"
+	"cli/core/schema"
+
+	"github.com/luno/jettison/errors"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrReadConfigLayer = errors.New("unable to read extended config file")
+	ErrExtendsCycle    = errors.New("config files extend each other")
+	ErrInvalidExtends  = errors.New("extends must be a list of config file paths or URLs")
+)
+
+// Document is a config document merged from one or more config files and the files they extend
+type Document struct {
+	Root *yaml.Node
+	// Files are the config files that were merged, in the order they were merged in
+	Files []string
+
+	files map[*yaml.Node]string
+}
+
+// FileOf returns the config file node was read from
+func (d *Document) FileOf(node *yaml.Node) string {
+	if file, ok := d.files[node]; ok {
+		return file
+	}
+
+	return d.Files[len(d.Files)-1]
+}
+
+// Marshal encodes the merged document as YAML
+func (d *Document) Marshal() ([]byte, error) {
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+
+	err := encoder.Encode(d.Root)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	err = encoder.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	return b.Bytes(), nil
+}
+
+// Interpolate replaces the variables in the values of the document with lookup, see
+// interpolate.String. Errors are prefixed with the position of the value.
+func (d *Document) Interpolate(lookup interpolate.Lookup) error {
+	visited := make(map[*yaml.Node]bool)
+
+	var walk func(node *yaml.Node) error
+	walk = func(node *yaml.Node) error {
+		// Nodes may be shared between documents merged from several files
+		if visited[node] {
+			return nil
+		}
+		visited[node] = true
+
+		switch node.Kind {
+		case yaml.ScalarNode:
+			value, err := interpolate.String(node.Value, lookup)
+			if err != nil {
+				var hint string
+				if errors.Is(err, interpolate.ErrRequiredVariable) {
+					hint = "Export the variable, or set it in a file passed with --env-file"
+				}
+				return exitcode.New(exitcode.ConfigInvalid, errors.Wrap(err, fmt.Sprintf("%s:%d:%d", d.FileOf(node), node.Line, node.Column)), hint)
+			}
+			if value != node.Value {
+				node.Value = value
+				// Let plain scalars resolve to the type of their new value, eg. dev: ${DEV:-false}
+				if node.Style == 0 {
+					node.Tag = ""
+				}
+			}
+
+		case yaml.MappingNode:
+			// Keys are not interpolated
+			for i := 1; i < len(node.Content); i += 2 {
+				err := walk(node.Content[i])
+				if err != nil {
+					return err
+				}
+			}
+
+		default:
+			for _, child := range node.Content {
+				err := walk(child)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	return walk(d.Root)
+}
+
+// Load reads the config files at locations, which are paths or http(s) URLs, and merges them in
+// order. The files a config file extends are merged before the file itself.
+func Load(ctx context.Context, locations []string) (*Document, error) {
+	packageCache, err := cache.Default()
+	if err != nil {
+		return nil, err
+	}
+
+	return load(ctx, locations, packageCache.FetchHTTP)
+}
+
+type loader struct {
+	ctx      context.Context
+	fetch    func(ctx context.Context, url string) (string, error)
+	document *Document
+	// stack holds the files being loaded, to detect files extending each other
+	stack []string
+}
+
+func load(ctx context.Context, locations []string, fetch func(context.Context, string) (string, error)) (*Document, error) {
+	l := &loader{
+		ctx:      ctx,
+		fetch:    fetch,
+		document: &Document{files: make(map[*yaml.Node]string)},
+	}
+
+	var root *yaml.Node
+	for _, location := range locations {
+		node, err := l.load(location)
+		if err != nil {
+			return nil, err
+		}
+		root = l.merge(root, node)
+	}
+	l.document.Root = root
+
+	return l.document, nil
+}
+
+func readConfigLayerError(detail string) error {
+	return exitcode.New(exitcode.ConfigInvalid, errors.Wrap(ErrReadConfigLayer, detail), "Check the paths and URLs in extends, relative paths are resolved from the extending config file")
+}
+
+func isURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+func (l *loader) load(location string) (*yaml.Node, error) {
+	for i, loading := range l.stack {
+		if loading == location {
+			cycle := append(append([]string{}, l.stack[i:]...), location)
+			for j := range cycle {
+				cycle[j] = displayPath(cycle[j])
+			}
+			return nil, exitcode.New(exitcode.ConfigInvalid, errors.Wrap(ErrExtendsCycle, strings.Join(cycle, " -> ")), "Remove one of the extends in the cycle")
+		}
+	}
+	l.stack = append(l.stack, location)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	filePath := location
+	if isURL(location) {
+		var err error
+		filePath, err = l.fetch(l.ctx, location)
+		if err != nil {
+			return nil, readConfigLayerError(location + ": " + err.Error())
+		}
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, readConfigLayerError(err.Error())
+	}
+
+	file := displayPath(location)
+	root, validationErr := schema.Parse(file, data)
+	if validationErr != nil {
+		return nil, exitcode.New(exitcode.ConfigInvalid, validationErr, schema.ValidationHint)
+	}
+	root = l.register(root, file)
+
+	var merged *yaml.Node
+	extendsKey, extends := mappingEntry(root, "extends")
+	if extends != nil {
+		if extends.Kind != yaml.SequenceNode {
+			return nil, exitcode.New(exitcode.ConfigInvalid, errors.Wrap(ErrInvalidExtends, fmt.Sprintf("%s:%d:%d", file, extends.Line, extends.Column)), "")
+		}
+
+		for _, extended := range extends.Content {
+			if extended.Kind != yaml.ScalarNode || extended.Value == "" {
+				return nil, exitcode.New(exitcode.ConfigInvalid, errors.Wrap(ErrInvalidExtends, fmt.Sprintf("%s:%d:%d", file, extended.Line, extended.Column)), "")
+			}
+
+			node, err := l.load(resolveLocation(location, extended.Value))
+			if err != nil {
+				return nil, err
+			}
+			merged = l.merge(merged, node)
+		}
+
+		root = l.without(root, extendsKey)
+	}
+	l.document.Files = append(l.document.Files, file)
+
+	return l.merge(merged, root), nil
+}
+
+// resolveLocation resolves an extended config file relative to the file extending it
+func resolveLocation(base, location string) string {
+	if isURL(location) || filepath.IsAbs(location) {
+		return location
+	}
+
+	if isURL(base) {
+		baseURL, err := url.Parse(base)
+		if err != nil {
+			return location
+		}
+		ref, err := url.Parse(location)
+		if err != nil {
+			return location
+		}
+		return baseURL.ResolveReference(ref).String()
+	}
+
+	return filepath.Join(filepath.Dir(base), location)
+}
+
+// register records file as the origin of every node in the tree of node. Aliases are replaced by
+// the nodes they refer to, so that merged documents never refer to anchors of another file.
+func (l *loader) register(node *yaml.Node, file string) *yaml.Node {
+	node = resolveAlias(node)
+	node.Anchor = ""
+	l.document.files[node] = file
+
+	for i, child := range node.Content {
+		node.Content[i] = l.register(child, file)
+	}
+
+	return node
+}
+
+// derive returns a copy of node holding content, attributed to the same file as node
+func (l *loader) derive(node *yaml.Node, content []*yaml.Node) *yaml.Node {
+	derived := *node
+	derived.Content = content
+	l.document.files[&derived] = l.document.files[node]
+
+	return &derived
+}
+
+// without returns a copy of the mapping node without the entry of key
+func (l *loader) without(node *yaml.Node, key *yaml.Node) *yaml.Node {
+	var content []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i] != key {
+			content = append(content, node.Content[i], node.Content[i+1])
+		}
+	}
+
+	return l.derive(node, content)
+}
+
+// merge merges overlay onto base:
+//   - packages are the union of both lists
+//   - customPackages are merged by id, with a custom package in overlay replacing the one in base
+//   - profiles are merged by name, with the keys of a profile in overlay replacing those in base
+//   - any other key in overlay replaces the one in base
+func (l *loader) merge(base, overlay *yaml.Node) *yaml.Node {
+	if base == nil {
+		return overlay
+	}
+
+	return l.mergeMapping(base, overlay, func(key string, baseValue, overlayValue *yaml.Node) *yaml.Node {
+		switch key {
+		case "packages":
+			return l.union(baseValue, overlayValue)
+		case "customPackages":
+			return l.mergeBy(baseValue, overlayValue, "id", func(_, overlayItem *yaml.Node) *yaml.Node {
+				return overlayItem
+			})
+		case "profiles":
+			return l.mergeBy(baseValue, overlayValue, "name", func(baseItem, overlayItem *yaml.Node) *yaml.Node {
+				return l.mergeMapping(baseItem, overlayItem, func(_ string, _, overlayValue *yaml.Node) *yaml.Node {
+					return overlayValue
+				})
+			})
+		}
+
+		return overlayValue
+	})
+}
+
+// mergeMapping merges the entries of overlay onto base, keeping the order of the keys in base and
+// appending new keys. Values of keys in both are merged with mergeValue.
+func (l *loader) mergeMapping(base, overlay *yaml.Node, mergeValue func(key string, baseValue, overlayValue *yaml.Node) *yaml.Node) *yaml.Node {
+	if base.Kind != yaml.MappingNode || overlay.Kind != yaml.MappingNode {
+		return overlay
+	}
+
+	content := append([]*yaml.Node{}, base.Content...)
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+
+		replaced := false
+		for j := 0; j+1 < len(content); j += 2 {
+			if content[j].Value == key.Value {
+				content[j], content[j+1] = key, mergeValue(key.Value, content[j+1], value)
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			content = append(content, key, value)
+		}
+	}
+
+	return l.derive(overlay, content)
+}
+
+// union appends the scalars of overlay that aren't in base to base
+func (l *loader) union(base, overlay *yaml.Node) *yaml.Node {
+	if base.Kind != yaml.SequenceNode || overlay.Kind != yaml.SequenceNode {
+		return overlay
+	}
+
+	content := append([]*yaml.Node{}, base.Content...)
+	for _, item := range overlay.Content {
+		found := false
+		for _, existing := range base.Content {
+			if existing.Kind == yaml.ScalarNode && item.Kind == yaml.ScalarNode && existing.Value == item.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			content = append(content, item)
+		}
+	}
+
+	return l.derive(overlay, content)
+}
+
+// mergeBy merges the mappings of overlay onto those of base with the same value of field, appending
+// the other mappings of overlay
+func (l *loader) mergeBy(base, overlay *yaml.Node, field string, mergeItem func(baseItem, overlayItem *yaml.Node) *yaml.Node) *yaml.Node {
+	if base.Kind != yaml.SequenceNode || overlay.Kind != yaml.SequenceNode {
+		return overlay
+	}
+
+	content := append([]*yaml.Node{}, base.Content...)
+	indexes := make(map[string]int)
+	for i, item := range base.Content {
+		if _, value := mappingEntry(item, field); value != nil && value.Value != "" {
+			indexes[value.Value] = i
+		}
+	}
+
+	for _, item := range overlay.Content {
+		_, value := mappingEntry(item, field)
+		if value != nil {
+			if i, ok := indexes[value.Value]; ok {
+				content[i] = mergeItem(content[i], item)
+				continue
+			}
+		}
+		content = append(content, item)
+	}
+
+	return l.derive(overlay, content)
+}
+
+// mappingEntry returns the key and value nodes of key in a mapping node
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+
+	return nil, nil
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+
+	return node
+}
+
+// displayPath returns path relative to the working directory if it is within it
+func displayPath(path string) string {
+	if isURL(path) {
+		return path
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+
+	return rel
+}
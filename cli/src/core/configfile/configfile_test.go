@@ -0,0 +1,260 @@
+package configfile
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cli/core/cache"
+	"cli/core/interpolate"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		filePath := filepath.Join(dir, name)
+		jtest.RequireNil(t, os.MkdirAll(filepath.Dir(filePath), os.ModePerm))
+		jtest.RequireNil(t, os.WriteFile(filePath, []byte(content), 0o644))
+	}
+}
+
+func TestLoad(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/configs/shared.yml":
+			w.Write([]byte("logPath: /var/log/instant\nextends: [profiles.yml]\n"))
+		case "/configs/profiles.yml":
+			w.Write([]byte("profiles:\n  - name: monitoring\n    packages: [monitoring]\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	fetch := cache.New(t.TempDir()).FetchHTTP
+
+	type cases struct {
+		files          map[string]string
+		locations      []string
+		expectedConfig string
+		expectedFiles  []string
+		expectedErr    error
+	}
+
+	testCases := []cases{
+		// case: merge semantics of extended files and overlays
+		{
+			files: map[string]string{
+				"base/config.yml": `projectName: platform
+image: jembi/platform:2.4.0
+packages: [core, client]
+customPackages:
+  - id: disi
+    path: ./disi
+    ref: v1
+profiles:
+  - name: dev
+    packages: [core, disi]
+    dev: true
+`,
+				"prod.yml": `extends: [base/config.yml]
+image: jembi/platform:2.5.0
+packages: [client, monitoring]
+customPackages:
+  - id: disi
+    path: https://example.org/disi.zip
+  - id: reports
+    path: ./reports
+profiles:
+  - name: dev
+    dev: false
+  - name: prod
+    packages: [core]
+`,
+				"local.yml": "logPath: /tmp/logs\n",
+			},
+			locations: []string{"prod.yml", "local.yml"},
+			expectedConfig: `projectName: platform
+image: jembi/platform:2.5.0
+packages: [core, client, monitoring]
+customPackages:
+  - id: disi
+    path: https://example.org/disi.zip
+  - id: reports
+    path: ./reports
+profiles:
+  - name: dev
+    packages: [core, disi]
+    dev: false
+  - name: prod
+    packages: [core]
+logPath: /tmp/logs
+`,
+			expectedFiles: []string{"base/config.yml", "prod.yml", "local.yml"},
+		},
+		// case: extended URLs resolve relative extends against the URL
+		{
+			files: map[string]string{
+				"config.yml": "extends: [" + server.URL + "/configs/shared.yml]\nimage: jembi/platform\n",
+			},
+			locations: []string{"config.yml"},
+			expectedConfig: `profiles:
+  - name: monitoring
+    packages: [monitoring]
+logPath: /var/log/instant
+image: jembi/platform
+`,
+			expectedFiles: []string{server.URL + "/configs/profiles.yml", server.URL + "/configs/shared.yml", "config.yml"},
+		},
+		// case: cycle
+		{
+			files: map[string]string{
+				"a.yml": "extends: [b.yml]\n",
+				"b.yml": "extends: [a.yml]\n",
+			},
+			locations:   []string{"a.yml"},
+			expectedErr: ErrExtendsCycle,
+		},
+		// case: extends is not a list
+		{
+			files: map[string]string{
+				"config.yml": "extends: base.yml\n",
+			},
+			locations:   []string{"config.yml"},
+			expectedErr: ErrInvalidExtends,
+		},
+		// case: missing extended file
+		{
+			files: map[string]string{
+				"config.yml": "extends: [missing.yml]\n",
+			},
+			locations:   []string{"config.yml"},
+			expectedErr: ErrReadConfigLayer,
+		},
+	}
+
+	for _, tc := range testCases {
+		dir := t.TempDir()
+		writeFiles(t, dir, tc.files)
+
+		var locations []string
+		for _, location := range tc.locations {
+			locations = append(locations, filepath.Join(dir, location))
+		}
+
+		document, err := load(context.Background(), locations, fetch)
+		if tc.expectedErr != nil {
+			jtest.Require(t, tc.expectedErr, err)
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		data, err := document.Marshal()
+		jtest.RequireNil(t, err)
+		require.Equal(t, tc.expectedConfig, string(data))
+
+		var files []string
+		for _, file := range document.Files {
+			if filepath.IsAbs(file) {
+				file, err = filepath.Rel(dir, file)
+				jtest.RequireNil(t, err)
+			}
+			files = append(files, filepath.ToSlash(file))
+		}
+		require.Equal(t, tc.expectedFiles, files)
+	}
+}
+
+func TestDocument_FileOf(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"base.yml":   "image: jembi/platform\nprofiles:\n  - name: dev\n    packages: [core]\n",
+		"config.yml": "extends: [base.yml]\nprofiles:\n  - name: dev\n    dev: true\n",
+	})
+
+	document, err := load(context.Background(), []string{filepath.Join(dir, "config.yml")}, nil)
+	jtest.RequireNil(t, err)
+
+	_, profiles := mappingEntry(document.Root, "profiles")
+	_, packages := mappingEntry(profiles.Content[0], "packages")
+	_, dev := mappingEntry(profiles.Content[0], "dev")
+
+	require.Equal(t, "base.yml", filepath.Base(document.FileOf(packages)))
+	require.Equal(t, 4, packages.Line)
+	require.Equal(t, "config.yml", filepath.Base(document.FileOf(dev)))
+	require.Equal(t, 4, dev.Line)
+}
+
+func TestDocument_Interpolate(t *testing.T) {
+	variables := map[string]string{"HOME": "/home/instant", "DEV": "true"}
+	lookup := func(name string) (string, bool) {
+		value, ok := variables[name]
+		return value, ok
+	}
+
+	type cases struct {
+		config              string
+		expectedConfig      string
+		expectedErrorString string
+	}
+
+	testCases := []cases{
+		// case: values are interpolated, keys and quoted scalars keep their type
+		{
+			config: `image: ${PLATFORM_IMAGE:-jembi/platform:2.5.0}
+logPath: ${HOME}/instant-logs
+${HOME}: $$HOME
+profiles:
+  - name: dev
+    envVars:
+      - LOG_PATH=${HOME}/logs
+    dev: ${DEV}
+    only: "${DEV}"
+`,
+			expectedConfig: `image: jembi/platform:2.5.0
+logPath: /home/instant/instant-logs
+${HOME}: $HOME
+profiles:
+  - name: dev
+    envVars:
+      - LOG_PATH=/home/instant/logs
+    dev: true
+    only: "true"
+`,
+		},
+		// case: unset required variable
+		{
+			config:              "image: jembi/platform\nprofiles:\n  - name: ${PROFILE:?pick a profile}\n",
+			expectedErrorString: "config.yml:3:11: PROFILE (pick a profile): " + interpolate.ErrRequiredVariable.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"config.yml": tc.config})
+
+		wd, err := os.Getwd()
+		jtest.RequireNil(t, err)
+		jtest.RequireNil(t, os.Chdir(dir))
+
+		document, err := load(context.Background(), []string{filepath.Join(dir, "config.yml")}, nil)
+		jtest.RequireNil(t, err)
+		err = document.Interpolate(lookup)
+		jtest.RequireNil(t, os.Chdir(wd))
+
+		if tc.expectedErrorString != "" {
+			require.NotNil(t, err)
+			require.Equal(t, tc.expectedErrorString, err.Error())
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		data, err := document.Marshal()
+		jtest.RequireNil(t, err)
+		require.Equal(t, tc.expectedConfig, string(data))
+	}
+}
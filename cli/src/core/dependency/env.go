@@ -0,0 +1,132 @@
+package dependency
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/luno/jettison/errors"
+)
+
+var (
+	ErrUndeclaredEnvVars      = errors.New("env vars are not declared by any selected package")
+	ErrMissingRequiredEnvVars = errors.New("required env vars are not set")
+)
+
+// UndeclaredEnvVar is an env var that none of the selected packages declares
+type UndeclaredEnvVar struct {
+	Name string
+	// Suggestion is the closest declared env var name, or empty if none is close
+	Suggestion string
+}
+
+// MissingEnvVar is a required env var of a package that is not set
+type MissingEnvVar struct {
+	Name    string
+	Package string
+}
+
+// EnvVarCheck holds the problems found comparing env vars with the declarations of packages
+type EnvVarCheck struct {
+	Undeclared []UndeclaredEnvVar
+	Missing    []MissingEnvVar
+}
+
+// CheckEnvVars compares env vars in KEY=value form with the environmentVariables and
+// requiredEnvironmentVariables declared by the packages with ids. Required env vars are missing
+// if they are not set or empty.
+func (g *Graph) CheckEnvVars(ids []string, envVars []string) EnvVarCheck {
+	declared := make(map[string]bool)
+	var declaredNames []string
+	declare := func(name string) {
+		if !declared[name] {
+			declared[name] = true
+			declaredNames = append(declaredNames, name)
+		}
+	}
+
+	for _, id := range ids {
+		pack, ok := g.packages[id]
+		if !ok {
+			continue
+		}
+		for name := range pack.EnvironmentVariables {
+			declare(name)
+		}
+		for _, name := range pack.RequiredEnvironmentVariables {
+			declare(name)
+		}
+	}
+	sort.Strings(declaredNames)
+
+	values := make(map[string]string)
+	var names []string
+	for _, envVar := range envVars {
+		name, value, _ := strings.Cut(envVar, "=")
+		if _, ok := values[name]; !ok {
+			names = append(names, name)
+		}
+		values[name] = value
+	}
+	sort.Strings(names)
+
+	var check EnvVarCheck
+	for _, name := range names {
+		if !declared[name] {
+			check.Undeclared = append(check.Undeclared, UndeclaredEnvVar{Name: name, Suggestion: closestName(name, declaredNames)})
+		}
+	}
+
+	for _, id := range ids {
+		pack, ok := g.packages[id]
+		if !ok {
+			continue
+		}
+		for _, name := range pack.RequiredEnvironmentVariables {
+			if values[name] == "" {
+				check.Missing = append(check.Missing, MissingEnvVar{Name: name, Package: id})
+			}
+		}
+	}
+
+	return check
+}
+
+// closestName returns the candidate with the smallest edit distance to name, if it is close enough
+// to be a likely typo
+func closestName(name string, candidates []string) string {
+	maxDistance := max(2, len(name)/4)
+
+	var closest string
+	closestDistance := maxDistance + 1
+	for _, candidate := range candidates {
+		distance := editDistance(strings.ToUpper(name), strings.ToUpper(candidate))
+		if distance < closestDistance {
+			closest, closestDistance = candidate, distance
+		}
+	}
+
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			substitution := previous[j-1]
+			if a[i-1] != b[j-1] {
+				substitution++
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, substitution)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
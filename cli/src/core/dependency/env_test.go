@@ -0,0 +1,67 @@
+package dependency
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraph_CheckEnvVars(t *testing.T) {
+	graph := NewGraph([]PackageMetadata{
+		{
+			Id:                   "interoperability-layer-openhim",
+			EnvironmentVariables: map[string]interface{}{"OPENHIM_CORE_MEDIATOR_HOSTNAME": "localhost", "OPENHIM_MEDIATOR_API_PORT": "443"},
+		},
+		{
+			Id:                           "database-postgres",
+			EnvironmentVariables:         map[string]interface{}{"POSTGRES_REPLICA_SETS": "1"},
+			RequiredEnvironmentVariables: []string{"POSTGRES_PASSWORD"},
+		},
+		{
+			Id:                           "monitoring",
+			RequiredEnvironmentVariables: []string{"GRAFANA_PASSWORD"},
+		},
+	})
+
+	type cases struct {
+		ids           []string
+		envVars       []string
+		expectedCheck EnvVarCheck
+	}
+
+	testCases := []cases{
+		// case: declared and required env vars are set
+		{
+			ids:     []string{"database-postgres", "interoperability-layer-openhim"},
+			envVars: []string{"POSTGRES_PASSWORD=instant", "OPENHIM_MEDIATOR_API_PORT=8080"},
+		},
+		// case: typos are suggested the closest declared name
+		{
+			ids:     []string{"interoperability-layer-openhim"},
+			envVars: []string{"OPENHIM_CORE_MEDIATOR_HOSTNAM=openhim", "openhim_mediator_api_port=8080", "UNRELATED=1"},
+			expectedCheck: EnvVarCheck{
+				Undeclared: []UndeclaredEnvVar{
+					{Name: "OPENHIM_CORE_MEDIATOR_HOSTNAM", Suggestion: "OPENHIM_CORE_MEDIATOR_HOSTNAME"},
+					{Name: "UNRELATED"},
+					{Name: "openhim_mediator_api_port", Suggestion: "OPENHIM_MEDIATOR_API_PORT"},
+				},
+			},
+		},
+		// case: env vars of packages that aren't selected are undeclared, required env vars must not be empty
+		{
+			ids:     []string{"database-postgres", "monitoring"},
+			envVars: []string{"POSTGRES_PASSWORD=", "OPENHIM_MEDIATOR_API_PORT=8080"},
+			expectedCheck: EnvVarCheck{
+				Undeclared: []UndeclaredEnvVar{{Name: "OPENHIM_MEDIATOR_API_PORT"}},
+				Missing: []MissingEnvVar{
+					{Name: "POSTGRES_PASSWORD", Package: "database-postgres"},
+					{Name: "GRAFANA_PASSWORD", Package: "monitoring"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.expectedCheck, graph.CheckEnvVars(tc.ids, tc.envVars))
+	}
+}
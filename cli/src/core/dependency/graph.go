@@ -0,0 +1,160 @@
+package dependency
+
+import (
+	"sort"
+	"strings"
+
+	"cli/core/exitcode"
+
+	"github.com/luno/jettison/errors"
+)
+
+var (
+	ErrUnknownPackage   = errors.New("package not found in the image or custom packages")
+	ErrDependencyCycle  = errors.New("circular dependency")
+	ErrUnknownDirection = errors.New("unknown deploy command, expected init, up, down or destroy")
+)
+
+// UnknownPackageHint tells the user how to resolve ErrUnknownPackage
+const UnknownPackageHint = "Check the package ids and dependencies against the packages in the config image and custom packages"
+
+func unknownPackage(detail string) error {
+	return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrUnknownPackage, detail), UnknownPackageHint)
+}
+
+// Graph is the dependency graph of the packages available to the deployment container
+type Graph struct {
+	packages map[string]PackageMetadata
+}
+
+// NewGraph builds the dependency graph of packages. As in the deployment container, a package
+// replaces any earlier package with the same id.
+func NewGraph(packages []PackageMetadata) *Graph {
+	graph := &Graph{packages: make(map[string]PackageMetadata)}
+	for _, pack := range packages {
+		graph.packages[pack.Id] = pack
+	}
+
+	return graph
+}
+
+// Package returns the metadata of the package with id
+func (g *Graph) Package(id string) (PackageMetadata, bool) {
+	pack, ok := g.packages[id]
+	return pack, ok
+}
+
+// Ids returns the ids of every package in the graph, sorted
+func (g *Graph) Ids() []string {
+	ids := make([]string, 0, len(g.packages))
+	for id := range g.packages {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Resolve returns ids and their transitive dependencies ordered so that every package comes
+// after its dependencies. Every package in the graph is resolved if ids is empty.
+func (g *Graph) Resolve(ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		ids = g.Ids()
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+
+	var order, stack []string
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case visited:
+			return nil
+		case visiting:
+			cycle := append(append([]string{}, stack[indexOf(stack, id):]...), id)
+			return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrDependencyCycle, strings.Join(cycle, " -> ")), "Remove one of the dependencies in the cycle from its package-metadata.json")
+		}
+
+		pack, ok := g.packages[id]
+		if !ok {
+			if len(stack) > 0 {
+				return unknownPackage(stack[len(stack)-1] + " depends on " + id)
+			}
+			return unknownPackage(id)
+		}
+
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, dependency := range pack.Dependencies {
+			err := visit(dependency)
+			if err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = visited
+
+		order = append(order, id)
+		return nil
+	}
+
+	for _, id := range ids {
+		err := visit(id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// Order returns the order in which the deployment container acts on ids for deployCommand:
+// dependencies first for init and up, and dependents first for down and destroy. With only set,
+// dependencies are ignored and ids are acted on as given.
+func (g *Graph) Order(ids []string, deployCommand string, only bool) ([]string, error) {
+	if only {
+		for _, id := range ids {
+			if _, ok := g.packages[id]; !ok {
+				return nil, unknownPackage(id)
+			}
+		}
+		if len(ids) == 0 {
+			return g.Ids(), nil
+		}
+		return ids, nil
+	}
+
+	order, err := g.Resolve(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	switch deployCommand {
+	case "init", "up":
+		return order, nil
+
+	case "down", "destroy":
+		reversed := make([]string, len(order))
+		for i, id := range order {
+			reversed[len(order)-1-i] = id
+		}
+		return reversed, nil
+	}
+
+	return nil, errors.Wrap(ErrUnknownDirection, deployCommand)
+}
+
+func indexOf(ids []string, id string) int {
+	for i := range ids {
+		if ids[i] == id {
+			return i
+		}
+	}
+
+	return -1
+}
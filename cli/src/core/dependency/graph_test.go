@@ -0,0 +1,170 @@
+package dependency
+
+import (
+	"testing"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func testGraph(dependencies map[string][]string) *Graph {
+	var packages []PackageMetadata
+	for id, packageDependencies := range dependencies {
+		packages = append(packages, PackageMetadata{Id: id, Dependencies: packageDependencies})
+	}
+
+	return NewGraph(packages)
+}
+
+func TestGraph_Order(t *testing.T) {
+	graph := testGraph(map[string][]string{
+		"database-postgres":              nil,
+		"interoperability-layer-openhim": {"database-mongo"},
+		"database-mongo":                 nil,
+		"client-registry-santempi":       {"database-postgres", "interoperability-layer-openhim"},
+		"monitoring":                     nil,
+	})
+
+	type cases struct {
+		ids           []string
+		deployCommand string
+		only          bool
+		expectedOrder []string
+		expectedErr   error
+	}
+
+	testCases := []cases{
+		// case: dependencies before dependents for up
+		{
+			ids:           []string{"client-registry-santempi"},
+			deployCommand: "up",
+			expectedOrder: []string{"database-postgres", "database-mongo", "interoperability-layer-openhim", "client-registry-santempi"},
+		},
+		// case: dependents before dependencies for destroy
+		{
+			ids:           []string{"client-registry-santempi"},
+			deployCommand: "destroy",
+			expectedOrder: []string{"client-registry-santempi", "interoperability-layer-openhim", "database-mongo", "database-postgres"},
+		},
+		// case: shared dependencies are only acted on once
+		{
+			ids:           []string{"interoperability-layer-openhim", "client-registry-santempi"},
+			deployCommand: "init",
+			expectedOrder: []string{"database-mongo", "interoperability-layer-openhim", "database-postgres", "client-registry-santempi"},
+		},
+		// case: all packages when no ids are given
+		{
+			deployCommand: "down",
+			expectedOrder: []string{"monitoring", "client-registry-santempi", "interoperability-layer-openhim", "database-mongo", "database-postgres"},
+		},
+		// case: only ignores dependencies
+		{
+			ids:           []string{"client-registry-santempi", "monitoring"},
+			deployCommand: "down",
+			only:          true,
+			expectedOrder: []string{"client-registry-santempi", "monitoring"},
+		},
+		// case: unknown package with only
+		{
+			ids:           []string{"dashboard-visualiser-jsreport"},
+			deployCommand: "up",
+			only:          true,
+			expectedErr:   ErrUnknownPackage,
+		},
+		// case: unknown deploy command
+		{
+			ids:           []string{"monitoring"},
+			deployCommand: "restart",
+			expectedErr:   ErrUnknownDirection,
+		},
+	}
+
+	for _, tc := range testCases {
+		order, err := graph.Order(tc.ids, tc.deployCommand, tc.only)
+		if tc.expectedErr != nil {
+			jtest.Require(t, tc.expectedErr, err)
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		require.Equal(t, tc.expectedOrder, order)
+	}
+}
+
+func TestGraph_Resolve(t *testing.T) {
+	type cases struct {
+		dependencies    map[string][]string
+		ids             []string
+		expectedErr     error
+		expectedMessage string
+	}
+
+	testCases := []cases{
+		// case: cycle reports its full path
+		{
+			dependencies: map[string][]string{
+				"a": {"b"},
+				"b": {"c"},
+				"c": {"a"},
+			},
+			ids:             []string{"a"},
+			expectedErr:     ErrDependencyCycle,
+			expectedMessage: "a -> b -> c -> a",
+		},
+		// case: cycle below the chosen package
+		{
+			dependencies: map[string][]string{
+				"a": {"b"},
+				"b": {"c"},
+				"c": {"b"},
+			},
+			ids:             []string{"a"},
+			expectedErr:     ErrDependencyCycle,
+			expectedMessage: "b -> c -> b",
+		},
+		// case: package depending on itself
+		{
+			dependencies: map[string][]string{
+				"a": {"a"},
+			},
+			ids:             []string{"a"},
+			expectedErr:     ErrDependencyCycle,
+			expectedMessage: "a -> a",
+		},
+		// case: unknown dependency names its dependent
+		{
+			dependencies: map[string][]string{
+				"a": {"b"},
+			},
+			ids:             []string{"a"},
+			expectedErr:     ErrUnknownPackage,
+			expectedMessage: "a depends on b",
+		},
+		// case: unknown chosen package
+		{
+			dependencies: map[string][]string{
+				"a": nil,
+			},
+			ids:         []string{"b"},
+			expectedErr: ErrUnknownPackage,
+		},
+	}
+
+	for _, tc := range testCases {
+		_, err := testGraph(tc.dependencies).Resolve(tc.ids)
+		jtest.Require(t, tc.expectedErr, err)
+		require.Contains(t, err.Error(), tc.expectedMessage)
+	}
+}
+
+func TestNewGraph(t *testing.T) {
+	graph := NewGraph([]PackageMetadata{
+		{Id: "database-mongo", Source: "image jembi/platform"},
+		{Id: "database-mongo", Source: "custom ./mongo"},
+	})
+
+	pack, ok := graph.Package("database-mongo")
+	require.True(t, ok)
+	require.Equal(t, "custom ./mongo", pack.Source)
+	require.Equal(t, []string{"database-mongo"}, graph.Ids())
+}
@@ -0,0 +1,233 @@
+package dependency
+
+import (
+	"archive/tar"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"cli/core/exitcode"
+
+	"github.com/luno/jettison/errors"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// The schema is a copy of schema/package-metadata.schema.json at the root of the repository, which
+// the deployment container validates package metadata against
+//
+//go:generate cp ../../../../schema/package-metadata.schema.json .
+//go:embed package-metadata.schema.json
+var metadataSchemaSource string
+
+var metadataSchema = jsonschema.MustCompileString("package-metadata.schema.json", metadataSchemaSource)
+
+const (
+	MetadataFileName = "package-metadata.json"
+	// LegacyMetadataFileName is the metadata file of packages that predate package-metadata.json,
+	// which the deployment container still reads when a package has no package-metadata.json
+	LegacyMetadataFileName = "instant.json"
+
+	// MaxDepth is how deep the deployment container looks for package metadata below /instant
+	MaxDepth = 5
+)
+
+var ErrInvalidMetadata = errors.New("invalid package metadata")
+
+// PackageMetadata is the content of a package-metadata.json, or legacy instant.json, file
+type PackageMetadata struct {
+	Id                           string                 `json:"id"`
+	Name                         string                 `json:"name"`
+	Description                  string                 `json:"description"`
+	Type                         string                 `json:"type"`
+	Version                      string                 `json:"version"`
+	Dependencies                 []string               `json:"dependencies"`
+	EnvironmentVariables         map[string]interface{} `json:"environmentVariables"`
+	RequiredEnvironmentVariables []string               `json:"requiredEnvironmentVariables,omitempty"`
+	SharedConfigs                []string               `json:"sharedConfigs,omitempty"`
+
+	// Path is the location of the metadata file within its source
+	Path string `json:"-"`
+	// Source is where the package comes from, eg. the config image or the path of a custom package
+	Source string `json:"-"`
+}
+
+// ParseMetadata validates data against the package metadata schema and parses it
+func ParseMetadata(data []byte, path, source string) (*PackageMetadata, error) {
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&document)
+	if err != nil {
+		return nil, invalidMetadata(path + ": " + err.Error())
+	}
+
+	err = metadataSchema.Validate(document)
+	var validationErr *jsonschema.ValidationError
+	if errors.As(err, &validationErr) {
+		return nil, invalidMetadata(path + ": " + strings.Join(validationMessages(validationErr), ", "))
+	} else if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	var metadata PackageMetadata
+	err = json.Unmarshal(data, &metadata)
+	if err != nil {
+		return nil, invalidMetadata(path + ": " + err.Error())
+	}
+	metadata.Path = path
+	metadata.Source = source
+
+	return &metadata, nil
+}
+
+func invalidMetadata(detail string) error {
+	return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrInvalidMetadata, detail), "Fix the package-metadata.json file against schema/package-metadata.schema.json")
+}
+
+// validationMessages flattens a schema validation error into the messages of its causes, prefixed
+// with the location of the offending value
+func validationMessages(validationErr *jsonschema.ValidationError) []string {
+	if len(validationErr.Causes) == 0 {
+		location := validationErr.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		return []string{location + ": " + validationErr.Message}
+	}
+
+	var messages []string
+	for _, cause := range validationErr.Causes {
+		messages = append(messages, validationMessages(cause)...)
+	}
+
+	return messages
+}
+
+// Discover finds and parses the package metadata files in root, searching at most maxDepth
+// directories deep. Metadata found in root itself is at depth 0. Directories without a
+// package-metadata.json file are read from their legacy instant.json file, if any.
+func Discover(root string, maxDepth int, source string) ([]PackageMetadata, error) {
+	var files metadataFiles
+	err := filepath.WalkDir(root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		depth := strings.Count(filepath.ToSlash(rel), "/")
+
+		if d.IsDir() {
+			if rel != "." && (d.Name() == ".git" || depth >= maxDepth) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !isMetadataFile(d.Name()) {
+			return nil
+		}
+
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		files.add(filepath.ToSlash(rel), data)
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	return files.parse(source)
+}
+
+// DiscoverTar finds and parses the package metadata files in a tar stream of a directory, like the
+// one returned when copying a directory out of a container. The top-level entry of the stream is
+// the directory itself, so metadata in the directory is at depth 0. Directories without a
+// package-metadata.json file are read from their legacy instant.json file, if any.
+func DiscoverTar(r io.Reader, maxDepth int, source string) ([]PackageMetadata, error) {
+	var files metadataFiles
+
+	tarReader := tar.NewReader(r)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+
+		if header.Typeflag != tar.TypeReg || !isMetadataFile(path.Base(header.Name)) {
+			continue
+		}
+
+		// Strip the directory the stream was taken from
+		_, rel, _ := strings.Cut(path.Clean(header.Name), "/")
+		if strings.Count(rel, "/") > maxDepth {
+			continue
+		}
+
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		files.add(rel, data)
+	}
+
+	return files.parse(source)
+}
+
+func isMetadataFile(name string) bool {
+	return name == MetadataFileName || name == LegacyMetadataFileName
+}
+
+// metadataFiles holds the metadata file of every package directory found, in the order they were
+// found, preferring package-metadata.json over instant.json
+type metadataFiles struct {
+	dirs  []string
+	files map[string]metadataFile
+}
+
+type metadataFile struct {
+	path string
+	data []byte
+}
+
+func (m *metadataFiles) add(filePath string, data []byte) {
+	if m.files == nil {
+		m.files = make(map[string]metadataFile)
+	}
+
+	dir := path.Dir(filePath)
+	existing, ok := m.files[dir]
+	if !ok {
+		m.dirs = append(m.dirs, dir)
+	} else if path.Base(existing.path) == MetadataFileName {
+		return
+	}
+	m.files[dir] = metadataFile{path: filePath, data: data}
+}
+
+func (m *metadataFiles) parse(source string) ([]PackageMetadata, error) {
+	var packages []PackageMetadata
+	for _, dir := range m.dirs {
+		file := m.files[dir]
+		metadata, err := ParseMetadata(file.data, file.path, source)
+		if err != nil {
+			return nil, err
+		}
+		packages = append(packages, *metadata)
+	}
+
+	return packages, nil
+}
@@ -0,0 +1,163 @@
+package dependency
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/luno/jettison/errors"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func metadataJSON(id string, dependencies string) string {
+	return `{
+	"id": "` + id + `",
+	"name": "Test Package",
+	"description": "A package for tests",
+	"type": "infrastructure",
+	"version": "0.0.1",
+	"dependencies": [` + dependencies + `],
+	"environmentVariables": {"PORT": 8080}
+}`
+}
+
+func TestSchemaMatchesRepository(t *testing.T) {
+	repositorySchema, err := os.ReadFile("../../../../schema/package-metadata.schema.json")
+	jtest.RequireNil(t, err)
+
+	require.Equal(t, string(repositorySchema), metadataSchemaSource, "run go generate to update the embedded schema")
+}
+
+func TestParseMetadata(t *testing.T) {
+	type cases struct {
+		data             string
+		expectedMetadata *PackageMetadata
+		expectedErr      error
+		expectedMessage  string
+	}
+
+	testCases := []cases{
+		// case: valid metadata
+		{
+			data: metadataJSON("database-mongo", `"interoperability-layer-openhim"`),
+			expectedMetadata: &PackageMetadata{
+				Id:                   "database-mongo",
+				Name:                 "Test Package",
+				Description:          "A package for tests",
+				Type:                 "infrastructure",
+				Version:              "0.0.1",
+				Dependencies:         []string{"interoperability-layer-openhim"},
+				EnvironmentVariables: map[string]interface{}{"PORT": float64(8080)},
+				Path:                 "mongo/package-metadata.json",
+				Source:               "image jembi/platform",
+			},
+		},
+		// case: missing required fields
+		{
+			data:            `{"id": "database-mongo", "name": "Mongo", "description": "", "type": "infrastructure", "version": "1.0"}`,
+			expectedErr:     ErrInvalidMetadata,
+			expectedMessage: "mongo/package-metadata.json: /: missing properties: 'dependencies', 'environmentVariables'",
+		},
+		// case: invalid version and type
+		{
+			data:            `{"id": "database-mongo", "name": "Mongo", "description": "", "type": "database", "version": "v1", "dependencies": [], "environmentVariables": {}}`,
+			expectedErr:     ErrInvalidMetadata,
+			expectedMessage: "/type",
+		},
+		// case: invalid json
+		{
+			data:        `{"id": `,
+			expectedErr: ErrInvalidMetadata,
+		},
+	}
+
+	for _, tc := range testCases {
+		metadata, err := ParseMetadata([]byte(tc.data), "mongo/package-metadata.json", "image jembi/platform")
+		if tc.expectedErr != nil {
+			jtest.Require(t, tc.expectedErr, err)
+			require.Contains(t, err.Error(), tc.expectedMessage)
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		require.Equal(t, tc.expectedMetadata, metadata)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+
+	files := map[string]string{
+		"package-metadata.json":                       metadataJSON("root", ""),
+		"mongo/package-metadata.json":                 metadataJSON("database-mongo", ""),
+		"mongo/instant.json":                          metadataJSON("legacy-mongo", ""),
+		"kafka/instant.json":                          metadataJSON("message-bus-kafka", ""),
+		"a/b/c/d/package-metadata.json":               metadataJSON("deep", ""),
+		"a/b/c/d/e/package-metadata.json":             metadataJSON("too-deep", ""),
+		".git/package-metadata.json":                  metadataJSON("git", ""),
+		"mongo/docker-compose.yml":                    "version: '3.9'",
+		"openhim/importer/volume/package-metadata.md": "not metadata",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(root, name)
+		jtest.RequireNil(t, os.MkdirAll(filepath.Dir(filePath), os.ModePerm))
+		jtest.RequireNil(t, os.WriteFile(filePath, []byte(content), 0o644))
+	}
+
+	packages, err := Discover(root, 4, "custom ./packages")
+	jtest.RequireNil(t, err)
+
+	var ids []string
+	for _, pack := range packages {
+		require.Equal(t, "custom ./packages", pack.Source)
+		ids = append(ids, pack.Id)
+	}
+	require.ElementsMatch(t, []string{"root", "database-mongo", "message-bus-kafka", "deep"}, ids)
+
+	// Invalid metadata fails discovery
+	jtest.RequireNil(t, os.WriteFile(filepath.Join(root, "mongo", MetadataFileName), []byte(`{}`), 0o644))
+	_, err = Discover(root, 4, "custom ./packages")
+	require.True(t, errors.Is(err, ErrInvalidMetadata))
+}
+
+func TestDiscoverTar(t *testing.T) {
+	var buf bytes.Buffer
+	tarWriter := tar.NewWriter(&buf)
+
+	files := map[string]string{
+		"instant/mongo/package-metadata.json":             metadataJSON("database-mongo", ""),
+		"instant/mongo/instant.json":                      metadataJSON("legacy-mongo", ""),
+		"instant/kafka/instant.json":                      metadataJSON("message-bus-kafka", ""),
+		"instant/a/b/c/d/e/f/instant.json":                metadataJSON("too-deep-legacy", ""),
+		"instant/a/b/c/d/e/package-metadata.json":         metadataJSON("deep", ""),
+		"instant/a/b/c/d/e/f/package-metadata.json":       metadataJSON("too-deep", ""),
+		"instant/mongo/importer/package-metadata.json.md": "not metadata",
+	}
+	for name, content := range files {
+		jtest.RequireNil(t, tarWriter.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0o644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tarWriter.Write([]byte(content))
+		jtest.RequireNil(t, err)
+	}
+	jtest.RequireNil(t, tarWriter.Close())
+
+	packages, err := DiscoverTar(&buf, MaxDepth, "image jembi/platform")
+	jtest.RequireNil(t, err)
+
+	paths := make(map[string]string)
+	for _, pack := range packages {
+		paths[pack.Id] = pack.Path
+	}
+	require.Equal(t, map[string]string{
+		"database-mongo":    "mongo/package-metadata.json",
+		"message-bus-kafka": "kafka/instant.json",
+		"deep":              "a/b/c/d/e/package-metadata.json",
+	}, paths)
+}
@@ -0,0 +1,169 @@
+package dependency
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"cli/core/exitcode"
+
+	"github.com/luno/jettison/errors"
+)
+
+// Formats the dependency graph can be rendered in
+const (
+	FormatDot     = "dot"
+	FormatMermaid = "mermaid"
+	FormatJSON    = "json"
+)
+
+var ErrUnknownFormat = errors.New("unknown graph format, expected dot, mermaid or json")
+
+// FormatHint tells the user how to resolve ErrUnknownFormat
+const FormatHint = "Use --format=dot, --format=mermaid or --format=json"
+
+// Render writes the subgraph of the packages in ids to w in format, with an edge from every package
+// to each of its dependencies. ids must be closed under dependencies, like the order returned by
+// Resolve.
+func (g *Graph) Render(w io.Writer, ids []string, format string) error {
+	switch format {
+	case FormatDot:
+		return g.renderDot(w, ids)
+	case FormatMermaid:
+		return g.renderMermaid(w, ids)
+	case FormatJSON:
+		return g.renderJSON(w, ids)
+	}
+
+	return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrUnknownFormat, format), FormatHint)
+}
+
+func (g *Graph) renderDot(w io.Writer, ids []string) error {
+	var b strings.Builder
+	b.WriteString("digraph packages {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	for _, id := range ids {
+		pack := g.packages[id]
+
+		style := "solid"
+		if pack.Type == "use-case" {
+			style = "rounded"
+		}
+		fmt.Fprintf(&b, "  %s [label=%s, style=%s];\n", dotQuote(id), dotQuote(strings.Join(nodeLabel(pack), "\n")), style)
+	}
+
+	for _, id := range ids {
+		for _, dependency := range g.packages[id].Dependencies {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(id), dotQuote(dependency))
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	return nil
+}
+
+func (g *Graph) renderMermaid(w io.Writer, ids []string) error {
+	// Package ids aren't valid Mermaid node ids, so nodes are numbered in the order of ids
+	nodeIds := make(map[string]string)
+	for i, id := range ids {
+		nodeIds[id] = fmt.Sprintf("p%d", i)
+	}
+
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+
+	for _, id := range ids {
+		pack := g.packages[id]
+
+		open, close := "[", "]"
+		if pack.Type == "use-case" {
+			open, close = "(", ")"
+		}
+		fmt.Fprintf(&b, "  %s%s\"%s\"%s\n", nodeIds[id], open, mermaidEscape(strings.Join(nodeLabel(pack), "<br/>")), close)
+	}
+
+	for _, id := range ids {
+		for _, dependency := range g.packages[id].Dependencies {
+			fmt.Fprintf(&b, "  %s --> %s\n", nodeIds[id], nodeIds[dependency])
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	return nil
+}
+
+type jsonGraph struct {
+	Packages []jsonPackage `json:"packages"`
+}
+
+type jsonPackage struct {
+	Id           string   `json:"id"`
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	Version      string   `json:"version"`
+	Source       string   `json:"source"`
+	Path         string   `json:"path"`
+	Dependencies []string `json:"dependencies"`
+}
+
+func (g *Graph) renderJSON(w io.Writer, ids []string) error {
+	graph := jsonGraph{Packages: []jsonPackage{}}
+	for _, id := range ids {
+		pack := g.packages[id]
+
+		dependencies := pack.Dependencies
+		if dependencies == nil {
+			dependencies = []string{}
+		}
+
+		graph.Packages = append(graph.Packages, jsonPackage{
+			Id:           pack.Id,
+			Name:         pack.Name,
+			Type:         pack.Type,
+			Version:      pack.Version,
+			Source:       pack.Source,
+			Path:         pack.Path,
+			Dependencies: dependencies,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(graph)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	return nil
+}
+
+// nodeLabel returns the lines describing a package in a rendered graph
+func nodeLabel(pack PackageMetadata) []string {
+	return []string{
+		pack.Id,
+		pack.Type + " " + pack.Version,
+		pack.Source,
+	}
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
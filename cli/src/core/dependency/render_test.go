@@ -0,0 +1,106 @@
+package dependency
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraph_Render(t *testing.T) {
+	graph := NewGraph([]PackageMetadata{
+		{
+			Id:      "database-mongo",
+			Type:    "infrastructure",
+			Version: "0.0.1",
+			Source:  "image jembi/platform:latest",
+			Path:    "database-mongo/package-metadata.json",
+		},
+		{
+			Id:           "disi-on-platform",
+			Type:         "use-case",
+			Version:      "1.2",
+			Dependencies: []string{"database-mongo"},
+			Source:       `custom "./disi"`,
+			Path:         "package-metadata.json",
+		},
+	})
+	ids := []string{"database-mongo", "disi-on-platform"}
+
+	type cases struct {
+		format         string
+		expectedOutput string
+		expectedErr    error
+	}
+
+	testCases := []cases{
+		// case: dot
+		{
+			format: FormatDot,
+			expectedOutput: `digraph packages {
+  rankdir=LR;
+  node [shape=box];
+  "database-mongo" [label="database-mongo\ninfrastructure 0.0.1\nimage jembi/platform:latest", style=solid];
+  "disi-on-platform" [label="disi-on-platform\nuse-case 1.2\ncustom \"./disi\"", style=rounded];
+  "disi-on-platform" -> "database-mongo";
+}
+`,
+		},
+		// case: mermaid
+		{
+			format: FormatMermaid,
+			expectedOutput: `graph LR
+  p0["database-mongo<br/>infrastructure 0.0.1<br/>image jembi/platform:latest"]
+  p1("disi-on-platform<br/>use-case 1.2<br/>custom #quot;./disi#quot;")
+  p1 --> p0
+`,
+		},
+		// case: json
+		{
+			format: FormatJSON,
+			expectedOutput: `{
+  "packages": [
+    {
+      "id": "database-mongo",
+      "name": "",
+      "type": "infrastructure",
+      "version": "0.0.1",
+      "source": "image jembi/platform:latest",
+      "path": "database-mongo/package-metadata.json",
+      "dependencies": []
+    },
+    {
+      "id": "disi-on-platform",
+      "name": "",
+      "type": "use-case",
+      "version": "1.2",
+      "source": "custom \"./disi\"",
+      "path": "package-metadata.json",
+      "dependencies": [
+        "database-mongo"
+      ]
+    }
+  ]
+}
+`,
+		},
+		// case: unknown format
+		{
+			format:      "svg",
+			expectedErr: ErrUnknownFormat,
+		},
+	}
+
+	for _, tc := range testCases {
+		var output bytes.Buffer
+		err := graph.Render(&output, ids, tc.format)
+		if tc.expectedErr != nil {
+			jtest.Require(t, tc.expectedErr, err)
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		require.Equal(t, tc.expectedOutput, output.String())
+	}
+}
@@ -2,16 +2,20 @@ package deploy
 
 import (
 	"context"
+	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"cli/core"
+	"cli/core/cache"
+	"cli/core/dependency"
+	"cli/core/exitcode"
 	"cli/core/parse"
 	"cli/util/docker"
 	"cli/util/file"
@@ -23,19 +27,127 @@ import (
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/luno/jettison/errors"
-	"github.com/luno/jettison/log"
 	cp "github.com/otiai10/copy"
 )
 
-func mountCustomPackage(ctx context.Context, cli *client.Client, customPackage core.CustomPackage, instantContainerId string) error {
-	gitRegex := regexp.MustCompile(`\.git`)
-	httpRegex := regexp.MustCompile("http")
-	zipRegex := regexp.MustCompile(`\.zip`)
-	tarRegex := regexp.MustCompile(`\.tar`)
+var (
+	ErrMissingGitToken = errors.New("environment variable holding the git token is not set")
+	ErrInvalidSubdir   = errors.New("custom package subdir must be within the repository")
+	ErrNoArchive       = errors.New("sha256 and integrity can only be verified for archive custom packages")
+)
+
+const defaultConcurrency = 5
+
+var deploymentContainerCreated bool
+
+// DeploymentContainerCreated reports whether this process has created the deployment container,
+// and therefore whether there is a container and volume to clean up on exit
+func DeploymentContainerCreated() bool {
+	return deploymentContainerCreated
+}
+
+// stageCustomPackages fetches the custom packages into their own directories in stagingDir, up to
+// concurrency packages at a time, and returns the staged directory of each package by name. The
+// status of each package is written to out.
+func stageCustomPackages(ctx context.Context, out io.Writer, customPackages []core.CustomPackage, concurrency int, stagingDir string) (map[string]string, error) {
+	var mu sync.Mutex
+	stagedPackages := make(map[string]string)
+
+	err := forEachCustomPackage(out, customPackages, concurrency, func(customPackage core.CustomPackage) error {
+		name := parse.GetCustomPackageName(customPackage)
+		stagedPath := path.Join(stagingDir, name)
+
+		err := stageCustomPackage(ctx, customPackage, stagedPath)
+		if err != nil {
+			os.RemoveAll(stagedPath)
+			return err
+		}
+
+		mu.Lock()
+		stagedPackages[name] = stagedPath
+		mu.Unlock()
 
-	const CUSTOM_PACKAGE_LOCAL_PATH = "/tmp/custom-package/"
-	customPackageTmpLocation := path.Join(CUSTOM_PACKAGE_LOCAL_PATH, parse.GetCustomPackageName(customPackage))
-	err := os.RemoveAll(CUSTOM_PACKAGE_LOCAL_PATH)
+		return nil
+	})
+	if err != nil {
+		removeStagedPackages(stagedPackages)
+		return nil, err
+	}
+
+	return stagedPackages, nil
+}
+
+// getConcurrency returns the number of custom packages to fetch at a time
+func getConcurrency(packageSpec *core.PackageSpec) (int, error) {
+	if packageSpec.Concurrency == "" {
+		return defaultConcurrency, nil
+	}
+
+	concurrency, err := strconv.Atoi(packageSpec.Concurrency)
+	if err != nil || concurrency < 1 {
+		return 0, exitcode.New(exitcode.ValidationFailed, errors.Wrap(parse.ErrInvalidConcurrency, packageSpec.Concurrency), parse.ConcurrencyHint)
+	}
+
+	return concurrency, nil
+}
+
+func removeStagedPackages(stagedPackages map[string]string) {
+	for _, stagedPath := range stagedPackages {
+		os.RemoveAll(stagedPath)
+	}
+}
+
+// forEachCustomPackage calls fn for every custom package with at most concurrency calls running at
+// a time, writing the status of each package to out. Every failure is reported in a
+// *CustomPackageError.
+func forEachCustomPackage(out io.Writer, customPackages []core.CustomPackage, concurrency int, fn func(core.CustomPackage) error) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures = make(map[string]error)
+		slots    = make(chan struct{}, concurrency)
+	)
+
+	for _, customPackage := range customPackages {
+		wg.Add(1)
+		go func(customPackage core.CustomPackage) {
+			defer wg.Done()
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			name := parse.GetCustomPackageName(customPackage)
+			mu.Lock()
+			fmt.Fprintln(out, "> Fetching custom package", name)
+			mu.Unlock()
+
+			start := time.Now()
+			err := fn(customPackage)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				fmt.Fprintln(out, "> Custom package", name, "failed:", err)
+				failures[name] = err
+				return
+			}
+
+			fmt.Fprintln(out, "> Custom package", name, "ready in", time.Since(start).Round(time.Millisecond))
+		}(customPackage)
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return errors.Wrap(&CustomPackageError{Failures: failures}, "")
+	}
+
+	return nil
+}
+
+// stageCustomPackage fetches a custom package into customPackageTmpLocation. Every package is
+// staged in its own directory so that packages can be fetched concurrently.
+func stageCustomPackage(ctx context.Context, customPackage core.CustomPackage, customPackageTmpLocation string) error {
+	err := os.RemoveAll(customPackageTmpLocation)
 	if err != nil {
 		return errors.Wrap(err, "")
 	}
@@ -44,72 +156,78 @@ func mountCustomPackage(ctx context.Context, cli *client.Client, customPackage c
 		return errors.Wrap(err, "")
 	}
 
-	if gitRegex.MatchString(customPackage.Path) && !httpRegex.MatchString(customPackage.Path) {
-		err = git.CloneRepo(customPackage.Path, customPackageTmpLocation)
+	source := parse.GetCustomPackageSource(customPackage)
+	if source.Kind != parse.SourceHTTP && (customPackage.Sha256 != "" || customPackage.Integrity != "") {
+		return exitcode.New(exitcode.ConfigInvalid, errors.Wrap(ErrNoArchive, parse.GetCustomPackageName(customPackage)), "Remove sha256 and integrity from custom packages that are not archives")
+	}
+
+	switch source.Kind {
+	case parse.SourceGit:
+		auth, err := gitAuth(customPackage)
 		if err != nil {
 			return err
 		}
 
-	} else if httpRegex.MatchString(customPackage.Path) {
-		resp, err := http.Get(customPackage.Path)
+		packageCache, err := cache.Default()
 		if err != nil {
-			return errors.Wrap(err, "")
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != 200 {
-			return errors.Wrap(err, "Error in downloading custom package - HTTP status code: "+strconv.Itoa(resp.StatusCode))
+			return err
 		}
 
-		if zipRegex.MatchString(customPackage.Path) {
-			tmpZip, err := os.CreateTemp("", "tmp-*.zip")
-			if err != nil {
-				return errors.Wrap(err, "")
-			}
-
-			_, err = io.Copy(tmpZip, resp.Body)
-			if err != nil {
-				return errors.Wrap(err, "")
-			}
-
-			err = file.UnzipSource(tmpZip.Name(), customPackageTmpLocation)
+		err = packageCache.FetchGit(source.Location, source.Ref, auth, func(clonePath string) error {
+			packagePath, err := joinSubdir(clonePath, source.Subdir)
 			if err != nil {
 				return err
 			}
 
-			err = os.Remove(tmpZip.Name())
+			err = cp.Copy(packagePath, customPackageTmpLocation, cp.Options{
+				Skip: func(srcinfo os.FileInfo, src, dest string) (bool, error) {
+					return srcinfo.IsDir() && srcinfo.Name() == ".git", nil
+				},
+			})
 			if err != nil {
 				return errors.Wrap(err, "")
 			}
 
-		} else if tarRegex.MatchString(customPackage.Path) {
-			tmpTar, err := os.CreateTemp("", "tmp-*.tar")
-			if err != nil {
-				return errors.Wrap(err, "")
-			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
 
-			_, err = io.Copy(tmpTar, resp.Body)
-			if err != nil {
-				return errors.Wrap(err, "")
-			}
+	case parse.SourceHTTP:
+		packageCache, err := cache.Default()
+		if err != nil {
+			return err
+		}
 
-			err = file.UntarSource(tmpTar.Name(), customPackageTmpLocation)
-			if err != nil {
-				return err
-			}
+		archivePath, err := packageCache.FetchHTTP(ctx, source.Location)
+		if err != nil {
+			return err
+		}
 
-			err = os.Remove(tmpTar.Name())
-			if err != nil {
-				return err
-			}
+		err = verifyIntegrity(customPackage, archivePath)
+		if err != nil {
+			return err
 		}
-	} else {
-		err := cp.Copy(customPackage.Path, customPackageTmpLocation)
+
+		err = file.Extract(archivePath, customPackageTmpLocation, file.ExtractOptions{StripTopLevelDir: true})
+		if err != nil {
+			return errors.Wrap(err, "custom package "+parse.GetCustomPackageName(customPackage))
+		}
+
+	default:
+		err := cp.Copy(source.Location, customPackageTmpLocation)
 		if err != nil {
 			return errors.Wrap(err, "")
 		}
 	}
 
+	return nil
+}
+
+// copyCustomPackage copies a staged custom package into the packages directory of the deployment
+// container
+func copyCustomPackage(ctx context.Context, cli *client.Client, customPackageTmpLocation string, instantContainerId string) error {
 	customPackageReader, err := file.TarSource(customPackageTmpLocation)
 	if err != nil {
 		return err
@@ -119,14 +237,67 @@ func mountCustomPackage(ctx context.Context, cli *client.Client, customPackage c
 		return errors.Wrap(err, "")
 	}
 
-	err = os.RemoveAll(CUSTOM_PACKAGE_LOCAL_PATH)
-	if err != nil {
-		return errors.Wrap(err, "")
+	return nil
+}
+
+// gitAuth builds the git credentials of a custom package, reading secrets from the environment
+func gitAuth(customPackage core.CustomPackage) (git.Auth, error) {
+	if customPackage.Auth == nil {
+		return git.Auth{}, nil
+	}
+
+	auth := git.Auth{
+		SSHKeyPath: customPackage.Auth.SSHKey,
+		Username:   customPackage.Auth.Username,
+	}
+
+	if customPackage.Auth.SSHPassphraseEnv != "" {
+		auth.SSHKeyPassphrase = os.Getenv(customPackage.Auth.SSHPassphraseEnv)
+	}
+
+	if customPackage.Auth.TokenEnv != "" {
+		auth.Token = os.Getenv(customPackage.Auth.TokenEnv)
+		if auth.Token == "" {
+			err := errors.Wrap(ErrMissingGitToken, customPackage.Auth.TokenEnv+" for custom package "+parse.GetCustomPackageName(customPackage))
+			return git.Auth{}, exitcode.New(exitcode.ConfigInvalid, err, "Export the environment variable named by tokenEnv in the custom package auth")
+		}
+	}
+
+	return auth, nil
+}
+
+// verifyIntegrity checks a downloaded custom package archive against its sha256 and integrity
+// fields, if set
+func verifyIntegrity(customPackage core.CustomPackage, archivePath string) error {
+	for _, expected := range []string{customPackage.Sha256, customPackage.Integrity} {
+		if expected == "" {
+			continue
+		}
+
+		err := file.VerifyIntegrity(archivePath, expected)
+		if errors.Is(err, file.ErrIntegrityMismatch) {
+			err = errors.Wrap(err, "custom package "+parse.GetCustomPackageName(customPackage))
+			return exitcode.New(exitcode.ValidationFailed, err, "If the archive was changed on purpose, update sha256 or integrity with the output of 'instant package checksum'")
+		} else if err != nil {
+			return errors.Wrap(err, "custom package "+parse.GetCustomPackageName(customPackage))
+		}
 	}
 
 	return nil
 }
 
+// joinSubdir joins subdir onto root, rejecting subdirectories that escape root
+func joinSubdir(root, subdir string) (string, error) {
+	joined := filepath.Join(root, subdir)
+
+	rel, err := filepath.Rel(root, joined)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return "", exitcode.New(exitcode.ConfigInvalid, errors.Wrap(ErrInvalidSubdir, subdir), "Use a subdir relative to the root of the repository")
+	}
+
+	return joined, nil
+}
+
 func copyCredsToInstantContainer() (err error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -166,7 +337,9 @@ func copyCredsToInstantContainer() (err error) {
 	return nil
 }
 
-// Attaches a container's STDOUT until that container has been removed
+// Attaches a container's STDOUT, starts it and waits until it has been removed,
+// returning a *DeploymentError if the container exited with a non-zero or
+// unknown status code or reported failing package scripts
 func attachUntilRemoved(cli client.ContainerAPIClient, ctx context.Context, instantContainerId string) error {
 	attachResponse, err := cli.ContainerAttach(ctx, instantContainerId, container.AttachOptions{Stdout: true, Stream: true, Logs: true, Stderr: true})
 	if err != nil {
@@ -174,34 +347,109 @@ func attachUntilRemoved(cli client.ContainerAPIClient, ctx context.Context, inst
 	}
 	defer attachResponse.Close()
 
+	failureWriter := &scriptFailureWriter{}
+	output := io.MultiWriter(os.Stdout, failureWriter)
+
+	copyErr := make(chan error, 1)
 	go func() {
-		_, err = stdcopy.StdCopy(os.Stdout, os.Stdout, attachResponse.Reader)
+		_, err := stdcopy.StdCopy(output, output, attachResponse.Reader)
 		if err != nil && !strings.Contains(err.Error(), "use of closed network connection") {
-			log.Error(ctx, err)
-			panic(err)
+			copyErr <- errors.Wrap(err, "")
 		}
+		close(copyErr)
 	}()
 
+	// The wait is registered before the container is started, so that the exit
+	// status of a container removed as soon as it exits is not missed
 	successC, errC := cli.ContainerWait(ctx, instantContainerId, "removed")
+
+	err = cli.ContainerStart(ctx, instantContainerId, container.StartOptions{})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	var exitCode int64
 	select {
-	case <-successC:
-		return nil
+	case waitResponse := <-successC:
+		exitCode = waitResponse.StatusCode
 	case err := <-errC:
-		if strings.Contains(err.Error(), "No such container") {
-			return nil
+		if !strings.Contains(err.Error(), "No such container") {
+			return errors.Wrap(err, "")
 		}
-		return errors.Wrap(err, "")
+		exitCode = unknownExitCode
+	}
+
+	// Wait for the remaining output so that no failure reports are missed
+	err = <-copyErr
+	if err != nil {
+		return err
 	}
+
+	failedScripts := failureWriter.FailedScripts()
+	if exitCode != 0 || len(failedScripts) > 0 {
+		deploymentErr := &DeploymentError{
+			ExitCode:      exitCode,
+			FailedScripts: failedScripts,
+		}
+		return exitcode.New(exitcode.DeploymentFailed, errors.Wrap(deploymentErr, ""), "Check the output of the deployment container above for the failing package scripts")
+	}
+
+	return nil
 }
 
-func LaunchDeploymentContainer(packageSpec *core.PackageSpec, config *core.Config) error {
-	ctx := context.Background()
+// Launch launches the deployment container for the package spec and config, or only writes the
+// deployment plan to stdout if packageSpec.DryRun is set, see PreviewDeployment
+func Launch(ctx context.Context, packageSpec *core.PackageSpec, config *core.Config) error {
+	if packageSpec.DryRun {
+		return PreviewDeployment(ctx, os.Stdout, packageSpec, config)
+	}
 
+	return LaunchDeploymentContainer(ctx, packageSpec, config)
+}
+
+func LaunchDeploymentContainer(ctx context.Context, packageSpec *core.PackageSpec, config *core.Config) error {
 	cli, err := docker.NewDockerClient()
 	if err != nil {
 		return errors.Wrap(err, "")
 	}
 
+	concurrency, err := getConcurrency(packageSpec)
+	if err != nil {
+		return err
+	}
+
+	// Every launch stages its custom packages in its own directory, so that concurrent launches on
+	// the same host don't overwrite each other's packages
+	stagingDir, err := os.MkdirTemp("", "instant-custom-packages-*")
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer os.RemoveAll(stagingDir)
+
+	stagedPackages, err := stageCustomPackages(ctx, os.Stdout, packageSpec.CustomPackages, concurrency, stagingDir)
+	if err != nil {
+		return err
+	}
+
+	// The deployment container resolves the packages itself, so the order and env var checks are
+	// skipped with a warning if the packages can't be resolved here
+	var graph *dependency.Graph
+	var order []string
+	packages, err := discoverPackages(ctx, cli, packageSpec, config, stagedPackages)
+	if err == nil {
+		graph, order, err = resolvePackages(packageSpec, packages)
+	}
+	if err != nil {
+		fmt.Println("> Warning: could not resolve package dependencies, leaving it to the deployment container:", err)
+	} else {
+		fmt.Println("> Deployment order:", strings.Join(order, ", "))
+
+		err = checkEnvVars(os.Stdout, graph, order, packageSpec)
+		if err != nil {
+			return err
+		}
+	}
+
 	mounts := []mount.Mount{
 		{
 			Type:   mount.TypeVolume,
@@ -246,9 +494,10 @@ func LaunchDeploymentContainer(packageSpec *core.PackageSpec, config *core.Confi
 	if err != nil {
 		return errors.Wrap(err, "")
 	}
+	deploymentContainerCreated = true
 
-	for _, customPackage := range packageSpec.CustomPackages {
-		err = mountCustomPackage(ctx, cli, customPackage, instantContainer.ID)
+	for _, stagedPath := range stagedPackages {
+		err = copyCustomPackage(ctx, cli, stagedPath, instantContainer.ID)
 		if err != nil {
 			return err
 		}
@@ -259,11 +508,6 @@ func LaunchDeploymentContainer(packageSpec *core.PackageSpec, config *core.Confi
 		return err
 	}
 
-	err = cli.ContainerStart(ctx, instantContainer.ID, container.StartOptions{})
-	if err != nil {
-		return errors.Wrap(err, "")
-	}
-
 	err = attachUntilRemoved(cli, ctx, instantContainer.ID)
 	if err != nil {
 		return err
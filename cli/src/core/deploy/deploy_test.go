@@ -5,9 +5,19 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"net"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"cli/core"
+	"cli/core/exitcode"
+	"cli/util/file"
 
 	"github.com/docker/docker/api/types"
 	_container "github.com/docker/docker/api/types/container"
@@ -22,6 +32,7 @@ func Test_attachUntilRemoved(t *testing.T) {
 
 	type cases struct {
 		expectedError string
+		expectedCode  int
 		hookFunc      func()
 	}
 
@@ -44,9 +55,21 @@ func Test_attachUntilRemoved(t *testing.T) {
 				}, nil).Once()
 			},
 		},
-		// Case: receive expected "No such container" message
+		// Case: receive non-zero exit status from the removed container
+		{
+			expectedError: "deployment container exited with status 1",
+			expectedCode:  exitcode.DeploymentFailed,
+			hookFunc: func() {
+				mockApiClient.On("ContainerWait").Return(_container.WaitResponse{
+					StatusCode: 1,
+					Error:      nil,
+				}, nil).Once()
+			},
+		},
+		// Case: container removed before its exit status could be read
 		{
-			expectedError: "No such container",
+			expectedError: "deployment container exited with an unknown status",
+			expectedCode:  exitcode.DeploymentFailed,
 			hookFunc: func() {
 				mockApiClient.On("ContainerWait").Return(nil, "No such container").Once()
 			},
@@ -64,14 +87,64 @@ func Test_attachUntilRemoved(t *testing.T) {
 		testCase.hookFunc()
 
 		err := attachUntilRemoved(mockApiClient, context.Background(), "")
-		if err != nil {
+		if testCase.expectedError != "" {
+			require.NotNil(t, err)
 			require.Equal(t, strings.Contains(err.Error(), testCase.expectedError), true)
+			if testCase.expectedCode != 0 {
+				require.Equal(t, testCase.expectedCode, exitcode.Classify(err).Code)
+			}
 		} else {
 			jtest.RequireNil(t, err)
 		}
 	}
 }
 
+func Test_scriptFailureWriter(t *testing.T) {
+	type cases struct {
+		output                 []string
+		expectedFailedScripts  []string
+		expectedFailedPackages []string
+	}
+
+	testCases := []cases{
+		// case: no failures reported
+		{
+			output: []string{"🚀 Starting package Core (core)...\n", "\n🟢 Success!\n"},
+		},
+		// case: failures reported across split writes and an unterminated final line
+		{
+			output: []string{
+				"❌ Script core/swa",
+				"rm.sh returned an error\n\t some output\n",
+				"❌ Script nested/client/swarm.sh returned an error",
+			},
+			expectedFailedScripts:  []string{"core/swarm.sh", "nested/client/swarm.sh"},
+			expectedFailedPackages: []string{"core", "client"},
+		},
+		// case: failures of scripts run from the docker and kubernetes directories of packages
+		{
+			output: []string{
+				"❌ Script monitoring/docker/swarm.sh returned an error\n",
+				"❌ Script client/kubernetes/main/k8s.sh returned an error\n",
+			},
+			expectedFailedScripts:  []string{"monitoring/docker/swarm.sh", "client/kubernetes/main/k8s.sh"},
+			expectedFailedPackages: []string{"monitoring", "client"},
+		},
+	}
+
+	for _, tc := range testCases {
+		writer := &scriptFailureWriter{}
+		for _, o := range tc.output {
+			_, err := writer.Write([]byte(o))
+			jtest.RequireNil(t, err)
+		}
+
+		deploymentErr := DeploymentError{FailedScripts: writer.FailedScripts()}
+		require.Equal(t, tc.expectedFailedScripts, deploymentErr.FailedScripts)
+		require.Equal(t, tc.expectedFailedPackages, deploymentErr.FailedPackages())
+	}
+}
+
 type MockApiClient struct {
 	mock.Mock
 	client.ContainerAPIClient
@@ -93,6 +166,10 @@ func (mock *MockApiClient) ContainerAttach(ctx context.Context, container string
 	return response, err
 }
 
+func (mock *MockApiClient) ContainerStart(ctx context.Context, container string, options _container.StartOptions) error {
+	return nil
+}
+
 func (mock *MockApiClient) ContainerWait(ctx context.Context, container string, condition _container.WaitCondition) (<-chan _container.WaitResponse, <-chan error) {
 	args := mock.Called()
 
@@ -122,3 +199,89 @@ func (mock *MockApiClient) ContainerWait(ctx context.Context, container string,
 
 	return newChan, errChan
 }
+
+func Test_forEachCustomPackage(t *testing.T) {
+	var customPackages []core.CustomPackage
+	for i := 0; i < 6; i++ {
+		customPackages = append(customPackages, core.CustomPackage{Id: fmt.Sprintf("package-%d", i), Path: fmt.Sprintf("./package-%d", i)})
+	}
+
+	var out bytes.Buffer
+	var running, maxRunning, calls int32
+	err := forEachCustomPackage(&out, customPackages, 2, func(customPackage core.CustomPackage) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&calls, 1)
+
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		if customPackage.Id == "package-1" || customPackage.Id == "package-4" {
+			return errors.New("unexpected HTTP status code")
+		}
+		return nil
+	})
+
+	require.Equal(t, int32(6), calls)
+	require.LessOrEqual(t, maxRunning, int32(2))
+
+	var customPackageErr *CustomPackageError
+	require.True(t, errors.As(err, &customPackageErr))
+	require.Len(t, customPackageErr.Failures, 2)
+	require.Contains(t, customPackageErr.Failures, "package-1")
+	require.Contains(t, customPackageErr.Failures, "package-4")
+	require.Equal(t, "2 custom package(s) failed: package-1: unexpected HTTP status code; package-4: unexpected HTTP status code", customPackageErr.Error())
+	require.Contains(t, out.String(), "> Fetching custom package package-0\n")
+	require.Contains(t, out.String(), "> Custom package package-1 failed: unexpected HTTP status code\n")
+
+	err = forEachCustomPackage(io.Discard, customPackages, 5, func(core.CustomPackage) error { return nil })
+	jtest.RequireNil(t, err)
+}
+
+func Test_verifyIntegrity(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "package.zip")
+	jtest.RequireNil(t, os.WriteFile(archivePath, []byte("package"), 0644))
+
+	sha256, err := file.Sha256(archivePath)
+	jtest.RequireNil(t, err)
+
+	type cases struct {
+		customPackage core.CustomPackage
+		expectedErr   error
+		expectedCode  int
+	}
+
+	testCases := []cases{
+		// case: matching sha256
+		{customPackage: core.CustomPackage{Id: "package", Sha256: sha256}},
+		// case: no sha256 or integrity
+		{customPackage: core.CustomPackage{Id: "package"}},
+		// case: integrity mismatch
+		{
+			customPackage: core.CustomPackage{Id: "package", Sha256: strings.Repeat("0", 64)},
+			expectedErr:   file.ErrIntegrityMismatch,
+			expectedCode:  exitcode.ValidationFailed,
+		},
+		// case: unsupported integrity value
+		{
+			customPackage: core.CustomPackage{Id: "package", Integrity: "md5"},
+			expectedErr:   file.ErrUnsupportedIntegrity,
+			expectedCode:  exitcode.General,
+		},
+	}
+
+	for _, tc := range testCases {
+		err := verifyIntegrity(tc.customPackage, archivePath)
+		if tc.expectedErr != nil {
+			require.True(t, errors.Is(err, tc.expectedErr), err)
+			require.Equal(t, tc.expectedCode, exitcode.Classify(err).Code)
+			continue
+		}
+		jtest.RequireNil(t, err)
+	}
+}
@@ -0,0 +1,101 @@
+package deploy
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"cli/core"
+	"cli/core/parse"
+	"cli/util/slice"
+)
+
+// PrintPlan writes what a launch of the deployment container with the given package spec and
+// config would do, without touching Docker. order is the order in which the deployment container
+// acts on the packages, or nil if it could not be resolved.
+func PrintPlan(w io.Writer, packageSpec *core.PackageSpec, config *core.Config, order []string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Config image:\t%s\n", config.Image)
+	fmt.Fprintf(tw, "Deploy command:\t%s\n", packageSpec.DeployCommand)
+
+	fmt.Fprintln(tw, "\nPackages:")
+	packages := planPackages(packageSpec)
+	if len(packages) == 0 {
+		fmt.Fprintln(tw, "  (all packages in the image)")
+	}
+	for _, pack := range packages {
+		source := "image " + config.Image
+		if pack.custom != nil {
+			source = "custom " + pack.custom.Path
+		}
+		fmt.Fprintf(tw, "  %s\t%s\n", pack.name, source)
+	}
+
+	fmt.Fprintln(tw, "\nDeployment order:")
+	if order == nil && packageSpec.Resolve {
+		fmt.Fprintln(tw, "  (unresolved, see the deployment container output)")
+	} else if order == nil {
+		fmt.Fprintln(tw, "  (not resolved, pass --resolve to read it from the package metadata)")
+	}
+	for i, id := range order {
+		fmt.Fprintf(tw, "  %d. %s\n", i+1, id)
+	}
+
+	fmt.Fprintln(tw, "\nEnvironment variables:")
+	envVars := append([]string{}, packageSpec.EnvironmentVariables...)
+	sort.Strings(envVars)
+	if len(envVars) == 0 {
+		fmt.Fprintln(tw, "  (none)")
+	}
+	for _, envVar := range envVars {
+		fmt.Fprintf(tw, "  %s\n", envVar)
+	}
+
+	fmt.Fprintln(tw, "\nDeployment container command:")
+	fmt.Fprintf(tw, "  %s\n", quoteArgs(parse.GetInstantCommand(*packageSpec)))
+
+	return tw.Flush()
+}
+
+type plannedPackage struct {
+	name   string
+	custom *core.CustomPackage
+}
+
+// planPackages lists the packages in the order they are passed to the deployment container
+func planPackages(packageSpec *core.PackageSpec) []plannedPackage {
+	customPackages := make(map[string]*core.CustomPackage)
+	for i, customPackage := range packageSpec.CustomPackages {
+		customPackages[parse.GetCustomPackageName(customPackage)] = &packageSpec.CustomPackages[i]
+	}
+
+	var packages []plannedPackage
+	for _, pack := range packageSpec.Packages {
+		packages = append(packages, plannedPackage{name: pack, custom: customPackages[pack]})
+	}
+
+	for _, customPackage := range packageSpec.CustomPackages {
+		name := parse.GetCustomPackageName(customPackage)
+		if !slice.SliceContains(packageSpec.Packages, name) {
+			packages = append(packages, plannedPackage{name: name, custom: customPackages[name]})
+		}
+	}
+
+	return packages
+}
+
+func quoteArgs(args []string) string {
+	var quoted []string
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'") {
+			arg = strconv.Quote(arg)
+		}
+		quoted = append(quoted, arg)
+	}
+
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,107 @@
+package deploy
+
+import (
+	"bytes"
+	"testing"
+
+	"cli/core"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintPlan(t *testing.T) {
+	type cases struct {
+		packageSpec    *core.PackageSpec
+		order          []string
+		expectedOutput string
+	}
+
+	config := &core.Config{Image: "jembi/platform:latest"}
+
+	testCases := []cases{
+		// case: image and custom packages with env vars
+		{
+			packageSpec: &core.PackageSpec{
+				DeployCommand:        "up",
+				Packages:             []string{"core", "disi-on-platform"},
+				CustomPackages:       []core.CustomPackage{{Id: "disi-on-platform", Path: "git@github.com:jembi/disi-on-platform.git"}, {Path: "./my package"}},
+				EnvironmentVariables: []string{"SECOND=two", "FIRST=one"},
+				IsDev:                true,
+			},
+			order: []string{"core", "disi-on-platform", "my package"},
+			expectedOutput: `Config image:    jembi/platform:latest
+Deploy command:  up
+
+Packages:
+  core              image jembi/platform:latest
+  disi-on-platform  custom git@github.com:jembi/disi-on-platform.git
+  my package        custom ./my package
+
+Deployment order:
+  1. core
+  2. disi-on-platform
+  3. my package
+
+Environment variables:
+  FIRST=one
+  SECOND=two
+
+Deployment container command:
+  up -t swarm --dev core disi-on-platform "my package"
+`,
+		},
+		// case: no packages or env vars, and an unresolved order
+		{
+			packageSpec: &core.PackageSpec{
+				DeployCommand: "destroy",
+				Resolve:       true,
+			},
+			expectedOutput: `Config image:    jembi/platform:latest
+Deploy command:  destroy
+
+Packages:
+  (all packages in the image)
+
+Deployment order:
+  (unresolved, see the deployment container output)
+
+Environment variables:
+  (none)
+
+Deployment container command:
+  destroy -t swarm
+`,
+		},
+		// case: order not resolved without --resolve
+		{
+			packageSpec: &core.PackageSpec{
+				DeployCommand: "down",
+				Packages:      []string{"core"},
+			},
+			expectedOutput: `Config image:    jembi/platform:latest
+Deploy command:  down
+
+Packages:
+  core  image jembi/platform:latest
+
+Deployment order:
+  (not resolved, pass --resolve to read it from the package metadata)
+
+Environment variables:
+  (none)
+
+Deployment container command:
+  down -t swarm core
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		var output bytes.Buffer
+		err := PrintPlan(&output, tc.packageSpec, config, tc.order)
+		jtest.RequireNil(t, err)
+
+		require.Equal(t, tc.expectedOutput, output.String())
+	}
+}
@@ -0,0 +1,165 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"cli/core"
+	"cli/core/dependency"
+	"cli/core/exitcode"
+	"cli/core/parse"
+	"cli/util/docker"
+
+	"github.com/luno/jettison/errors"
+)
+
+// The directory of the config image the deployment container looks for packages in
+const instantDir = "/instant"
+
+// PreviewDeployment writes the deployment plan of the package spec and config to w without touching
+// Docker. With packageSpec.Resolve, the packages are read from the local config image and the
+// custom packages are fetched to include the order in which the deployment container would act on
+// the packages. Invalid package metadata and unresolvable dependencies are then returned as errors,
+// as are env vars failing checkEnvVars. If the packages can't be read at all, eg. because Docker is
+// unreachable, the plan is written without an order.
+func PreviewDeployment(ctx context.Context, w io.Writer, packageSpec *core.PackageSpec, config *core.Config) error {
+	if !packageSpec.Resolve {
+		return PrintPlan(w, packageSpec, config, nil)
+	}
+
+	graph, order, err := ResolvePackages(ctx, w, packageSpec, config)
+	if isDependencyError(err) {
+		return err
+	} else if err != nil {
+		fmt.Fprintln(w, "> Could not resolve package dependencies:", err)
+	} else {
+		err = checkEnvVars(w, graph, order, packageSpec)
+		if err != nil {
+			return err
+		}
+	}
+
+	return PrintPlan(w, packageSpec, config, order)
+}
+
+// ResolvePackages builds the dependency graph of the packages in the local config image and the
+// custom packages of the package spec, and returns it with the order in which the deployment
+// container would act on the packages of the package spec. Custom packages are fetched into a
+// temporary directory that is removed before returning, writing their status to out.
+func ResolvePackages(ctx context.Context, out io.Writer, packageSpec *core.PackageSpec, config *core.Config) (*dependency.Graph, []string, error) {
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cli.Close()
+
+	concurrency, err := getConcurrency(packageSpec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stagingDir, err := os.MkdirTemp("", "instant-plan-*")
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "")
+	}
+	defer os.RemoveAll(stagingDir)
+
+	stagedPackages, err := stageCustomPackages(ctx, out, packageSpec.CustomPackages, concurrency, stagingDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	packages, err := discoverPackages(ctx, cli, packageSpec, config, stagedPackages)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resolvePackages(packageSpec, packages)
+}
+
+func isDependencyError(err error) bool {
+	return errors.Is(err, dependency.ErrInvalidMetadata) ||
+		errors.Is(err, dependency.ErrDependencyCycle) ||
+		errors.Is(err, dependency.ErrUnknownPackage)
+}
+
+// resolvePackages builds the dependency graph of the staged packages, and returns the order in
+// which the deployment container will act on the packages of the package spec
+func resolvePackages(packageSpec *core.PackageSpec, staged []StagedPackage) (*dependency.Graph, []string, error) {
+	var packages []dependency.PackageMetadata
+	for _, pack := range staged {
+		packages = append(packages, pack.Metadata)
+	}
+
+	graph := dependency.NewGraph(packages)
+
+	order, err := graph.Order(packageIds(packageSpec), packageSpec.DeployCommand, packageSpec.IsOnly)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return graph, order, nil
+}
+
+// checkEnvVars compares the env vars of the package spec with those declared by the packages in
+// order when deploying them. Env vars that none of the packages declares are reported as warnings,
+// or as an error with --strict-env, and required env vars that are not set are an error.
+func checkEnvVars(w io.Writer, graph *dependency.Graph, order []string, packageSpec *core.PackageSpec) error {
+	if packageSpec.DeployCommand != "init" && packageSpec.DeployCommand != "up" {
+		return nil
+	}
+
+	check := graph.CheckEnvVars(order, packageSpec.EnvironmentVariables)
+
+	var undeclared []string
+	for _, envVar := range check.Undeclared {
+		description := envVar.Name
+		if envVar.Suggestion != "" {
+			description += " (did you mean " + envVar.Suggestion + "?)"
+		}
+		undeclared = append(undeclared, description)
+
+		if !packageSpec.StrictEnv {
+			fmt.Fprintln(w, "> Warning: env var", description, "is not declared by any of the selected packages")
+		}
+	}
+	if packageSpec.StrictEnv && len(undeclared) > 0 {
+		err := errors.Wrap(dependency.ErrUndeclaredEnvVars, strings.Join(undeclared, ", "))
+		return exitcode.New(exitcode.ValidationFailed, err, "Check the env var names against the environmentVariables of the package metadata, or drop --strict-env")
+	}
+
+	if len(check.Missing) > 0 {
+		var missing []string
+		for _, envVar := range check.Missing {
+			missing = append(missing, envVar.Name+" (required by "+envVar.Package+")")
+		}
+		err := errors.Wrap(dependency.ErrMissingRequiredEnvVars, strings.Join(missing, ", "))
+		return exitcode.New(exitcode.ValidationFailed, err, "Set the env vars with --env-var, --env-file or a profile")
+	}
+
+	return nil
+}
+
+// packageIds returns the ids of the packages passed to the deployment container, in the order they
+// are passed
+func packageIds(packageSpec *core.PackageSpec) []string {
+	var ids []string
+	for _, pack := range planPackages(packageSpec) {
+		ids = append(ids, pack.name)
+	}
+
+	return ids
+}
+
+func customPackageSource(packageSpec *core.PackageSpec, name string) string {
+	for _, customPackage := range packageSpec.CustomPackages {
+		if parse.GetCustomPackageName(customPackage) == name {
+			return "custom " + customPackage.Path
+		}
+	}
+
+	return "custom " + name
+}
@@ -0,0 +1,71 @@
+package deploy
+
+import (
+	"bytes"
+	"testing"
+
+	"cli/core"
+	"cli/core/dependency"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_checkEnvVars(t *testing.T) {
+	graph := dependency.NewGraph([]dependency.PackageMetadata{
+		{
+			Id:                           "database-postgres",
+			EnvironmentVariables:         map[string]interface{}{"POSTGRES_REPLICA_SETS": "1"},
+			RequiredEnvironmentVariables: []string{"POSTGRES_PASSWORD"},
+		},
+	})
+	order := []string{"database-postgres"}
+
+	type cases struct {
+		packageSpec         core.PackageSpec
+		expectedOutput      string
+		expectedErrorString string
+	}
+
+	testCases := []cases{
+		// case: undeclared env vars are warnings
+		{
+			packageSpec: core.PackageSpec{
+				DeployCommand:        "up",
+				EnvironmentVariables: []string{"POSTGRES_PASSWORD=instant", "POSTGRES_REPLICA_SET=3"},
+			},
+			expectedOutput: "> Warning: env var POSTGRES_REPLICA_SET (did you mean POSTGRES_REPLICA_SETS?) is not declared by any of the selected packages\n",
+		},
+		// case: undeclared env vars fail with --strict-env
+		{
+			packageSpec: core.PackageSpec{
+				DeployCommand:        "init",
+				EnvironmentVariables: []string{"POSTGRES_PASSWORD=instant", "POSTGRES_REPLICA_SET=3"},
+				StrictEnv:            true,
+			},
+			expectedErrorString: "POSTGRES_REPLICA_SET (did you mean POSTGRES_REPLICA_SETS?): " + dependency.ErrUndeclaredEnvVars.Error(),
+		},
+		// case: required env vars must be set
+		{
+			packageSpec:         core.PackageSpec{DeployCommand: "up"},
+			expectedErrorString: "POSTGRES_PASSWORD (required by database-postgres): " + dependency.ErrMissingRequiredEnvVars.Error(),
+		},
+		// case: env vars are not checked when taking packages down
+		{
+			packageSpec: core.PackageSpec{DeployCommand: "down", EnvironmentVariables: []string{"UNKNOWN=1"}, StrictEnv: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		var b bytes.Buffer
+		err := checkEnvVars(&b, graph, order, &tc.packageSpec)
+		if tc.expectedErrorString != "" {
+			require.NotNil(t, err)
+			require.Equal(t, tc.expectedErrorString, err.Error())
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		require.Equal(t, tc.expectedOutput, b.String())
+	}
+}
@@ -0,0 +1,137 @@
+package deploy
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Matches the line printed by instant.ts when a package script fails, eg.
+// "❌ Script core/swarm.sh returned an error"
+var failedScriptRegex = regexp.MustCompile(`❌ Script (\S+) returned an error`)
+
+// unknownExitCode is the exit code of a deployment container that was removed
+// before its exit status could be read
+const unknownExitCode = -1
+
+// DeploymentError is returned by LaunchDeploymentContainer when the deployment
+// container exits with a non-zero status code or reports failing package scripts
+type DeploymentError struct {
+	ExitCode      int64
+	FailedScripts []string
+}
+
+func (e *DeploymentError) Error() string {
+	message := fmt.Sprintf("deployment container exited with status %d", e.ExitCode)
+	if e.ExitCode == unknownExitCode {
+		message = "deployment container exited with an unknown status"
+	}
+	if len(e.FailedScripts) > 0 {
+		message += fmt.Sprintf(", failed packages: %s", strings.Join(e.FailedPackages(), ", "))
+	}
+
+	return message
+}
+
+// FailedPackages returns the package ids derived from the package roots of the
+// failed scripts. instant.ts runs the scripts of a package from its root, or
+// from the docker or kubernetes/main directories in it.
+func (e *DeploymentError) FailedPackages() []string {
+	var packages []string
+	for _, script := range e.FailedScripts {
+		root := path.Dir(script)
+		for _, scriptDir := range []string{"/kubernetes/main", "/docker"} {
+			root = strings.TrimSuffix(root, scriptDir)
+		}
+		packages = append(packages, path.Base(root))
+	}
+
+	return packages
+}
+
+// scriptFailureWriter records the scripts reported as failed in the output of
+// the deployment container
+type scriptFailureWriter struct {
+	mu            sync.Mutex
+	buffer        []byte
+	failedScripts []string
+}
+
+func (w *scriptFailureWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buffer = append(w.buffer, p...)
+	for {
+		i := bytes.IndexByte(w.buffer, '\n')
+		if i < 0 {
+			break
+		}
+
+		w.scanLine(w.buffer[:i])
+		w.buffer = w.buffer[i+1:]
+	}
+
+	return len(p), nil
+}
+
+func (w *scriptFailureWriter) scanLine(line []byte) {
+	match := failedScriptRegex.FindSubmatch(line)
+	if match != nil {
+		w.failedScripts = append(w.failedScripts, string(match[1]))
+	}
+}
+
+// FailedScripts returns the failed scripts found so far, including any
+// unterminated trailing line
+func (w *scriptFailureWriter) FailedScripts() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buffer) > 0 {
+		w.scanLine(w.buffer)
+		w.buffer = nil
+	}
+
+	return w.failedScripts
+}
+
+// CustomPackageError is returned by LaunchDeploymentContainer when one or more custom packages
+// could not be fetched or copied into the deployment container
+type CustomPackageError struct {
+	Failures map[string]error
+}
+
+func (e *CustomPackageError) Error() string {
+	var failures []string
+	for _, name := range e.names() {
+		failures = append(failures, fmt.Sprintf("%s: %v", name, e.Failures[name]))
+	}
+
+	return fmt.Sprintf("%d custom package(s) failed: %s", len(e.Failures), strings.Join(failures, "; "))
+}
+
+// Unwrap returns the failures in the order of their package names, so that the exit code of the
+// first failure that has one is the one the CLI terminates with
+func (e *CustomPackageError) Unwrap() []error {
+	var errs []error
+	for _, name := range e.names() {
+		errs = append(errs, e.Failures[name])
+	}
+
+	return errs
+}
+
+func (e *CustomPackageError) names() []string {
+	names := make([]string, 0, len(e.Failures))
+	for name := range e.Failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
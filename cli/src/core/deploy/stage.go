@@ -0,0 +1,162 @@
+package deploy
+
+import (
+	"context"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"cli/core"
+	"cli/core/dependency"
+	"cli/util/docker"
+	"cli/util/file"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/luno/jettison/errors"
+)
+
+// StagedPackage is a package of the config image or a custom package, whose files may be copied to
+// a local directory
+type StagedPackage struct {
+	Metadata dependency.PackageMetadata
+	// Dir is the local directory of the package, empty if only its metadata was read
+	Dir string
+	// DeployedDir is the directory of the package in the deployment container
+	DeployedDir string
+}
+
+// StagePackages fetches the custom packages of the package spec into stagingDir, writing their
+// status to out, copies the packages of the config image next to them and returns the staged
+// packages in the order the deployment container reads them, see appendCustomPackages
+func StagePackages(ctx context.Context, out io.Writer, packageSpec *core.PackageSpec, config *core.Config, stagingDir string) ([]StagedPackage, error) {
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		return nil, err
+	}
+	defer cli.Close()
+
+	concurrency, err := getConcurrency(packageSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	stagedPackages, err := stageCustomPackages(ctx, out, packageSpec.CustomPackages, concurrency, customStagingDir(stagingDir))
+	if err != nil {
+		return nil, err
+	}
+
+	imageDir := filepath.Join(stagingDir, "image")
+	err = copyImagePackages(ctx, cli, config.Image, imageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	packages, err := dependency.Discover(imageDir, dependency.MaxDepth, "image "+config.Image)
+	if err != nil {
+		return nil, err
+	}
+	var staged []StagedPackage
+	for _, pack := range packages {
+		dir := path.Dir(pack.Path)
+		staged = append(staged, StagedPackage{Metadata: pack, Dir: filepath.Join(imageDir, dir), DeployedDir: path.Join(instantDir, dir)})
+	}
+
+	return appendCustomPackages(packageSpec, staged, stagedPackages)
+}
+
+// customStagingDir is the directory of stagingDir that custom packages are fetched into
+func customStagingDir(stagingDir string) string {
+	return filepath.Join(stagingDir, "custom")
+}
+
+// discoverPackages reads the metadata of the packages of the config image, without copying their
+// files, and returns them followed by the packages of the staged custom packages, see
+// appendCustomPackages
+func discoverPackages(ctx context.Context, cli client.ContainerAPIClient, packageSpec *core.PackageSpec, config *core.Config, stagedPackages map[string]string) ([]StagedPackage, error) {
+	packages, err := imagePackages(ctx, cli, config.Image)
+	if err != nil {
+		return nil, err
+	}
+	var discovered []StagedPackage
+	for _, pack := range packages {
+		discovered = append(discovered, StagedPackage{Metadata: pack, DeployedDir: path.Join(instantDir, path.Dir(pack.Path))})
+	}
+
+	return appendCustomPackages(packageSpec, discovered, stagedPackages)
+}
+
+// appendCustomPackages appends the packages of the staged custom packages to the packages of the
+// config image, in the order the deployment container reads them. Custom packages are copied over
+// the packages of the image, so they come last to replace image packages with the same id.
+func appendCustomPackages(packageSpec *core.PackageSpec, staged []StagedPackage, stagedPackages map[string]string) ([]StagedPackage, error) {
+	names := make([]string, 0, len(stagedPackages))
+	for name := range stagedPackages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		customPackages, err := dependency.Discover(stagedPackages[name], dependency.MaxDepth-1, customPackageSource(packageSpec, name))
+		if err != nil {
+			return nil, err
+		}
+		for _, pack := range customPackages {
+			dir := path.Dir(pack.Path)
+			staged = append(staged, StagedPackage{Metadata: pack, Dir: filepath.Join(stagedPackages[name], dir), DeployedDir: path.Join(instantDir, name, dir)})
+		}
+	}
+
+	return staged, nil
+}
+
+// imagePackages reads the metadata of the packages bundled in image from a container that is
+// created, but never started, for the purpose. Only the metadata files are read from the stream of
+// the packages directory.
+func imagePackages(ctx context.Context, cli client.ContainerAPIClient, image string) ([]dependency.PackageMetadata, error) {
+	created, err := cli.ContainerCreate(ctx, &container.Config{Image: image}, nil, nil, nil, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	defer cli.ContainerRemove(context.Background(), created.ID, container.RemoveOptions{Force: true})
+
+	reader, _, err := cli.CopyFromContainer(ctx, created.ID, instantDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	defer reader.Close()
+
+	return dependency.DiscoverTar(reader, dependency.MaxDepth, "image "+image)
+}
+
+// copyImagePackages copies the packages bundled in image to dir, from a container that is created,
+// but never started, for the purpose
+func copyImagePackages(ctx context.Context, cli client.ContainerAPIClient, image, dir string) error {
+	created, err := cli.ContainerCreate(ctx, &container.Config{Image: image}, nil, nil, nil, "")
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer cli.ContainerRemove(context.Background(), created.ID, container.RemoveOptions{Force: true})
+
+	reader, _, err := cli.CopyFromContainer(ctx, created.ID, instantDir)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer reader.Close()
+
+	archive, err := os.CreateTemp("", "instant-packages-*.tar")
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	_, err = io.Copy(archive, reader)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	return file.Extract(archive.Name(), dir, file.ExtractOptions{StripTopLevelDir: true})
+}
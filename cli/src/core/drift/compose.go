@@ -0,0 +1,546 @@
+package drift
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cli/core/exitcode"
+	"cli/core/interpolate"
+	"cli/util/docker"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/luno/jettison/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// imageLabel is the service label docker stack deploy sets to the image of the compose file
+const imageLabel = "com.docker.stack.image"
+
+// composeFile holds the parts of a compose file that are compared with deployed services
+type composeFile struct {
+	Services map[string]*composeService `yaml:"services"`
+	Networks map[string]*composeObject  `yaml:"networks"`
+	Volumes  map[string]*composeObject  `yaml:"volumes"`
+}
+
+type composeService struct {
+	Image       string          `yaml:"image"`
+	Environment mappingOrList   `yaml:"environment"`
+	Ports       []composePort   `yaml:"ports"`
+	Volumes     []composeVolume `yaml:"volumes"`
+	Networks    networkList     `yaml:"networks"`
+	Deploy      composeDeploy   `yaml:"deploy"`
+}
+
+type composeDeploy struct {
+	Mode     string        `yaml:"mode"`
+	Replicas *uint64       `yaml:"replicas"`
+	Labels   mappingOrList `yaml:"labels"`
+}
+
+// composeObject is a top-level network or volume, named after the stack unless it has a name or is
+// external
+type composeObject struct {
+	Name     string   `yaml:"name"`
+	External external `yaml:"external"`
+}
+
+type external struct {
+	External bool
+	Name     string
+}
+
+func (e *external) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		var legacy struct {
+			Name string `yaml:"name"`
+		}
+		e.External = true
+		return node.Decode(&legacy)
+	}
+
+	return node.Decode(&e.External)
+}
+
+// mappingOrList is a mapping of names to values, written as a mapping or as a list of name=value
+// entries. Names without a value are nil.
+type mappingOrList map[string]*string
+
+func (m *mappingOrList) UnmarshalYAML(node *yaml.Node) error {
+	*m = make(mappingOrList)
+
+	if node.Kind == yaml.SequenceNode {
+		var entries []string
+		err := node.Decode(&entries)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			name, value, ok := strings.Cut(entry, "=")
+			if ok {
+				(*m)[name] = &value
+			} else {
+				(*m)[name] = nil
+			}
+		}
+		return nil
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return errors.New(fmt.Sprintf("line %d: expected a mapping or a list", node.Line))
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		name, value := node.Content[i].Value, node.Content[i+1]
+		if value.Tag == "!!null" {
+			(*m)[name] = nil
+			continue
+		}
+		s := value.Value
+		(*m)[name] = &s
+	}
+
+	return nil
+}
+
+// networkList is the networks of a service, written as a list or as a mapping to their options
+type networkList []string
+
+func (n *networkList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		return node.Decode((*[]string)(n))
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return errors.New(fmt.Sprintf("line %d: expected a mapping or a list", node.Line))
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		*n = append(*n, node.Content[i].Value)
+	}
+	sort.Strings(*n)
+
+	return nil
+}
+
+// composePort is a published port, in the short syntax [host:]published:target[/protocol] or the
+// long syntax. The short syntax may hold ranges of ports.
+type composePort struct {
+	Ports []swarm.PortConfig
+}
+
+func (p *composePort) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		var long struct {
+			Target    uint32 `yaml:"target"`
+			Published string `yaml:"published"`
+			Protocol  string `yaml:"protocol"`
+			Mode      string `yaml:"mode"`
+		}
+		err := node.Decode(&long)
+		if err != nil {
+			return err
+		}
+		published, err := parsePort(long.Published)
+		if err != nil {
+			return err
+		}
+		p.Ports = []swarm.PortConfig{{
+			TargetPort:    long.Target,
+			PublishedPort: published,
+			Protocol:      swarm.PortConfigProtocol(long.Protocol),
+			PublishMode:   swarm.PortConfigPublishMode(long.Mode),
+		}}
+		return nil
+	}
+
+	spec := node.Value
+	spec, protocol, _ := strings.Cut(spec, "/")
+	parts := strings.Split(spec, ":")
+	target := parts[len(parts)-1]
+	published := ""
+	if len(parts) > 1 {
+		published = parts[len(parts)-2]
+	}
+
+	targets, err := parsePortRange(target)
+	if err != nil {
+		return errors.Wrap(err, node.Value)
+	}
+	publishedPorts := make([]uint32, len(targets))
+	if published != "" {
+		publishedPorts, err = parsePortRange(published)
+		if err != nil {
+			return errors.Wrap(err, node.Value)
+		}
+		if len(publishedPorts) != len(targets) {
+			return errors.New("port ranges don't match: " + node.Value)
+		}
+	}
+
+	for i := range targets {
+		p.Ports = append(p.Ports, swarm.PortConfig{
+			TargetPort:    targets[i],
+			PublishedPort: publishedPorts[i],
+			Protocol:      swarm.PortConfigProtocol(protocol),
+		})
+	}
+
+	return nil
+}
+
+func parsePort(s string) (uint32, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	port, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return 0, errors.New("invalid port: " + s)
+	}
+
+	return uint32(port), nil
+}
+
+func parsePortRange(s string) ([]uint32, error) {
+	start, end, isRange := strings.Cut(s, "-")
+	first, err := parsePort(start)
+	if err != nil {
+		return nil, err
+	}
+	if !isRange {
+		return []uint32{first}, nil
+	}
+
+	last, err := parsePort(end)
+	if err != nil || last < first {
+		return nil, errors.New("invalid port range: " + s)
+	}
+	var ports []uint32
+	for port := first; port <= last; port++ {
+		ports = append(ports, port)
+	}
+
+	return ports, nil
+}
+
+// composeVolume is a mount of a service, in the short syntax [source:]target[:mode] or the long
+// syntax
+type composeVolume struct {
+	Type     string `yaml:"type"`
+	Source   string `yaml:"source"`
+	Target   string `yaml:"target"`
+	ReadOnly bool   `yaml:"read_only"`
+}
+
+func (v *composeVolume) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		type long composeVolume
+		return node.Decode((*long)(v))
+	}
+
+	parts := strings.Split(node.Value, ":")
+	switch len(parts) {
+	case 1:
+		v.Target = parts[0]
+	case 2:
+		v.Source, v.Target = parts[0], parts[1]
+	default:
+		v.Source, v.Target = parts[0], parts[1]
+		v.ReadOnly = strings.Contains(","+parts[2]+",", ",ro,")
+	}
+
+	v.Type = string(mount.TypeVolume)
+	if strings.HasPrefix(v.Source, "/") || strings.HasPrefix(v.Source, ".") || strings.HasPrefix(v.Source, "~") {
+		v.Type = string(mount.TypeBind)
+	}
+
+	return nil
+}
+
+func invalidComposeFile(detail string) error {
+	return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrInvalidComposeFile, detail), "Fix the compose file at the reported line, or set the env vars it requires with --env-var, --env-file or a profile")
+}
+
+// loadCompose reads the compose files of a package, interpolating their values with environment,
+// and merges them in order
+func loadCompose(files []string, environment map[string]string) (*composeFile, error) {
+	lookup := func(name string) (string, bool) {
+		value, ok := environment[name]
+		return value, ok
+	}
+
+	merged := &composeFile{
+		Services: make(map[string]*composeService),
+		Networks: make(map[string]*composeObject),
+		Volumes:  make(map[string]*composeObject),
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+
+		var document yaml.Node
+		err = yaml.Unmarshal(data, &document)
+		if err != nil {
+			return nil, invalidComposeFile(file + ": " + err.Error())
+		}
+		err = interpolateNode(&document, lookup)
+		if err != nil {
+			return nil, invalidComposeFile(file + ": " + err.Error())
+		}
+
+		var compose composeFile
+		err = document.Decode(&compose)
+		if err != nil {
+			return nil, invalidComposeFile(file + ": " + err.Error())
+		}
+		merged.merge(&compose)
+	}
+
+	return merged, nil
+}
+
+// interpolateNode replaces the variables in the values of node and its children, see
+// interpolate.String
+func interpolateNode(node *yaml.Node, lookup interpolate.Lookup) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		value, err := interpolate.String(node.Value, lookup)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("line %d", node.Line))
+		}
+		if value != node.Value {
+			node.Value = value
+			if node.Style == 0 {
+				node.Tag = ""
+			}
+		}
+
+	case yaml.MappingNode:
+		// Keys are not interpolated
+		for i := 1; i < len(node.Content); i += 2 {
+			err := interpolateNode(node.Content[i], lookup)
+			if err != nil {
+				return err
+			}
+		}
+
+	default:
+		for _, child := range node.Content {
+			err := interpolateNode(child, lookup)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// merge merges overlay into c the way docker stack deploy merges several compose files: values
+// are replaced, and mappings, ports and volumes are merged by name, port and target
+func (c *composeFile) merge(overlay *composeFile) {
+	for name, object := range overlay.Networks {
+		c.Networks[name] = object
+	}
+	for name, object := range overlay.Volumes {
+		c.Volumes[name] = object
+	}
+
+	for name, service := range overlay.Services {
+		if service == nil {
+			service = &composeService{}
+		}
+		base, ok := c.Services[name]
+		if !ok {
+			c.Services[name] = service
+			continue
+		}
+
+		if service.Image != "" {
+			base.Image = service.Image
+		}
+		base.Environment = mergeMappings(base.Environment, service.Environment)
+		base.Deploy.Labels = mergeMappings(base.Deploy.Labels, service.Deploy.Labels)
+		if service.Deploy.Mode != "" {
+			base.Deploy.Mode = service.Deploy.Mode
+		}
+		if service.Deploy.Replicas != nil {
+			base.Deploy.Replicas = service.Deploy.Replicas
+		}
+
+		for _, network := range service.Networks {
+			if !containsString(base.Networks, network) {
+				base.Networks = append(base.Networks, network)
+			}
+		}
+
+		base.Ports = append(base.Ports, service.Ports...)
+
+		for _, volume := range service.Volumes {
+			replaced := false
+			for i := range base.Volumes {
+				if base.Volumes[i].Target == volume.Target {
+					base.Volumes[i] = volume
+					replaced = true
+				}
+			}
+			if !replaced {
+				base.Volumes = append(base.Volumes, volume)
+			}
+		}
+	}
+}
+
+func mergeMappings(base, overlay mappingOrList) mappingOrList {
+	merged := make(mappingOrList)
+	for name, value := range base {
+		merged[name] = value
+	}
+	for name, value := range overlay {
+		merged[name] = value
+	}
+
+	return merged
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// serviceSpecs converts the services of c to the parts of the swarm service specs docker stack
+// deploy creates for them that are compared, by service name
+func (c *composeFile) serviceSpecs(pack Package) map[string]swarm.ServiceSpec {
+	specs := make(map[string]swarm.ServiceSpec)
+	for name, service := range c.Services {
+		if service == nil {
+			service = &composeService{}
+		}
+		scoped := pack.Id + "_" + name
+
+		labels := map[string]string{
+			docker.StackNamespaceLabel: pack.Id,
+			imageLabel:                 service.Image,
+		}
+		for label, value := range service.Deploy.Labels {
+			if value != nil {
+				labels[label] = *value
+			}
+		}
+
+		spec := swarm.ServiceSpec{
+			Annotations: swarm.Annotations{Name: scoped, Labels: labels},
+			TaskTemplate: swarm.TaskSpec{
+				ContainerSpec: &swarm.ContainerSpec{
+					Image:  service.Image,
+					Env:    serviceEnv(service.Environment, pack.Environment),
+					Mounts: c.mounts(service, pack),
+				},
+				Networks: c.networks(service, pack),
+			},
+			Mode:         serviceMode(service.Deploy),
+			EndpointSpec: &swarm.EndpointSpec{},
+		}
+		// Ports published by several of the layered compose files are published once
+		published := make(map[swarm.PortConfig]bool)
+		for _, port := range service.Ports {
+			for _, config := range port.Ports {
+				if !published[config] {
+					published[config] = true
+					spec.EndpointSpec.Ports = append(spec.EndpointSpec.Ports, config)
+				}
+			}
+		}
+
+		specs[scoped] = spec
+	}
+
+	return specs
+}
+
+// serviceEnv returns the env vars of a service, taking the values of the ones without a value from
+// environment
+func serviceEnv(env mappingOrList, environment map[string]string) []string {
+	var envVars []string
+	for name, value := range env {
+		if value != nil {
+			envVars = append(envVars, name+"="+*value)
+		} else if value, ok := environment[name]; ok {
+			envVars = append(envVars, name+"="+value)
+		}
+	}
+	sort.Strings(envVars)
+
+	return envVars
+}
+
+func serviceMode(deploy composeDeploy) swarm.ServiceMode {
+	switch deploy.Mode {
+	case docker.ModeGlobal:
+		return swarm.ServiceMode{Global: &swarm.GlobalService{}}
+	case docker.ModeReplicatedJob:
+		return swarm.ServiceMode{ReplicatedJob: &swarm.ReplicatedJob{}}
+	case docker.ModeGlobalJob:
+		return swarm.ServiceMode{GlobalJob: &swarm.GlobalJob{}}
+	}
+
+	return swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: deploy.Replicas}}
+}
+
+func (c *composeFile) mounts(service *composeService, pack Package) []mount.Mount {
+	var mounts []mount.Mount
+	for _, volume := range service.Volumes {
+		m := mount.Mount{Type: mount.Type(volume.Type), Source: volume.Source, Target: volume.Target, ReadOnly: volume.ReadOnly}
+		switch {
+		case m.Type == mount.TypeBind && !path.IsAbs(m.Source):
+			// Bind mounts are deployed from the directory of the package in the deployment container
+			m.Source = path.Join(pack.DeployedDir, m.Source)
+		case m.Type == mount.TypeVolume && m.Source != "":
+			m.Source = c.objectName(c.Volumes, m.Source, pack)
+		}
+		mounts = append(mounts, m)
+	}
+
+	return mounts
+}
+
+func (c *composeFile) networks(service *composeService, pack Package) []swarm.NetworkAttachmentConfig {
+	networks := service.Networks
+	if len(networks) == 0 {
+		networks = []string{"default"}
+	}
+
+	var attachments []swarm.NetworkAttachmentConfig
+	for _, network := range networks {
+		attachments = append(attachments, swarm.NetworkAttachmentConfig{Target: c.objectName(c.Networks, network, pack)})
+	}
+
+	return attachments
+}
+
+// objectName returns the name of a network or volume as docker stack deploy creates or looks it up
+func (c *composeFile) objectName(objects map[string]*composeObject, name string, pack Package) string {
+	object := objects[name]
+	switch {
+	case object == nil:
+	case object.External.Name != "":
+		return object.External.Name
+	case object.Name != "":
+		return object.Name
+	case object.External.External:
+		return name
+	}
+
+	return pack.Id + "_" + name
+}
@@ -0,0 +1,369 @@
+package drift
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cli/core/dependency"
+	"cli/core/env"
+	"cli/core/exitcode"
+	"cli/util/docker"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/client"
+	"github.com/luno/jettison/errors"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+var (
+	ErrInvalidComposeFile = errors.New("invalid package compose file")
+	ErrDrift              = errors.New("deployed services differ from the compose files of their packages")
+)
+
+// Package is a package whose compose files are compared with its deployed services
+type Package struct {
+	Id string
+	// Dir is the local directory of the package
+	Dir string
+	// DeployedDir is the directory of the package in the deployment container, which the sources of
+	// bind mounts are relative to
+	DeployedDir string
+	// Environment holds the env vars the compose files are interpolated with
+	Environment map[string]string
+}
+
+// Environment returns the env vars the deployment container deploys a package with: the defaults
+// of its metadata, overridden by envVars in KEY=value form
+func Environment(metadata dependency.PackageMetadata, envVars []string) map[string]string {
+	environment := make(map[string]string)
+	for name, value := range metadata.EnvironmentVariables {
+		environment[name] = fmt.Sprint(value)
+	}
+	for _, envVar := range envVars {
+		name, value, ok := strings.Cut(envVar, "=")
+		if ok {
+			environment[name] = value
+		}
+	}
+
+	return environment
+}
+
+const (
+	// BaseComposeFile is the compose file of a package that docker::deploy_service layers the other
+	// compose files of the package onto
+	BaseComposeFile = "docker-compose.yml"
+	// DevComposeFile is the overlay of the base compose file a package is deployed with in dev mode
+	DevComposeFile = "docker-compose.dev.yml"
+)
+
+// Options select the compose files the services of packages are expected from
+type Options struct {
+	// Overlays are the names of the compose files layered onto the base compose file, in order, by
+	// the packages that have them, eg. docker-compose.cluster.yml
+	Overlays []string
+	// Dev layers the dev compose file last, as packages do in dev mode
+	Dev bool
+}
+
+// Report compares the compose files of packages with their deployed services, writing the diffs of
+// the services that drifted to w. An error wrapping ErrDrift is returned if any service drifted.
+func Report(ctx context.Context, w io.Writer, packages []Package, options Options) error {
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	networkNames, err := NetworkNames(ctx, cli)
+	if err != nil {
+		return err
+	}
+
+	var diffs []ServiceDiff
+	for _, pack := range packages {
+		expected, err := ExpectedServices(pack, options)
+		if err != nil {
+			return err
+		}
+		deployed, err := docker.ListStackServices(ctx, cli, pack.Id, false)
+		if err != nil {
+			return err
+		}
+		diffs = append(diffs, Compare(pack.Id, expected, deployed, networkNames)...)
+	}
+
+	return Render(w, diffs)
+}
+
+// ServiceDiff is the difference between the spec of a service in the compose files of its package
+// and its deployed spec
+type ServiceDiff struct {
+	Package string
+	Service string
+	// Diff is the unified diff from the compose files to the deployed spec, empty if they match
+	Diff string
+}
+
+// ComposeFiles returns the compose files of the package in dir in the order docker::deploy_service
+// layers them: the base compose file, the overlays of options the package has and the dev compose
+// file with options.Dev. A package without a base compose file has no compose files.
+func ComposeFiles(dir string, options Options) ([]string, error) {
+	names := []string{BaseComposeFile}
+	names = append(names, options.Overlays...)
+	if options.Dev {
+		names = append(names, DevComposeFile)
+	}
+
+	var files []string
+	for _, name := range names {
+		file := filepath.Join(dir, name)
+		_, err := os.Stat(file)
+		if os.IsNotExist(err) {
+			if name == BaseComposeFile {
+				return nil, nil
+			}
+			continue
+		} else if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// ExpectedServices loads the compose files of pack, interpolating its environment, and converts
+// their services to swarm service specs as docker stack deploy does, by service name
+func ExpectedServices(pack Package, options Options) (map[string]swarm.ServiceSpec, error) {
+	files, err := ComposeFiles(pack.Dir, options)
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+
+	compose, err := loadCompose(files, pack.Environment)
+	if err != nil {
+		return nil, err
+	}
+
+	return compose.serviceSpecs(pack), nil
+}
+
+// Compare returns the differences between the expected specs of the services of a package and its
+// deployed services, sorted by service name. networkNames holds the names of networks by id, as
+// deployed services refer to networks by id.
+func Compare(id string, expected map[string]swarm.ServiceSpec, deployed []swarm.Service, networkNames map[string]string) []ServiceDiff {
+	deployedSpecs := make(map[string]swarm.ServiceSpec)
+	names := make(map[string]bool)
+	for _, service := range deployed {
+		deployedSpecs[service.Spec.Name] = service.Spec
+		names[service.Spec.Name] = true
+	}
+	for name := range expected {
+		names[name] = true
+	}
+
+	var sorted []string
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var diffs []ServiceDiff
+	for _, name := range sorted {
+		from, to := name+" (compose files)", name+" (deployed)"
+
+		var a, b []string
+		if spec, ok := expected[name]; ok {
+			a = describe(spec, nil)
+		} else {
+			from = name + " (not in the compose files)"
+		}
+		if spec, ok := deployedSpecs[name]; ok {
+			b = describe(spec, networkNames)
+		} else {
+			to = name + " (not deployed)"
+		}
+
+		diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{A: a, B: b, FromFile: from, ToFile: to, Context: 3})
+		diffs = append(diffs, ServiceDiff{Package: id, Service: name, Diff: diff})
+	}
+
+	return diffs
+}
+
+// Render writes the diffs of the services that drifted from their compose files to w, and returns
+// an error wrapping ErrDrift if there are any
+func Render(w io.Writer, diffs []ServiceDiff) error {
+	var drifted []string
+	for _, diff := range diffs {
+		if diff.Diff == "" {
+			continue
+		}
+		drifted = append(drifted, diff.Service)
+		fmt.Fprint(w, diff.Diff)
+	}
+
+	if len(drifted) > 0 {
+		fmt.Fprintf(w, "> %d of %d service(s) drifted: %s\n", len(drifted), len(diffs), strings.Join(drifted, ", "))
+		err := errors.Wrap(ErrDrift, strings.Join(drifted, ", "))
+		return exitcode.New(exitcode.Drift, err, "Redeploy the packages with 'project up' or 'package up', or update their compose files")
+	}
+	fmt.Fprintf(w, "> No drift in %d service(s)\n", len(diffs))
+
+	return nil
+}
+
+// describe renders the compared fields of spec as lines, sorted within each field. Networks are
+// named with networkNames when it has their id.
+func describe(spec swarm.ServiceSpec, networkNames map[string]string) []string {
+	var lines []string
+
+	if containerSpec := spec.TaskTemplate.ContainerSpec; containerSpec != nil {
+		lines = append(lines, "image: "+normaliseImage(containerSpec.Image))
+
+		lines = append(lines, "env:")
+		envVars := append([]string{}, containerSpec.Env...)
+		sort.Strings(envVars)
+		for _, envVar := range envVars {
+			lines = append(lines, "  "+maskEnvVar(envVar))
+		}
+
+		lines = append(lines, "mounts:")
+		var mounts []string
+		for _, m := range containerSpec.Mounts {
+			description := fmt.Sprintf("%s %s:%s", m.Type, m.Source, m.Target)
+			if m.ReadOnly {
+				description += ":ro"
+			}
+			mounts = append(mounts, description)
+		}
+		sort.Strings(mounts)
+		lines = append(lines, indent(mounts)...)
+	}
+
+	lines = append(lines, "mode: "+describeMode(spec.Mode))
+
+	lines = append(lines, "ports:")
+	if spec.EndpointSpec != nil {
+		var ports []string
+		for _, port := range spec.EndpointSpec.Ports {
+			protocol, publishMode := port.Protocol, port.PublishMode
+			if protocol == "" {
+				protocol = swarm.PortConfigProtocolTCP
+			}
+			if publishMode == "" {
+				publishMode = swarm.PortConfigPublishModeIngress
+			}
+			ports = append(ports, fmt.Sprintf("%d:%d/%s (%s)", port.PublishedPort, port.TargetPort, protocol, publishMode))
+		}
+		sort.Strings(ports)
+		lines = append(lines, indent(ports)...)
+	}
+
+	lines = append(lines, "networks:")
+	var networks []string
+	for _, n := range spec.TaskTemplate.Networks {
+		networks = append(networks, networkName(n, networkNames))
+	}
+	sort.Strings(networks)
+	lines = append(lines, indent(networks)...)
+
+	lines = append(lines, "labels:")
+	var labels []string
+	for key, value := range spec.Labels {
+		if key == imageLabel {
+			value = normaliseImage(value)
+		}
+		labels = append(labels, key+"="+value)
+	}
+	sort.Strings(labels)
+	lines = append(lines, indent(labels)...)
+
+	for i := range lines {
+		lines[i] += "\n"
+	}
+
+	return lines
+}
+
+func indent(lines []string) []string {
+	indented := make([]string, len(lines))
+	for i, line := range lines {
+		indented[i] = "  " + line
+	}
+
+	return indented
+}
+
+// normaliseImage returns image without its digest and with the latest tag if it has no tag, as
+// docker stack deploy pins images to their digest and tags them
+func normaliseImage(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+
+	return reference.FamiliarString(reference.TagNameOnly(named))
+}
+
+// maskEnvVar masks the value of secret env vars, with a fingerprint of the value to tell changed
+// values apart
+func maskEnvVar(envVar string) string {
+	name, value, ok := strings.Cut(envVar, "=")
+	if !ok || value == "" || !env.IsSecret(name) {
+		return envVar
+	}
+
+	sum := sha256.Sum256([]byte(value))
+
+	return fmt.Sprintf("%s=******** (sha256 %x)", name, sum[:4])
+}
+
+func describeMode(mode swarm.ServiceMode) string {
+	switch {
+	case mode.Global != nil:
+		return docker.ModeGlobal
+	case mode.ReplicatedJob != nil:
+		return docker.ModeReplicatedJob
+	case mode.GlobalJob != nil:
+		return docker.ModeGlobalJob
+	case mode.Replicated != nil && mode.Replicated.Replicas != nil:
+		return fmt.Sprintf("%s, %d replica(s)", docker.ModeReplicated, *mode.Replicated.Replicas)
+	}
+
+	// Swarm deploys a single replica if none are set
+	return docker.ModeReplicated + ", 1 replica(s)"
+}
+
+func networkName(attachment swarm.NetworkAttachmentConfig, networkNames map[string]string) string {
+	if name, ok := networkNames[attachment.Target]; ok {
+		return name
+	}
+
+	return attachment.Target
+}
+
+// NetworkNames returns the names of the networks of the Docker daemon by id
+func NetworkNames(ctx context.Context, cli client.NetworkAPIClient) (map[string]string, error) {
+	networks, err := cli.NetworkList(ctx, network.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	names := make(map[string]string)
+	for _, n := range networks {
+		names[n.ID] = n.Name
+	}
+
+	return names, nil
+}
@@ -0,0 +1,318 @@
+package drift
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cli/core/exitcode"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+const postgresCompose = `services:
+  postgres-1:
+    image: postgres:${POSTGRES_VERSION}
+    environment:
+      POSTGRES_USER: ${POSTGRES_USER}
+      POSTGRES_PASSWORD: ${POSTGRES_PASSWORD}
+      PGDATA:
+    volumes:
+      - pg-data:/var/lib/postgresql/data
+      - ./config/postgresql.conf:/etc/postgresql.conf:ro
+    networks:
+      - postgres
+    deploy:
+      replicas: ${POSTGRES_REPLICAS:-1}
+      labels:
+        - backup=daily
+
+volumes:
+  pg-data:
+
+networks:
+  postgres:
+    name: postgres_public
+    external: true
+`
+
+const postgresComposeDev = `services:
+  postgres-1:
+    ports:
+      - target: 5432
+        published: 5432
+        mode: host
+`
+
+const postgresComposeCluster = `services:
+  postgres-1:
+    environment:
+      REPMGR_PARTNER_NODES: postgres-1,postgres-2
+    deploy:
+      placement:
+        max_replicas_per_node: 1
+      labels:
+        - backup=hourly
+`
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		jtest.RequireNil(t, err)
+	}
+
+	return dir
+}
+
+func TestComposeFiles(t *testing.T) {
+	type cases struct {
+		files         []string
+		options       Options
+		expectedFiles []string
+	}
+
+	testCases := []cases{
+		// case: only the base compose file without options
+		{
+			files:         []string{"docker-compose.yml", "docker-compose.dev.yml", "docker-compose.cluster.yml", "package-metadata.json"},
+			expectedFiles: []string{"docker-compose.yml"},
+		},
+		// case: overlays in order, then the dev compose file
+		{
+			files:         []string{"docker-compose.yml", "docker-compose.dev.yml", "docker-compose.cluster.yml", "docker-compose.tls.yml"},
+			options:       Options{Overlays: []string{"docker-compose.tls.yml", "docker-compose.cluster.yml"}, Dev: true},
+			expectedFiles: []string{"docker-compose.yml", "docker-compose.tls.yml", "docker-compose.cluster.yml", "docker-compose.dev.yml"},
+		},
+		// case: overlays the package doesn't have are skipped
+		{
+			files:         []string{"docker-compose.yml"},
+			options:       Options{Overlays: []string{"docker-compose.cluster.yml"}, Dev: true},
+			expectedFiles: []string{"docker-compose.yml"},
+		},
+		// case: no compose files without a base compose file
+		{
+			files:   []string{"docker-compose.cluster.yml", "docker-compose.dev.yml"},
+			options: Options{Overlays: []string{"docker-compose.cluster.yml"}, Dev: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		files := make(map[string]string)
+		for _, name := range tc.files {
+			files[name] = ""
+		}
+		dir := writeFiles(t, files)
+
+		var expectedFiles []string
+		for _, name := range tc.expectedFiles {
+			expectedFiles = append(expectedFiles, filepath.Join(dir, name))
+		}
+
+		composeFiles, err := ComposeFiles(dir, tc.options)
+		jtest.RequireNil(t, err)
+		require.Equal(t, expectedFiles, composeFiles)
+	}
+}
+
+func TestExpectedServices(t *testing.T) {
+	replicas := uint64(2)
+	pack := Package{
+		Id:          "database-postgres",
+		DeployedDir: "/instant/database-postgres",
+		Environment: map[string]string{
+			"POSTGRES_VERSION":  "16.3",
+			"POSTGRES_USER":     "postgres",
+			"POSTGRES_PASSWORD": "instant101",
+			"POSTGRES_REPLICAS": "2",
+			"PGDATA":            "/var/lib/postgresql/data/pgdata",
+		},
+	}
+
+	type cases struct {
+		files         map[string]string
+		options       Options
+		expectedEnv   []string
+		expectedPorts []swarm.PortConfig
+		expectedLabel string
+		expectedErr   error
+	}
+
+	testCases := []cases{
+		// case: compose file with its dev and cluster overlays left out
+		{
+			files: map[string]string{
+				"docker-compose.yml":         postgresCompose,
+				"docker-compose.dev.yml":     postgresComposeDev,
+				"docker-compose.cluster.yml": postgresComposeCluster,
+			},
+		},
+		// case: compose file with its dev file
+		{
+			files:         map[string]string{"docker-compose.yml": postgresCompose, "docker-compose.dev.yml": postgresComposeDev},
+			options:       Options{Dev: true},
+			expectedPorts: []swarm.PortConfig{{TargetPort: 5432, PublishedPort: 5432, PublishMode: swarm.PortConfigPublishModeHost}},
+		},
+		// case: cluster overlay merged onto the compose file, not deployed as a stack of its own
+		{
+			files: map[string]string{
+				"docker-compose.yml":         postgresCompose,
+				"docker-compose.dev.yml":     postgresComposeDev,
+				"docker-compose.cluster.yml": postgresComposeCluster,
+			},
+			options:       Options{Overlays: []string{"docker-compose.cluster.yml"}, Dev: true},
+			expectedEnv:   []string{"REPMGR_PARTNER_NODES=postgres-1,postgres-2"},
+			expectedPorts: []swarm.PortConfig{{TargetPort: 5432, PublishedPort: 5432, PublishMode: swarm.PortConfigPublishModeHost}},
+			expectedLabel: "hourly",
+		},
+		// case: invalid compose file
+		{
+			files:       map[string]string{"docker-compose.yml": "services: ["},
+			expectedErr: ErrInvalidComposeFile,
+		},
+	}
+
+	for _, tc := range testCases {
+		pack.Dir = writeFiles(t, tc.files)
+
+		specs, err := ExpectedServices(pack, tc.options)
+		if tc.expectedErr != nil {
+			jtest.Require(t, tc.expectedErr, err)
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		env := append([]string{"PGDATA=/var/lib/postgresql/data/pgdata", "POSTGRES_PASSWORD=instant101", "POSTGRES_USER=postgres"}, tc.expectedEnv...)
+		label := "daily"
+		if tc.expectedLabel != "" {
+			label = tc.expectedLabel
+		}
+
+		require.Equal(t, map[string]swarm.ServiceSpec{
+			"database-postgres_postgres-1": {
+				Annotations: swarm.Annotations{
+					Name: "database-postgres_postgres-1",
+					Labels: map[string]string{
+						"backup":                     label,
+						"com.docker.stack.image":     "postgres:16.3",
+						"com.docker.stack.namespace": "database-postgres",
+					},
+				},
+				TaskTemplate: swarm.TaskSpec{
+					ContainerSpec: &swarm.ContainerSpec{
+						Image: "postgres:16.3",
+						Env:   env,
+						Mounts: []mount.Mount{
+							{Type: mount.TypeVolume, Source: "database-postgres_pg-data", Target: "/var/lib/postgresql/data"},
+							{Type: mount.TypeBind, Source: "/instant/database-postgres/config/postgresql.conf", Target: "/etc/postgresql.conf", ReadOnly: true},
+						},
+					},
+					Networks: []swarm.NetworkAttachmentConfig{{Target: "postgres_public"}},
+				},
+				Mode:         swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &replicas}},
+				EndpointSpec: &swarm.EndpointSpec{Ports: tc.expectedPorts},
+			},
+		}, specs)
+	}
+}
+
+func TestCompareAndRender(t *testing.T) {
+	replicas := uint64(1)
+	spec := func(image string, env ...string) swarm.ServiceSpec {
+		return swarm.ServiceSpec{
+			Annotations: swarm.Annotations{
+				Name:   "database-postgres_postgres-1",
+				Labels: map[string]string{"com.docker.stack.image": image, "com.docker.stack.namespace": "database-postgres"},
+			},
+			TaskTemplate: swarm.TaskSpec{
+				ContainerSpec: &swarm.ContainerSpec{Image: image, Env: env},
+				Networks:      []swarm.NetworkAttachmentConfig{{Target: "net-1"}},
+			},
+			Mode: swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &replicas}},
+		}
+	}
+	expected := map[string]swarm.ServiceSpec{
+		"database-postgres_postgres-1": spec("postgres:16.3", "POSTGRES_USER=postgres", "POSTGRES_PASSWORD=instant101"),
+	}
+	expected["database-postgres_postgres-1"].TaskTemplate.Networks[0].Target = "postgres_public"
+	networkNames := map[string]string{"net-1": "postgres_public"}
+
+	type cases struct {
+		deployed       []swarm.Service
+		expectedOutput string
+		expectedErr    error
+	}
+
+	testCases := []cases{
+		// case: no drift, with the image pinned to its digest by docker stack deploy
+		{
+			deployed: []swarm.Service{{Spec: spec("postgres:16.3@sha256:d0f363f8366fbc3f52d172c6e76bc27151c3d643b870e1062b4e8bfe65baf609", "POSTGRES_PASSWORD=instant101", "POSTGRES_USER=postgres")}},
+			expectedOutput: `> No drift in 1 service(s)
+`,
+		},
+		// case: hand-edited image and secret env var
+		{
+			deployed: []swarm.Service{{Spec: spec("postgres:16.4", "POSTGRES_PASSWORD=changed", "POSTGRES_USER=postgres")}},
+			expectedOutput: `--- database-postgres_postgres-1 (compose files)
++++ database-postgres_postgres-1 (deployed)
+@@ -1,6 +1,6 @@
+-image: postgres:16.3
++image: postgres:16.4
+ env:
+-  POSTGRES_PASSWORD=******** (sha256 6e198ec5)
++  POSTGRES_PASSWORD=******** (sha256 d67e2e94)
+   POSTGRES_USER=postgres
+ mounts:
+ mode: replicated, 1 replica(s)
+@@ -8,5 +8,5 @@
+ networks:
+   postgres_public
+ labels:
+-  com.docker.stack.image=postgres:16.3
++  com.docker.stack.image=postgres:16.4
+   com.docker.stack.namespace=database-postgres
+> 1 of 1 service(s) drifted: database-postgres_postgres-1
+`,
+			expectedErr: ErrDrift,
+		},
+		// case: service not deployed
+		{
+			expectedOutput: `--- database-postgres_postgres-1 (compose files)
++++ database-postgres_postgres-1 (not deployed)
+@@ -1,12 +0,0 @@
+-image: postgres:16.3
+-env:
+-  POSTGRES_PASSWORD=******** (sha256 6e198ec5)
+-  POSTGRES_USER=postgres
+-mounts:
+-mode: replicated, 1 replica(s)
+-ports:
+-networks:
+-  postgres_public
+-labels:
+-  com.docker.stack.image=postgres:16.3
+-  com.docker.stack.namespace=database-postgres
+> 1 of 1 service(s) drifted: database-postgres_postgres-1
+`,
+			expectedErr: ErrDrift,
+		},
+	}
+
+	for _, tc := range testCases {
+		diffs := Compare("database-postgres", expected, tc.deployed, networkNames)
+
+		var out bytes.Buffer
+		err := Render(&out, diffs)
+		if tc.expectedErr != nil {
+			jtest.Require(t, tc.expectedErr, err)
+			require.Equal(t, exitcode.Drift, exitcode.Classify(err).Code)
+		} else {
+			jtest.RequireNil(t, err)
+		}
+		require.Equal(t, tc.expectedOutput, out.String())
+	}
+}
@@ -0,0 +1,112 @@
+package env
+
+import (
+	"sort"
+	"strings"
+)
+
+// Value is a value of an env var and where it was set, eg. --env-var or an env file
+type Value struct {
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// Variable is an env var with the value that took precedence, and the values it overrode from
+// the most to the least recent
+type Variable struct {
+	Name string `json:"name"`
+	Value
+	Overridden []Value `json:"overridden,omitempty"`
+}
+
+// Set holds env vars with the sources of their values. Values added later override the values
+// added earlier, so sources are added in increasing order of precedence.
+type Set struct {
+	variables map[string]*Variable
+}
+
+func NewSet() *Set {
+	return &Set{variables: make(map[string]*Variable)}
+}
+
+// Add sets the env vars in KEY=value form from source. Entries without a = are ignored.
+func (s *Set) Add(envVars []string, source string) {
+	for _, envVar := range envVars {
+		name, value, ok := strings.Cut(envVar, "=")
+		if !ok {
+			continue
+		}
+		s.Set(name, value, source)
+	}
+}
+
+// Set sets the env var name to value from source
+func (s *Set) Set(name, value, source string) {
+	variable, ok := s.variables[name]
+	if !ok {
+		s.variables[name] = &Variable{Name: name, Value: Value{Value: value, Source: source}}
+		return
+	}
+
+	variable.Overridden = append([]Value{variable.Value}, variable.Overridden...)
+	variable.Value = Value{Value: value, Source: source}
+}
+
+// Variables returns the env vars sorted by name
+func (s *Set) Variables() []Variable {
+	var variables []Variable
+	for _, variable := range s.variables {
+		variables = append(variables, *variable)
+	}
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].Name < variables[j].Name
+	})
+
+	return variables
+}
+
+// Strings returns the env vars in KEY=value form, sorted by name
+func (s *Set) Strings() []string {
+	var envVars []string
+	for _, variable := range s.Variables() {
+		envVars = append(envVars, variable.Name+"="+variable.Value.Value)
+	}
+
+	return envVars
+}
+
+var secretNameParts = []string{"PASSWORD", "PASSWD", "SECRET", "TOKEN", "KEY", "CREDENTIAL", "PRIVATE"}
+
+// IsSecret reports whether the env var name looks like it holds a secret, eg. DB_PASSWORD
+func IsSecret(name string) bool {
+	name = strings.ToUpper(name)
+	for _, part := range secretNameParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Masked returns the variable with its values hidden if its name looks like it holds a secret
+func (v Variable) Masked() Variable {
+	if !IsSecret(v.Name) {
+		return v
+	}
+
+	masked := Variable{Name: v.Name, Value: mask(v.Value)}
+	for _, overridden := range v.Overridden {
+		masked.Overridden = append(masked.Overridden, mask(overridden))
+	}
+
+	return masked
+}
+
+func mask(value Value) Value {
+	if value.Value != "" {
+		value.Value = "********"
+	}
+
+	return value
+}
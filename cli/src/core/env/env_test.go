@@ -0,0 +1,107 @@
+package env
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSet(t *testing.T) {
+	envVars := NewSet()
+	envVars.Add([]string{"LOG_LEVEL=info", "DB_PASSWORD=instant", "INVALID"}, ".env")
+	envVars.Add([]string{"LOG_LEVEL=debug", "URL=http://host?a=b"}, "--env-file .env.dev")
+	envVars.Set("LOG_LEVEL", "warn", "--env-var")
+
+	require.Equal(t, []Variable{
+		{Name: "DB_PASSWORD", Value: Value{Value: "instant", Source: ".env"}},
+		{
+			Name:  "LOG_LEVEL",
+			Value: Value{Value: "warn", Source: "--env-var"},
+			Overridden: []Value{
+				{Value: "debug", Source: "--env-file .env.dev"},
+				{Value: "info", Source: ".env"},
+			},
+		},
+		{Name: "URL", Value: Value{Value: "http://host?a=b", Source: "--env-file .env.dev"}},
+	}, envVars.Variables())
+	require.Equal(t, []string{"DB_PASSWORD=instant", "LOG_LEVEL=warn", "URL=http://host?a=b"}, envVars.Strings())
+}
+
+func TestRender(t *testing.T) {
+	envVars := NewSet()
+	envVars.Add([]string{"OPENHIM_API_TOKEN=abc", "LOG_LEVEL=info", "EMPTY_SECRET="}, ".env")
+	envVars.Add([]string{"OPENHIM_API_TOKEN=def", "LOG_LEVEL=debug"}, "--env-var")
+
+	type cases struct {
+		format         string
+		expectedOutput string
+		expectedErr    error
+	}
+
+	testCases := []cases{
+		// case: table
+		{
+			format: FormatTable,
+			expectedOutput: `NAME               VALUE     SOURCE     OVERRIDES
+EMPTY_SECRET                 .env       
+LOG_LEVEL          debug     --env-var  info (.env)
+OPENHIM_API_TOKEN  ********  --env-var  ******** (.env)
+`,
+		},
+		// case: json
+		{
+			format: FormatJSON,
+			expectedOutput: `{
+  "variables": [
+    {
+      "name": "EMPTY_SECRET",
+      "value": "",
+      "source": ".env"
+    },
+    {
+      "name": "LOG_LEVEL",
+      "value": "debug",
+      "source": "--env-var",
+      "overridden": [
+        {
+          "value": "info",
+          "source": ".env"
+        }
+      ]
+    },
+    {
+      "name": "OPENHIM_API_TOKEN",
+      "value": "********",
+      "source": "--env-var",
+      "overridden": [
+        {
+          "value": "********",
+          "source": ".env"
+        }
+      ]
+    }
+  ]
+}
+`,
+		},
+		// case: unknown format
+		{
+			format:      "yaml",
+			expectedErr: ErrUnknownFormat,
+		},
+	}
+
+	for _, tc := range testCases {
+		var b bytes.Buffer
+		err := Render(&b, envVars.Variables(), tc.format)
+		if tc.expectedErr != nil {
+			jtest.Require(t, tc.expectedErr, err)
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		require.Equal(t, tc.expectedOutput, b.String())
+	}
+}
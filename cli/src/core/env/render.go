@@ -0,0 +1,69 @@
+package env
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"cli/core/exitcode"
+
+	"github.com/luno/jettison/errors"
+)
+
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+)
+
+var ErrUnknownFormat = errors.New("unknown env output format, use table or json")
+
+// Render writes the env vars in format, with the values of secrets masked
+func Render(w io.Writer, variables []Variable, format string) error {
+	var masked []Variable
+	for _, variable := range variables {
+		masked = append(masked, variable.Masked())
+	}
+
+	switch format {
+	case FormatTable:
+		return renderTable(w, masked)
+	case FormatJSON:
+		return renderJSON(w, masked)
+	}
+
+	return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrUnknownFormat, format), "")
+}
+
+func renderTable(w io.Writer, variables []Variable) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVALUE\tSOURCE\tOVERRIDES")
+	for _, variable := range variables {
+		var overridden []string
+		for _, value := range variable.Overridden {
+			overridden = append(overridden, fmt.Sprintf("%s (%s)", value.Value, value.Source))
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", variable.Name, variable.Value.Value, variable.Source, strings.Join(overridden, ", "))
+	}
+
+	return tw.Flush()
+}
+
+func renderJSON(w io.Writer, variables []Variable) error {
+	if variables == nil {
+		variables = []Variable{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(struct {
+		Variables []Variable `json:"variables"`
+	}{variables})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	return nil
+}
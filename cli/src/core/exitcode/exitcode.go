@@ -0,0 +1,114 @@
+package exitcode
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/client"
+	"github.com/luno/jettison/errors"
+	"github.com/manifoldco/promptui"
+)
+
+// Exit codes of the CLI. These are part of its interface for scripts and must not change.
+const (
+	Success = 0
+	// General is any failure that doesn't fall into one of the categories below
+	General = 1
+	// Drift is a diff that found deployed services that differ from the compose files of their
+	// packages
+	Drift = 2
+	// ConfigInvalid is a config file that can't be read or is missing required fields
+	ConfigInvalid = 3
+	// ValidationFailed is a command-line that doesn't match the config file, eg. an undefined
+	// package or profile
+	ValidationFailed = 4
+	// DockerUnreachable is a Docker daemon that can't be connected to
+	DockerUnreachable = 5
+	// DeploymentFailed is a deployment container or package script that failed
+	DeploymentFailed = 6
+	// Degraded is a status check that found packages that are degraded or not deployed
+	Degraded = 7
+	// Interrupted is a command stopped by SIGINT or SIGTERM, as a shell would report it
+	Interrupted = 130
+)
+
+// ErrCommandExited is a command the CLI ran for the user, eg. with package exec, that exited with a
+// non-zero code
+var ErrCommandExited = errors.New("command exited with a non-zero code")
+
+// Error is an error with the exit code the CLI terminates with and a hint for the user on how to
+// resolve it
+type Error struct {
+	Code int
+	Err  error
+	Hint string
+	// Silent errors are not printed, as the output of the command already reports them
+	Silent bool
+}
+
+func New(code int, err error, hint string) *Error {
+	return &Error{Code: code, Err: err, Hint: hint}
+}
+
+// Exit returns an error that terminates the CLI with the exit code of a command it ran for the user,
+// which reports its own failures
+func Exit(code int) *Error {
+	return &Error{Code: code, Err: errors.Wrap(ErrCommandExited, fmt.Sprint("exit code ", code)), Silent: true}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// classifications are the exit codes of errors of third-party packages, which can't return an
+// *Error themselves
+var classifications = []struct {
+	err  error
+	code int
+	hint string
+}{
+	{promptui.ErrInterrupt, Interrupted, ""},
+	{promptui.ErrEOF, Interrupted, ""},
+	{context.Canceled, Interrupted, ""},
+}
+
+// Classify returns err as an *Error, taking the exit code and hint of the *Error it wraps, or
+// deriving them from the third-party errors it wraps if it wraps none
+func Classify(err error) *Error {
+	var exitErr *Error
+	if errors.As(err, &exitErr) {
+		if exitErr == err {
+			return exitErr
+		}
+		return &Error{Code: exitErr.Code, Err: err, Hint: exitErr.Hint, Silent: exitErr.Silent}
+	}
+
+	for _, classification := range classifications {
+		if errors.Is(err, classification.err) {
+			return New(classification.code, err, classification.hint)
+		}
+	}
+
+	if client.IsErrConnectionFailed(err) {
+		return New(DockerUnreachable, err, "Make sure the Docker daemon is running, or point DOCKER_HOST at a reachable one")
+	}
+
+	return New(General, err, "")
+}
+
+// Print writes the message and hint of err to w, unless it is silent
+func Print(w io.Writer, err *Error) {
+	if err.Silent {
+		return
+	}
+
+	fmt.Fprintln(w, "Error:", err.Error())
+	if err.Hint != "" {
+		fmt.Fprintln(w, "Hint:", err.Hint)
+	}
+}
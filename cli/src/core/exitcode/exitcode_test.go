@@ -0,0 +1,94 @@
+package exitcode
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/docker/docker/client"
+	"github.com/luno/jettison/errors"
+	"github.com/manifoldco/promptui"
+	"github.com/stretchr/testify/require"
+)
+
+var errNoSuchProfile = errors.New("no such profile")
+
+func TestClassify(t *testing.T) {
+	dockerClient, err := client.NewClientWithOpts(client.WithHost("unix:///nonexistent/docker.sock"))
+	require.NoError(t, err)
+	_, dockerErr := dockerClient.Ping(context.Background())
+
+	type cases struct {
+		err          error
+		expectedCode int
+		expectedHint bool
+	}
+
+	testCases := []cases{
+		// case: already classified error
+		{
+			err:          New(ValidationFailed, errors.New("no packages selected"), "select packages"),
+			expectedCode: ValidationFailed,
+			expectedHint: true,
+		},
+		// case: classified error wrapped on its way up
+		{
+			err:          errors.Wrap(New(ConfigInvalid, errors.Wrap(errNoSuchProfile, "dev"), "check the profiles"), "project platform"),
+			expectedCode: ConfigInvalid,
+			expectedHint: true,
+		},
+		// case: classified error without a hint
+		{
+			err:          errors.Wrap(New(ConfigInvalid, errors.New("invalid config file syntax"), ""), ""),
+			expectedCode: ConfigInvalid,
+		},
+		// case: unreachable Docker daemon
+		{
+			err:          errors.Wrap(dockerErr, ""),
+			expectedCode: DockerUnreachable,
+			expectedHint: true,
+		},
+		// case: exit code of a command run for the user
+		{
+			err:          errors.Wrap(Exit(2), ""),
+			expectedCode: 2,
+		},
+		// case: interrupted
+		{
+			err:          errors.Wrap(context.Canceled, ""),
+			expectedCode: Interrupted,
+		},
+		// case: interrupted prompt
+		{
+			err:          errors.Wrap(promptui.ErrInterrupt, ""),
+			expectedCode: Interrupted,
+		},
+		// case: anything else
+		{
+			err:          errors.New("something went wrong"),
+			expectedCode: General,
+		},
+	}
+
+	for _, tc := range testCases {
+		exitErr := Classify(tc.err)
+		require.Equal(t, tc.expectedCode, exitErr.Code, tc.err.Error())
+		require.Equal(t, tc.expectedHint, exitErr.Hint != "", tc.err.Error())
+		require.ErrorIs(t, exitErr, tc.err)
+		require.Equal(t, tc.err.Error(), exitErr.Error())
+	}
+}
+
+func TestPrint(t *testing.T) {
+	var buf bytes.Buffer
+	Print(&buf, New(ValidationFailed, errors.Wrap(errNoSuchProfile, "dev"), "Check the profile names in the config file"))
+
+	require.Equal(t, "Error: dev: no such profile\nHint: Check the profile names in the config file\n", buf.String())
+
+	buf.Reset()
+	Print(&buf, Exit(2))
+	require.Empty(t, buf.String())
+
+	Print(&buf, Classify(errors.Wrap(Exit(2), "")))
+	require.Empty(t, buf.String())
+}
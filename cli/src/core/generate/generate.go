@@ -8,6 +8,7 @@ import (
 	"path"
 
 	"cli/core"
+	"cli/core/exitcode"
 
 	"github.com/luno/jettison/errors"
 	"gopkg.in/yaml.v3"
@@ -69,7 +70,7 @@ func GeneratePackage(destination string, generatePackageSpec core.GeneratePackag
 
 func GenerateConfigFile(config *core.Config) error {
 	if config.Image == "" || config.ProjectName == "" {
-		return errors.Wrap(ErrInvalidConfig, "")
+		return exitcode.New(exitcode.ConfigInvalid, errors.Wrap(ErrInvalidConfig, ""), "")
 	}
 
 	firstFields := core.Config{
@@ -0,0 +1,131 @@
+package interpolate
+
+import (
+	"strings"
+
+	"github.com/luno/jettison/errors"
+)
+
+var (
+	ErrRequiredVariable = errors.New("required variable is not set")
+	ErrInvalidSyntax    = errors.New("invalid variable interpolation, use ${VAR}, ${VAR:-default}, ${VAR:?error} or $$ for a literal $")
+)
+
+// Lookup returns the value of the variable name, and whether it is set
+type Lookup func(name string) (string, bool)
+
+// String replaces the variables in s with their values, the way Docker Compose does:
+//   - ${VAR} is the value of VAR, or empty if it is not set
+//   - ${VAR:-default} is default if VAR is not set or empty, ${VAR-default} only if it is not set
+//   - ${VAR:?error} fails with error if VAR is not set or empty, ${VAR?error} only if it is not set
+//   - $$ is a literal $
+//
+// Defaults and errors may hold variables themselves. A $ followed by anything else is kept as is.
+func String(s string, lookup Lookup) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		switch s[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+
+		case '{':
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				return "", errors.Wrap(ErrInvalidSyntax, s[i:])
+			}
+
+			value, err := expand(s[i+2:end], lookup)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(value)
+			i = end
+
+		default:
+			b.WriteByte('$')
+		}
+	}
+
+	return b.String(), nil
+}
+
+// closingBrace returns the index of the } closing the expression starting at start, allowing for
+// nested expressions in defaults and errors, or -1 if there is none
+func closingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+
+	return -1
+}
+
+// expand returns the value of an expression like VAR:-default, without the enclosing ${}
+func expand(expression string, lookup Lookup) (string, error) {
+	name := variableName(expression)
+	if name == "" {
+		return "", errors.Wrap(ErrInvalidSyntax, "${"+expression+"}")
+	}
+	value, set := lookup(name)
+
+	operator := expression[len(name):]
+	if operator == "" {
+		return value, nil
+	}
+
+	orEmpty := strings.HasPrefix(operator, ":")
+	operator = strings.TrimPrefix(operator, ":")
+	if operator == "" {
+		return "", errors.Wrap(ErrInvalidSyntax, "${"+expression+"}")
+	}
+
+	missing := !set || (orEmpty && value == "")
+	switch operator[0] {
+	case '-':
+		if missing {
+			return String(operator[1:], lookup)
+		}
+		return value, nil
+
+	case '?':
+		if missing {
+			message, err := String(operator[1:], lookup)
+			if err != nil {
+				return "", err
+			}
+			if message == "" {
+				return "", errors.Wrap(ErrRequiredVariable, name)
+			}
+			return "", errors.Wrap(ErrRequiredVariable, name+" ("+message+")")
+		}
+		return value, nil
+	}
+
+	return "", errors.Wrap(ErrInvalidSyntax, "${"+expression+"}")
+}
+
+// variableName returns the variable name expression starts with
+func variableName(expression string) string {
+	for i, c := range expression {
+		isLetter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !isLetter && (i == 0 || c < '0' || c > '9') {
+			return expression[:i]
+		}
+	}
+
+	return expression
+}
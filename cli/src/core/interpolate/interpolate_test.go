@@ -0,0 +1,74 @@
+package interpolate
+
+import (
+	"testing"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestString(t *testing.T) {
+	variables := map[string]string{
+		"HOME":    "/home/instant",
+		"RELEASE": "v1.2.0",
+		"EMPTY":   "",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := variables[name]
+		return value, ok
+	}
+
+	type cases struct {
+		value               string
+		expectedValue       string
+		expectedErrorString string
+	}
+
+	testCases := []cases{
+		// case: no variables
+		{value: "jembi/platform:2.5.0", expectedValue: "jembi/platform:2.5.0"},
+		// case: set and unset variables
+		{value: "${HOME}/instant-logs${UNSET}", expectedValue: "/home/instant/instant-logs"},
+		// case: defaults
+		{
+			value:         "${UNSET:-jembi/platform}:${EMPTY:-latest}/${EMPTY-none}",
+			expectedValue: "jembi/platform:latest/",
+		},
+		// case: nested default
+		{
+			value:         "https://example.org/${UNSET:-${RELEASE}}.zip",
+			expectedValue: "https://example.org/v1.2.0.zip",
+		},
+		// case: escapes and lone dollar signs
+		{value: "pa$$word${RELEASE}$ $HOME $", expectedValue: "pa$wordv1.2.0$ $HOME $"},
+		// case: required variable that is set
+		{value: "${RELEASE:?set the release}", expectedValue: "v1.2.0"},
+		// case: required variable that is empty
+		{value: "${EMPTY?must be defined}", expectedValue: ""},
+		// case: required variable that is not set
+		{
+			value:               "${EMPTY:?set it in .env}",
+			expectedErrorString: "EMPTY (set it in .env): " + ErrRequiredVariable.Error(),
+		},
+		// case: required variable without a message
+		{value: "${UNSET?}", expectedErrorString: "UNSET: " + ErrRequiredVariable.Error()},
+		// case: unclosed expression
+		{value: "${HOME", expectedErrorString: "${HOME: " + ErrInvalidSyntax.Error()},
+		// case: invalid variable name
+		{value: "${1VAR}", expectedErrorString: "${1VAR}: " + ErrInvalidSyntax.Error()},
+		// case: unknown operator
+		{value: "${HOME:+set}", expectedErrorString: "${HOME:+set}: " + ErrInvalidSyntax.Error()},
+	}
+
+	for _, tc := range testCases {
+		value, err := String(tc.value, lookup)
+		if tc.expectedErrorString != "" {
+			require.NotNil(t, err)
+			require.Equal(t, tc.expectedErrorString, err.Error())
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		require.Equal(t, tc.expectedValue, value)
+	}
+}
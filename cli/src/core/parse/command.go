@@ -12,7 +12,12 @@ func GetCustomPackageName(customPackage core.CustomPackage) string {
 	if customPackage.Id != "" {
 		return customPackage.Id
 	}
-	return strings.TrimSuffix(path.Base(path.Clean(customPackage.Path)), path.Ext(customPackage.Path))
+
+	source := GetCustomPackageSource(customPackage)
+	if source.Subdir != "" {
+		return path.Base(path.Clean(source.Subdir))
+	}
+	return strings.TrimSuffix(path.Base(path.Clean(source.Location)), path.Ext(source.Location))
 }
 
 func GetInstantCommand(packageSpec core.PackageSpec) []string {
@@ -34,6 +34,12 @@ func TestGetCustomPackageName(t *testing.T) {
 
 		// case: git custom package without full URL
 		{core.CustomPackage{Path: "git@github.com:test/test-package.git"}, "test-package"},
+
+		// case: git custom package with ref
+		{core.CustomPackage{Path: "https://github.com/test/test-package.git#v1.2.0"}, "test-package"},
+
+		// case: git custom package in a monorepo subdirectory
+		{core.CustomPackage{Path: "https://github.com/test/monorepo.git#v1.2.0:packages/test-package"}, "test-package"},
 	}
 
 	for _, tc := range testCases {
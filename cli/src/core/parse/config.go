@@ -1,26 +1,40 @@
 package parse
 
 import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"cli/core"
+	"cli/core/configfile"
+	"cli/core/exitcode"
+	"cli/core/interpolate"
+	"cli/core/schema"
 	coreConfig "cli/core/state"
 
 	"github.com/luno/jettison/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
 )
 
 var (
 	ErrInvalidConfigFileSyntax = errors.New("invalid config file syntax, refer to https://github.com/openhie/package-starter-kit/blob/main/README.md, for information on valid config file syntax")
+	ErrReadConfigFile          = errors.New("unable to read config file")
 )
 
+func readConfigFileError(err error) error {
+	return exitcode.New(exitcode.ConfigInvalid, errors.Wrap(ErrReadConfigFile, err.Error()), "Check the config file passed with --config or INSTANT_CONFIG")
+}
+
 func unmarshalConfig(configViper *viper.Viper) (*core.Config, error) {
 	var config core.Config
 	err := configViper.Unmarshal(&config)
 	if err != nil {
 		if strings.Contains(err.Error(), "expected type") {
-			return nil, errors.Wrap(ErrInvalidConfigFileSyntax, "")
+			return nil, exitcode.New(exitcode.ConfigInvalid, errors.Wrap(ErrInvalidConfigFileSyntax, ""), "")
 		} else {
 			return nil, errors.Wrap(err, "")
 		}
@@ -37,17 +51,48 @@ func appendTag(config *core.Config) {
 	}
 }
 
+// GetConfigFromParams loads the config document of cmd, see LoadConfigDocument, and decodes it
 func GetConfigFromParams(cmd *cobra.Command) (*core.Config, error) {
-	configFile, err := cmd.Flags().GetString("config")
+	document, err := LoadConfigDocument(cmd)
 	if err != nil {
-		return nil, errors.Wrap(err, "")
+		return nil, err
+	}
+
+	return decodeConfig(document)
+}
+
+// GetValidConfigFromParams loads the config document of cmd, see LoadConfigDocument, checks it
+// against the config schema, see ValidateConfigDocument, and decodes it. The config files are read,
+// and their variables interpolated, once.
+func GetValidConfigFromParams(cmd *cobra.Command) (*core.Config, error) {
+	document, err := LoadConfigDocument(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ValidateConfigDocument(document)
+	if err != nil {
+		return nil, err
 	}
 
-	configViper, err := coreConfig.SetConfigViper(configFile)
+	return decodeConfig(document)
+}
+
+// decodeConfig decodes a loaded config document into a config, tagging its image with latest if it
+// has no tag
+func decodeConfig(document *configfile.Document) (*core.Config, error) {
+	data, err := document.Marshal()
 	if err != nil {
 		return nil, err
 	}
 
+	configViper := viper.New()
+	configViper.SetConfigType("yaml")
+	err = configViper.ReadConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, readConfigFileError(err)
+	}
+
 	populatedConfig, err := unmarshalConfig(configViper)
 	if err != nil {
 		return nil, err
@@ -57,3 +102,172 @@ func GetConfigFromParams(cmd *cobra.Command) (*core.Config, error) {
 
 	return populatedConfig, nil
 }
+
+// LoadConfigDocument reads and merges the config files selected by the --config flag of cmd, or
+// else the config file found by state.FindConfigFile, with the files they extend, and interpolates
+// the variables in their values. Relative env files and local custom package paths are resolved
+// from the directory of the config file they are in.
+func LoadConfigDocument(cmd *cobra.Command) (*configfile.Document, error) {
+	configFiles, err := cmd.Flags().GetStringSlice("config")
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	var firstConfigFile string
+	if len(configFiles) > 0 {
+		firstConfigFile = configFiles[0]
+	}
+
+	configFile, err := coreConfig.ConfigFilePath(firstConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	// The files a config file extends are reported by the loader, the config file itself here
+	_, err = os.Stat(configFile)
+	if err != nil {
+		return nil, readConfigFileError(err)
+	}
+
+	locations := []string{configFile}
+	for _, configFile := range configFiles[min(1, len(configFiles)):] {
+		absFilePath, err := filepath.Abs(configFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		locations = append(locations, absFilePath)
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	document, err := configfile.Load(ctx, locations)
+	if err != nil {
+		return nil, err
+	}
+
+	lookup, err := configVariables(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	err = document.Interpolate(lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	resolveConfigPaths(document, filepath.Dir(configFile))
+
+	return document, nil
+}
+
+// resolveConfigPaths makes the relative profile env files and local custom package paths of the
+// document absolute, resolving them from the directory of the config file they are in. Paths in
+// config files fetched from URLs are resolved from defaultDir.
+func resolveConfigPaths(document *configfile.Document, defaultDir string) {
+	resolve := func(node *yaml.Node) {
+		if node == nil || node.Kind != yaml.ScalarNode || node.Value == "" || filepath.IsAbs(node.Value) {
+			return
+		}
+
+		dir := defaultDir
+		if file := document.FileOf(node); !httpUrlRegex.MatchString(file) {
+			absFilePath, err := filepath.Abs(file)
+			if err == nil {
+				dir = filepath.Dir(absFilePath)
+			}
+		}
+		node.Value = filepath.Join(dir, node.Value)
+	}
+
+	for _, customPackage := range sequenceItems(document.Root, "customPackages") {
+		pathNode := mappingValue(customPackage, "path")
+		if pathNode != nil && GetCustomPackageSource(core.CustomPackage{Path: pathNode.Value}).Kind == SourceLocal {
+			resolve(pathNode)
+		}
+	}
+
+	for _, profile := range sequenceItems(document.Root, "profiles") {
+		for _, envFile := range sequenceItems(profile, "envFiles") {
+			resolve(envFile)
+		}
+	}
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// sequenceItems returns the items of the sequence at key in a mapping node
+func sequenceItems(node *yaml.Node, key string) []*yaml.Node {
+	value := mappingValue(node, key)
+	if value == nil || value.Kind != yaml.SequenceNode {
+		return nil
+	}
+
+	return value.Content
+}
+
+// configVariables returns the variables the config files are interpolated with. The process
+// environment takes precedence over the --env-file files of cmd, as in Docker Compose.
+func configVariables(cmd *cobra.Command) (interpolate.Lookup, error) {
+	envFileVars := make(map[string]string)
+	if cmd.Flags().Changed("env-file") {
+		envFiles, err := cmd.Flags().GetStringSlice("env-file")
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+
+		envVars, err := coreConfig.ReadEnvFiles(envFiles)
+		if err != nil {
+			return nil, err
+		}
+		for _, envVar := range envVars {
+			name, value, _ := strings.Cut(envVar, "=")
+			envFileVars[name] = value
+		}
+	}
+
+	return func(name string) (string, bool) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+		value, ok := envFileVars[name]
+
+		return value, ok
+	}, nil
+}
+
+// ValidateConfigFile checks the config files selected by the --config flag of cmd against the
+// config schema once merged, see ValidateConfigDocument
+func ValidateConfigFile(cmd *cobra.Command) error {
+	document, err := LoadConfigDocument(cmd)
+	if err != nil {
+		return err
+	}
+
+	return ValidateConfigDocument(document)
+}
+
+// ValidateConfigDocument checks a loaded config document against the config schema, returning an
+// error wrapping a *schema.ValidationError with the position of every problem found
+func ValidateConfigDocument(document *configfile.Document) error {
+	diagnostics := schema.CheckNode(document.Root, document.FileOf)
+	if len(diagnostics) > 0 {
+		return exitcode.New(exitcode.ConfigInvalid, &schema.ValidationError{Diagnostics: diagnostics}, schema.ValidationHint)
+	}
+
+	return nil
+}
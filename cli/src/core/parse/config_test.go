@@ -1,13 +1,24 @@
 package parse
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 
+	"cli/cmd/flags"
 	"cli/core"
-	coreConfig "cli/core/state"
+	"cli/core/configfile"
+	"cli/core/exitcode"
+	"cli/core/schema"
 
+	"github.com/luno/jettison/errors"
 	"github.com/luno/jettison/jtest"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
 )
 
@@ -87,10 +98,7 @@ func Test_unmarshalConfig(t *testing.T) {
 	}
 
 	for _, testCase := range testCases {
-		configViper, err := coreConfig.SetConfigViper(testCase.configPath)
-		jtest.RequireNil(t, err)
-
-		config, err := unmarshalConfig(configViper)
+		config, err := unmarshalConfig(readConfigViper(t, testCase.configPath))
 		if testCase.errString == "" {
 			jtest.RequireNil(t, err)
 			require.Equal(t, testCase.expectedConfig, *config)
@@ -99,3 +107,127 @@ func Test_unmarshalConfig(t *testing.T) {
 		}
 	}
 }
+
+// readConfigViper reads the config file at configPath into a viper for unmarshalConfig
+func readConfigViper(t *testing.T, configPath string) *viper.Viper {
+	configViper := viper.New()
+	configViper.SetConfigFile(configPath)
+	jtest.RequireNil(t, configViper.ReadInConfig())
+
+	return configViper
+}
+
+func Test_resolveConfigPaths(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"platform/base/base.yml": `image: jembi/platform
+customPackages:
+  - id: local
+    path: ../packages/local
+  - id: remote
+    path: https://github.com/jembi/disi-on-platform.git
+profiles:
+  - name: base
+    packages: [local]
+    envFiles: [.env.base]
+`,
+		"platform/config.toml": `extends = ["base/base.yml"]
+
+[[customPackages]]
+id = "absolute"
+path = "/opt/packages/absolute"
+
+[[profiles]]
+name = "dev"
+packages = ["remote"]
+envFiles = [".env.dev", "/etc/instant/.env"]
+`,
+	}
+	for name, content := range files {
+		filePath := filepath.Join(dir, name)
+		jtest.RequireNil(t, os.MkdirAll(filepath.Dir(filePath), os.ModePerm))
+		jtest.RequireNil(t, os.WriteFile(filePath, []byte(content), 0o644))
+	}
+
+	cmd := &cobra.Command{}
+	flags.SetConfigFlags(cmd)
+	jtest.RequireNil(t, cmd.Flags().Set("config", filepath.Join(dir, "platform/config.toml")))
+
+	config, err := GetConfigFromParams(cmd)
+	jtest.RequireNil(t, err)
+
+	require.Equal(t, []core.CustomPackage{
+		{Id: "local", Path: filepath.Join(dir, "platform/packages/local")},
+		{Id: "remote", Path: "https://github.com/jembi/disi-on-platform.git"},
+		{Id: "absolute", Path: "/opt/packages/absolute"},
+	}, config.CustomPackages)
+	require.Equal(t, []string{filepath.Join(dir, "platform/base/.env.base")}, config.Profiles[0].EnvFiles)
+	require.Equal(t, []string{filepath.Join(dir, "platform/.env.dev"), "/etc/instant/.env"}, config.Profiles[1].EnvFiles)
+}
+
+func TestGetValidConfigFromParams(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Write([]byte("packages:\n  - ${PACKAGE}\n"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config.yml")
+	jtest.RequireNil(t, os.WriteFile(configFile, []byte("extends: ["+server.URL+"/shared.yml]\nimage: jembi/platform\n"), 0o644))
+	t.Setenv("PACKAGE", "database-postgres")
+
+	cmd := &cobra.Command{}
+	flags.SetConfigFlags(cmd)
+	jtest.RequireNil(t, cmd.Flags().Set("config", configFile))
+
+	// The config files are read and interpolated once to be validated and decoded
+	config, err := GetValidConfigFromParams(cmd)
+	jtest.RequireNil(t, err)
+	require.Equal(t, "jembi/platform:latest", config.Image)
+	require.Equal(t, []string{"database-postgres"}, config.Packages)
+	require.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+}
+
+func TestValidateConfigDocument(t *testing.T) {
+	dir := t.TempDir()
+
+	type cases struct {
+		config              string
+		expectedErrorString string
+	}
+
+	testCases := []cases{
+		// case: valid config file
+		{config: "image: jembi/platform\n"},
+		// case: every problem of an invalid config file is reported at its position
+		{
+			config:              "image: 3\n",
+			expectedErrorString: "1 problem(s) in config file\n  " + filepath.Join(dir, "config.yml") + ":1:8: image: expected string, but got number",
+		},
+	}
+
+	for _, tc := range testCases {
+		configFile := filepath.Join(dir, "config.yml")
+		jtest.RequireNil(t, os.WriteFile(configFile, []byte(tc.config), 0o644))
+
+		document, err := configfile.Load(context.Background(), []string{configFile})
+		jtest.RequireNil(t, err)
+
+		err = ValidateConfigDocument(document)
+		if tc.expectedErrorString == "" {
+			jtest.RequireNil(t, err)
+			continue
+		}
+
+		require.True(t, errors.Is(err, schema.ErrInvalidConfig))
+		var validationErr *schema.ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		require.Equal(t, tc.expectedErrorString, validationErr.Error())
+		require.Equal(t, exitcode.ConfigInvalid, exitcode.Classify(err).Code)
+	}
+}
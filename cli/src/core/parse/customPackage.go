@@ -0,0 +1,61 @@
+package parse
+
+import (
+	"regexp"
+	"strings"
+
+	"cli/core"
+)
+
+type SourceKind string
+
+const (
+	SourceGit   SourceKind = "git"
+	SourceHTTP  SourceKind = "http"
+	SourceLocal SourceKind = "local"
+)
+
+var (
+	gitUrlRegex  = regexp.MustCompile(`^(git@|ssh://|git://)|\.git/?$`)
+	httpUrlRegex = regexp.MustCompile(`^https?://`)
+)
+
+// CustomPackageSource is where a custom package is fetched from
+type CustomPackageSource struct {
+	Kind SourceKind
+	// Location is the URL or file system path of the custom package, without any #ref:subdir fragment
+	Location string
+	Ref      string
+	Subdir   string
+}
+
+// GetCustomPackageSource resolves the source of a custom package. Git URLs may pin a ref and a
+// subdirectory with a fragment, eg. https://github.com/org/repo.git#v1.2.0:packages/foo, which
+// are overridden by the ref and subdir fields of the custom package.
+func GetCustomPackageSource(customPackage core.CustomPackage) CustomPackageSource {
+	source := CustomPackageSource{
+		Kind:     SourceLocal,
+		Location: customPackage.Path,
+	}
+
+	location, fragment, hasFragment := strings.Cut(customPackage.Path, "#")
+	isRemote := httpUrlRegex.MatchString(location) || gitUrlRegex.MatchString(location)
+
+	switch {
+	case isRemote && (hasFragment || customPackage.Ref != "" || gitUrlRegex.MatchString(location)):
+		source.Kind = SourceGit
+		source.Location = location
+		source.Ref, source.Subdir, _ = strings.Cut(fragment, ":")
+	case httpUrlRegex.MatchString(customPackage.Path):
+		source.Kind = SourceHTTP
+	}
+
+	if customPackage.Ref != "" {
+		source.Ref = customPackage.Ref
+	}
+	if customPackage.Subdir != "" {
+		source.Subdir = customPackage.Subdir
+	}
+
+	return source
+}
@@ -0,0 +1,77 @@
+package parse
+
+import (
+	"testing"
+
+	"cli/core"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetCustomPackageSource(t *testing.T) {
+	type cases struct {
+		customPackage  core.CustomPackage
+		expectedSource CustomPackageSource
+	}
+
+	testCases := []cases{
+		// case: ssh git URL
+		{
+			customPackage:  core.CustomPackage{Path: "git@github.com:jembi/disi-on-platform.git"},
+			expectedSource: CustomPackageSource{Kind: SourceGit, Location: "git@github.com:jembi/disi-on-platform.git"},
+		},
+		// case: https git URL is cloned rather than downloaded
+		{
+			customPackage:  core.CustomPackage{Path: "https://github.com/jembi/disi-on-platform.git"},
+			expectedSource: CustomPackageSource{Kind: SourceGit, Location: "https://github.com/jembi/disi-on-platform.git"},
+		},
+		// case: ref and subdir URL fragment
+		{
+			customPackage: core.CustomPackage{Path: "https://github.com/org/repo.git#v1.2.0:packages/foo"},
+			expectedSource: CustomPackageSource{
+				Kind:     SourceGit,
+				Location: "https://github.com/org/repo.git",
+				Ref:      "v1.2.0",
+				Subdir:   "packages/foo",
+			},
+		},
+		// case: subdir only URL fragment
+		{
+			customPackage: core.CustomPackage{Path: "git@github.com:org/repo.git#:packages/foo"},
+			expectedSource: CustomPackageSource{
+				Kind:     SourceGit,
+				Location: "git@github.com:org/repo.git",
+				Subdir:   "packages/foo",
+			},
+		},
+		// case: fields override the URL fragment
+		{
+			customPackage: core.CustomPackage{Path: "https://github.com/org/repo.git#v1.2.0", Ref: "main", Subdir: "packages/bar"},
+			expectedSource: CustomPackageSource{
+				Kind:     SourceGit,
+				Location: "https://github.com/org/repo.git",
+				Ref:      "main",
+				Subdir:   "packages/bar",
+			},
+		},
+		// case: https URL with a ref field is cloned
+		{
+			customPackage:  core.CustomPackage{Path: "https://github.com/org/repo", Ref: "main"},
+			expectedSource: CustomPackageSource{Kind: SourceGit, Location: "https://github.com/org/repo", Ref: "main"},
+		},
+		// case: archive download
+		{
+			customPackage:  core.CustomPackage{Path: "https://github.com/org/repo/releases/download/v1/package.tar"},
+			expectedSource: CustomPackageSource{Kind: SourceHTTP, Location: "https://github.com/org/repo/releases/download/v1/package.tar"},
+		},
+		// case: local path containing .git and #
+		{
+			customPackage:  core.CustomPackage{Path: "/home/user/.github/package#1"},
+			expectedSource: CustomPackageSource{Kind: SourceLocal, Location: "/home/user/.github/package#1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.expectedSource, GetCustomPackageSource(tc.customPackage))
+	}
+}
@@ -0,0 +1,81 @@
+package parse
+
+import (
+	"strings"
+
+	"cli/core"
+	"cli/core/env"
+	"cli/core/exitcode"
+	"cli/core/state"
+
+	"github.com/luno/jettison/errors"
+	"github.com/spf13/cobra"
+)
+
+// EnvVars returns the env vars passed to the deployment container with the sources of their
+// values, added in increasing order of precedence: the env files and envVars of the profiles
+// selected with --profile, profile by profile, the --env-file files and the --env-var flags. The
+// first --env-var of a name takes precedence over its repeats.
+func EnvVars(cmd *cobra.Command, config core.Config) (*env.Set, error) {
+	envVars := env.NewSet()
+
+	layers, err := selectedProfileLayers(cmd, config)
+	if err != nil {
+		return nil, err
+	}
+	err = addProfileEnvVars(envVars, layers)
+	if err != nil {
+		return nil, err
+	}
+
+	if cmd.Flags().Changed("env-file") {
+		envFiles, err := cmd.Flags().GetStringSlice("env-file")
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+
+		for _, envFile := range envFiles {
+			fileEnvVars, err := state.ReadEnvFiles([]string{envFile})
+			if err != nil {
+				return nil, err
+			}
+			envVars.Add(fileEnvVars, "--env-file "+envFile)
+		}
+	}
+
+	if cmd.Flags().Changed("env-var") {
+		paramsEnvVars, err := cmd.Flags().GetStringSlice("env-var")
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+
+		paramsEnvVars, err = uniqueEnvVars(paramsEnvVars)
+		if err != nil {
+			return nil, err
+		}
+		envVars.Add(paramsEnvVars, "--env-var")
+	}
+
+	return envVars, nil
+}
+
+// uniqueEnvVars returns the --env-var flags without the repeats of a name, as the first value of a
+// name passed with --env-var takes precedence over the later ones. Flags not in KEY=value form are
+// rejected.
+func uniqueEnvVars(envVars []string) ([]string, error) {
+	var unique []string
+	seen := make(map[string]bool)
+	for _, envVar := range envVars {
+		name, _, ok := strings.Cut(envVar, "=")
+		if !ok || name == "" {
+			return nil, exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrInvalidEnvVar, envVar), "Pass env vars as --env-var KEY=value")
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		unique = append(unique, envVar)
+	}
+
+	return unique, nil
+}
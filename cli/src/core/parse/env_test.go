@@ -0,0 +1,109 @@
+package parse
+
+import (
+	"os"
+	"testing"
+
+	"cli/core/exitcode"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvVars(t *testing.T) {
+	wd, err := os.Getwd()
+	jtest.RequireNil(t, err)
+
+	configFilePath := wd + "/../../features/unit-test-configs/config-case-5.yml"
+
+	type cases struct {
+		hookFunc            func(cmd *cobra.Command)
+		expectedEnvVars     []string
+		expectedErrorString string
+		expectedCode        int
+	}
+
+	testCases := []cases{
+		// case: env vars of the env files
+		{
+			hookFunc: func(cmd *cobra.Command) {
+				cmd.Flags().Set("env-file", wd+"/../../features/test-conf/.env.one")
+				cmd.Flags().Set("env-file", wd+"/../../features/test-conf/.env.two")
+			},
+			expectedEnvVars: []string{"FIRST_ENV_VAR=number_one", "SECOND_ENV_VAR=number_two"},
+		},
+		// case: return error from not finding env file
+		{
+			hookFunc: func(cmd *cobra.Command) {
+				cmd.Flags().Set("env-file", wd+"/../../features/test-conf/awlikdeuh")
+			},
+			expectedErrorString: "no such file or directory",
+		},
+		// case: env vars of the env files of the profile
+		{
+			hookFunc: func(cmd *cobra.Command) {
+				cmd.Flags().Set("profile", "dev")
+			},
+			expectedEnvVars: []string{"FIRST_ENV_VAR=number_one", "SECOND_ENV_VAR=number_two"},
+		},
+		// case: return error from non-existant env file of the profile
+		{
+			hookFunc: func(cmd *cobra.Command) {
+				cmd.Flags().Set("profile", "bad-env-file-path")
+			},
+			expectedErrorString: ".env.none: no such file or directory",
+		},
+		// case: env vars take precedence over env files, and env files over profiles
+		{
+			hookFunc: func(cmd *cobra.Command) {
+				cmd.Flags().Set("profile", "dev")
+				cmd.Flags().Set("env-file", wd+"/../../features/test-conf/.env.one")
+				cmd.Flags().Set("env-var", "FIRST_ENV_VAR=flag")
+				cmd.Flags().Set("env-var", "THIRD_ENV_VAR=flag")
+			},
+			expectedEnvVars: []string{"FIRST_ENV_VAR=flag", "SECOND_ENV_VAR=number_two", "THIRD_ENV_VAR=flag"},
+		},
+		// case: the first of repeated env vars takes precedence
+		{
+			hookFunc: func(cmd *cobra.Command) {
+				cmd.Flags().Set("env-var", "LOG_LEVEL=debug")
+				cmd.Flags().Set("env-var", "LOG_LEVEL=info")
+				cmd.Flags().Set("env-var", "EMPTY=")
+			},
+			expectedEnvVars: []string{"EMPTY=", "LOG_LEVEL=debug"},
+		},
+		// case: return error from an env var without a value
+		{
+			hookFunc: func(cmd *cobra.Command) {
+				cmd.Flags().Set("env-var", "LOG_LEVEL")
+			},
+			expectedErrorString: ErrInvalidEnvVar.Error(),
+			expectedCode:        exitcode.ValidationFailed,
+		},
+		// case: return error from an env var without a name
+		{
+			hookFunc: func(cmd *cobra.Command) {
+				cmd.Flags().Set("env-var", "=debug")
+			},
+			expectedErrorString: ErrInvalidEnvVar.Error(),
+			expectedCode:        exitcode.ValidationFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		cmd, config := loadCmdAndConfig(t, configFilePath, tc.hookFunc)
+
+		envVars, err := EnvVars(cmd, *config)
+		if tc.expectedErrorString != "" {
+			require.ErrorContains(t, err, tc.expectedErrorString)
+			if tc.expectedCode != 0 {
+				require.Equal(t, tc.expectedCode, exitcode.Classify(err).Code)
+			}
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		require.Equal(t, tc.expectedEnvVars, envVars.Strings())
+	}
+}
@@ -1,8 +1,10 @@
 package parse
 
 import (
+	"strconv"
+
 	"cli/core"
-	"cli/core/state"
+	"cli/core/exitcode"
 
 	"github.com/luno/jettison/errors"
 	"github.com/spf13/cobra"
@@ -53,35 +55,40 @@ func getPackageSpecFromParams(cmd *cobra.Command, config *core.Config) (*core.Pa
 	if err != nil {
 		return nil, errors.Wrap(err, "")
 	}
+	strictEnv, err := cmd.Flags().GetBool("strict-env")
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	resolve, err := cmd.Flags().GetBool("resolve")
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
 	concurrency, err := cmd.Flags().GetString("concurrency")
 	if err != nil {
 		return nil, errors.Wrap(err, "")
 	}
-
-	var envVariables []string
-	if cmd.Flags().Changed("env-file") {
-		envFiles, err := cmd.Flags().GetStringSlice("env-file")
-		if err != nil {
-			return nil, errors.Wrap(err, "")
-		}
-
-		envViper, err := state.GetEnvironmentVariableViper(envFiles)
-		if err != nil {
-			return nil, err
+	if concurrency != "" {
+		if n, err := strconv.Atoi(concurrency); err != nil || n < 1 {
+			return nil, exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrInvalidConcurrency, concurrency), ConcurrencyHint)
 		}
-		envVariables = state.GetEnvVariableString(envViper)
 	}
 
 	customPackages := parseCustomPackageFromPath(config, customPackagePaths)
 
 	packageSpec = core.PackageSpec{
-		Packages:             packageNames,
-		CustomPackages:       customPackages,
-		EnvironmentVariables: envVariables,
-		IsDev:                isDev,
-		IsOnly:               isOnly,
-		DeployCommand:        cmd.Use,
-		Concurrency:          concurrency,
+		Packages:       packageNames,
+		CustomPackages: customPackages,
+		IsDev:          isDev,
+		IsOnly:         isOnly,
+		DeployCommand:  cmd.Use,
+		Concurrency:    concurrency,
+		StrictEnv:      strictEnv,
+		Resolve:        resolve,
+		DryRun:         dryRun,
 	}
 
 	return &packageSpec, nil
@@ -3,13 +3,11 @@ package parse
 import (
 	"io"
 	"os"
-	"sort"
 	"strings"
 	"testing"
 
 	"cli/cmd/flags"
 	"cli/core"
-	"cli/core/state"
 
 	"github.com/luno/jettison/jtest"
 	"github.com/spf13/cobra"
@@ -61,13 +59,11 @@ func Test_getPackageSpecFromParams(t *testing.T) {
 				cmd.Flags().Set("name", "pack-1")
 				cmd.Flags().Set("name", "pack-2")
 
-				cmd.Flags().Set("env-file", wd+"/../../features/test-conf/.env.one")
-				cmd.Flags().Set("env-file", wd+"/../../features/test-conf/.env.two")
-
 				cmd.Flags().Set("custom-path", "disi-on-platform")
 
 				cmd.Flags().Set("dev", "true")
 				cmd.Flags().Set("only", "true")
+				cmd.Flags().Set("dry-run", "true")
 			},
 			wantSpecMatch: true,
 			packageSpec: &core.PackageSpec{
@@ -78,18 +74,18 @@ func Test_getPackageSpecFromParams(t *testing.T) {
 						Path: "git@github.com:jembi/disi-on-platform.git",
 					},
 				},
-				EnvironmentVariables: []string{"FIRST_ENV_VAR=number_one", "SECOND_ENV_VAR=number_two"},
-				IsDev:                true,
-				IsOnly:               true,
+				IsDev:  true,
+				IsOnly: true,
+				DryRun: true,
 			},
 		},
-		// case: return error from not finding env file
+		// case: return error from an invalid concurrency
 		{
 			hookFunc: func(cmd *cobra.Command) {
 				cmd.Flags().Set("name", "pack-1")
-				cmd.Flags().Set("env-file", wd+"/../../features/test-conf/awlikdeuh")
+				cmd.Flags().Set("concurrency", "0")
 			},
-			errorString: "no such file or directory",
+			errorString: ErrInvalidConcurrency.Error(),
 		},
 		// case: return no error when not specifying an env-file
 		{
@@ -127,10 +123,6 @@ func Test_getPackageSpecFromParams(t *testing.T) {
 		}
 
 		if tc.wantSpecMatch {
-			sort.Slice(pSpec.EnvironmentVariables, func(i, j int) bool {
-				return strings.Contains(pSpec.EnvironmentVariables[i], "FIRST_ENV_VAR")
-			})
-
 			if !assert.Equal(t, tc.packageSpec, pSpec) {
 				t.FailNow()
 			}
@@ -139,16 +131,16 @@ func Test_getPackageSpecFromParams(t *testing.T) {
 }
 
 func loadCmdAndConfig(t *testing.T, configFilePath string, hookFunc func(cmd *cobra.Command)) (*cobra.Command, *core.Config) {
-	configViper, err := state.SetConfigViper(configFilePath)
-	jtest.RequireNil(t, err)
-
-	config, err := unmarshalConfig(configViper)
-	jtest.RequireNil(t, err)
-
 	cmd := &cobra.Command{}
 
 	flags.SetPackageActionFlags(cmd)
 
+	err := cmd.Flags().Set("config", configFilePath)
+	jtest.RequireNil(t, err)
+
+	config, err := GetConfigFromParams(cmd)
+	jtest.RequireNil(t, err)
+
 	hookFunc(cmd)
 
 	return cmd, config
@@ -171,10 +163,7 @@ func Test_parseCustomPackageFromPath(t *testing.T) {
 	wd, err := os.Getwd()
 	jtest.RequireNil(t, err)
 
-	configViper, err := state.SetConfigViper(wd + "/../../features/unit-test-configs/config-case-4.yml")
-	jtest.RequireNil(t, err)
-
-	config, err := unmarshalConfig(configViper)
+	config, err := unmarshalConfig(readConfigViper(t, wd+"/../../features/unit-test-configs/config-case-4.yml"))
 	jtest.RequireNil(t, err)
 
 	gotCustomPackages := parseCustomPackageFromPath(config, []string{"path-to-1", "path-to-2"})
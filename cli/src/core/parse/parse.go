@@ -4,11 +4,10 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
-	"path/filepath"
+	"os"
 
 	"cli/core"
-	"cli/core/state"
+	"cli/core/exitcode"
 	"cli/util/docker"
 	"cli/util/slice"
 
@@ -18,31 +17,38 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// ParseAndPrepareLaunch parses the launch parameters from the command-line and config file, and
+// prepares the Docker environment for the deployment container unless --dry-run is set
 func ParseAndPrepareLaunch(cmd *cobra.Command) (*core.PackageSpec, *core.Config, error) {
-	if cmd.Flags().Changed("env-file") {
-		envFiles, err := cmd.Flags().GetStringSlice("env-file")
-		if err != nil {
-			return nil, nil, errors.Wrap(err, "")
-		}
-
-		state.EnvFiles = nil
+	packageSpec, config, err := ParseLaunch(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		for _, envFile := range envFiles {
-			if !filepath.IsAbs(envFile) {
-				absFilePath, err := filepath.Abs(envFile)
-				if err != nil {
-					return nil, nil, errors.Wrap(err, "")
-				}
+	pullPolicy, err := getPullPolicy(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
 
-				state.EnvFiles = append(state.EnvFiles, absFilePath)
-				continue
-			}
+	if !packageSpec.DryRun {
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
 
-			state.EnvFiles = append(state.EnvFiles, envFile)
+		err = prepareEnvironment(ctx, *config, pullPolicy)
+		if err != nil {
+			return nil, nil, err
 		}
 	}
 
-	config, err := GetConfigFromParams(cmd)
+	return packageSpec, config, nil
+}
+
+// ParseLaunch validates the config file and resolves the package spec and config from the
+// command-line and config file without touching Docker
+func ParseLaunch(cmd *cobra.Command) (*core.PackageSpec, *core.Config, error) {
+	config, err := GetValidConfigFromParams(cmd)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -57,7 +63,7 @@ func ParseAndPrepareLaunch(cmd *cobra.Command) (*core.PackageSpec, *core.Config,
 		return nil, nil, err
 	}
 
-	packageSpec, err = filterEnvVars(cmd, packageSpec)
+	packageSpec, err = filterEnvVars(cmd, *config, packageSpec)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -75,17 +81,33 @@ func ParseAndPrepareLaunch(cmd *cobra.Command) (*core.PackageSpec, *core.Config,
 		}
 	}
 
-	err = prepareEnvironment(*config)
+	return packageSpec, config, nil
+}
+
+// PullPolicy decides when the config image is pulled before launching the deployment container
+type PullPolicy string
+
+const (
+	PullAlways  PullPolicy = "always"
+	PullMissing PullPolicy = "missing"
+	PullNever   PullPolicy = "never"
+)
+
+func getPullPolicy(cmd *cobra.Command) (PullPolicy, error) {
+	pull, err := cmd.Flags().GetString("pull")
 	if err != nil {
-		return nil, nil, err
+		return "", errors.Wrap(err, "")
 	}
 
-	return packageSpec, config, nil
-}
+	switch policy := PullPolicy(pull); policy {
+	case PullAlways, PullMissing, PullNever:
+		return policy, nil
+	}
 
-func prepareEnvironment(config core.Config) error {
-	ctx := context.Background()
+	return "", exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrInvalidPullPolicy, pull), "Use --pull=always, --pull=missing or --pull=never")
+}
 
+func prepareEnvironment(ctx context.Context, config core.Config, pullPolicy PullPolicy) error {
 	cli, err := docker.NewDockerClient()
 	if err != nil {
 		return errors.Wrap(err, "")
@@ -94,31 +116,55 @@ func prepareEnvironment(config core.Config) error {
 	docker.RemoveStaleInstantContainer(cli, ctx)
 	docker.RemoveStaleInstantVolume(cli, ctx)
 
-	hasImage, err := hasImage(cli, config.Image)
+	return ensureImage(ctx, cli, config.Image, pullPolicy, os.Stdout)
+}
+
+// EnsureImage makes the config image available locally according to the --pull flag of cmd,
+// writing the progress of pulling it to out
+func EnsureImage(cmd *cobra.Command, config core.Config, out io.Writer) error {
+	pullPolicy, err := getPullPolicy(cmd)
+	if err != nil {
+		return err
+	}
+
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return ensureImage(ctx, cli, config.Image, pullPolicy, out)
+}
+
+func ensureImage(ctx context.Context, cli *client.Client, imageName string, pullPolicy PullPolicy, out io.Writer) error {
+	if pullPolicy == PullAlways {
+		fmt.Fprintln(out, "> Pulling image", imageName)
+		return docker.PullImage(ctx, cli, imageName, out)
+	}
+
+	hasImage, err := hasImage(ctx, cli, imageName)
 	if err != nil {
 		return err
 	}
 
 	if !hasImage {
-		fmt.Println("> Image", config.Image, "can't be found locally .. Pulling from docker")
-		reader, err := cli.ImagePull(ctx, config.Image, image.PullOptions{})
-		if err != nil {
-			return errors.Wrap(err, "")
+		if pullPolicy == PullNever {
+			return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrImageNotPresent, imageName), "Pull the image with --pull=missing, or with docker pull")
 		}
-		defer reader.Close()
 
-		// This io.Copy helps to wait for the image to finish downloading
-		_, err = io.Copy(ioutil.Discard, reader)
-		if err != nil {
-			return errors.Wrap(err, "")
-		}
+		fmt.Fprintln(out, "> Image", imageName, "can't be found locally .. Pulling from docker")
+		return docker.PullImage(ctx, cli, imageName, out)
 	}
 
 	return nil
 }
 
-func hasImage(dockerCli *client.Client, imageName string) (bool, error) {
-	images, err := dockerCli.ImageList(context.Background(), image.ListOptions{})
+func hasImage(ctx context.Context, dockerCli *client.Client, imageName string) (bool, error) {
+	images, err := dockerCli.ImageList(ctx, image.ListOptions{})
 	if err != nil {
 		return false, errors.Wrap(err, "")
 	}
@@ -132,47 +178,15 @@ func hasImage(dockerCli *client.Client, imageName string) (bool, error) {
 	return false, nil
 }
 
-// filterEnvVars checks for env vars that could have been set be prior functions (like in --profile),
-// and ensures that env vars parsed are taken in order of precedence of --env-var >> --env-file >> profile env files
-func filterEnvVars(cmd *cobra.Command, pSpec *core.PackageSpec) (*core.PackageSpec, error) {
-	var paramsEnvVars []string
-	if cmd.Flags().Changed("env-var") {
-		envVars, err := cmd.Flags().GetStringSlice("env-var")
-		if err != nil {
-			return nil, errors.Wrap(err, "")
-		}
-		paramsEnvVars = envVars
-	}
-
-	envVarsMap := make(map[string]string)
-	if len(paramsEnvVars) > 0 {
-		envVarsMap = slice.AppendUniqueToMapFromSlice(envVarsMap, paramsEnvVars)
-	}
-
-	var paramsEnvFileEnvVars []string
-	if cmd.Flags().Changed("env-file") {
-		envFiles, err := cmd.Flags().GetStringSlice("env-file")
-		if err != nil {
-			return nil, errors.Wrap(err, "")
-		}
-
-		envViper, err := state.GetEnvironmentVariableViper(envFiles)
-		if err != nil {
-			return nil, err
-		}
-		paramsEnvFileEnvVars = state.GetEnvVariableString(envViper)
-	}
-	if len(paramsEnvFileEnvVars) > 0 {
-		envVarsMap = slice.AppendUniqueToMapFromSlice(envVarsMap, paramsEnvFileEnvVars)
-	}
-	if len(pSpec.EnvironmentVariables) > 0 {
-		envVarsMap = slice.AppendUniqueToMapFromSlice(envVarsMap, pSpec.EnvironmentVariables)
-	}
-
-	pSpec.EnvironmentVariables = []string{}
-	for k, v := range envVarsMap {
-		pSpec.EnvironmentVariables = append(pSpec.EnvironmentVariables, k+v)
+// filterEnvVars sets the env vars of the package spec from every source, taken in order of
+// precedence of --env-var >> --env-file >> profiles, a profile's env vars over its env files and a
+// profile over the profiles it extends, see EnvVars
+func filterEnvVars(cmd *cobra.Command, config core.Config, pSpec *core.PackageSpec) (*core.PackageSpec, error) {
+	envVars, err := EnvVars(cmd, config)
+	if err != nil {
+		return nil, err
 	}
+	pSpec.EnvironmentVariables = envVars.Strings()
 
 	return pSpec, nil
 }
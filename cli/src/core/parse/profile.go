@@ -1,7 +1,11 @@
 package parse
 
 import (
+	"strings"
+
 	"cli/core"
+	"cli/core/env"
+	"cli/core/exitcode"
 	"cli/core/state"
 	"cli/util/slice"
 
@@ -10,18 +14,11 @@ import (
 )
 
 func getPackageSpecFromProfile(cmd *cobra.Command, config core.Config, packageSpec core.PackageSpec) (*core.PackageSpec, error) {
-	profile := core.Profile{}
-	profileName, err := cmd.Flags().GetString("profile")
+	layers, err := selectedProfileLayers(cmd, config)
 	if err != nil {
-		return nil, errors.Wrap(err, "")
-	}
-
-	for _, p := range config.Profiles {
-		if p.Name == profileName {
-			profile = p
-			break
-		}
+		return nil, err
 	}
+	profile := mergeLayers(layers)
 
 	if !cmd.Flags().Changed("dev") && profile.Dev {
 		packageSpec.IsDev = profile.Dev
@@ -35,24 +32,154 @@ func getPackageSpecFromProfile(cmd *cobra.Command, config core.Config, packageSp
 		packageSpec.Packages = append(profile.Packages, packageSpec.Packages...)
 	}
 
-	envVarsMap := make(map[string]string)
-	if len(profile.EnvVars) > 0 {
-		envVarsMap = slice.AppendUniqueToMapFromSlice(envVarsMap, profile.EnvVars)
+	return &packageSpec, nil
+}
+
+// selectedProfileLayers returns the layers of the profiles selected with --profile, see
+// profileLayers
+func selectedProfileLayers(cmd *cobra.Command, config core.Config) ([]core.Profile, error) {
+	profileNames, err := cmd.Flags().GetStringSlice("profile")
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	return profileLayers(config, profileNames)
+}
+
+// addProfileEnvVars adds the env vars of the profile layers to envVars layer by layer, so that a
+// profile takes precedence over the profiles it extends. The env vars of the env files of a layer
+// are added before its envVars, so that the envVars of a profile take precedence over its env
+// files. An env file listed by several layers is only added at its first occurrence.
+func addProfileEnvVars(envVars *env.Set, layers []core.Profile) error {
+	var envFiles []string
+	for _, layer := range layers {
+		for _, envFile := range layer.EnvFiles {
+			if slice.SliceContains(envFiles, envFile) {
+				continue
+			}
+			envFiles = append(envFiles, envFile)
+
+			fileEnvVars, err := state.ReadEnvFiles([]string{envFile})
+			if err != nil {
+				return err
+			}
+			envVars.Add(fileEnvVars, "profile "+layer.Name+" env file "+envFile)
+		}
+
+		envVars.Add(layer.EnvVars, "profile "+layer.Name+" envVars")
+	}
+
+	return nil
+}
+
+// resolveProfiles combines the profiles named on the command-line, each including the profiles it
+// extends, into one profile, see profileLayers and mergeProfiles
+func resolveProfiles(config core.Config, names []string) (core.Profile, error) {
+	layers, err := profileLayers(config, names)
+	if err != nil {
+		return core.Profile{}, err
 	}
 
-	if len(profile.EnvFiles) > 0 {
-		envViper, err := state.GetEnvironmentVariableViper(profile.EnvFiles)
+	resolved := mergeLayers(layers)
+	resolved.Name = strings.Join(names, ",")
+
+	return resolved, nil
+}
+
+// profileLayers returns the profiles named on the command-line and the profiles they extend, in
+// increasing order of precedence: the profiles a profile extends come before the profile, and a
+// later --profile after an earlier one. A profile extended more than once is only layered at its
+// first occurrence.
+func profileLayers(config core.Config, names []string) ([]core.Profile, error) {
+	var layers []core.Profile
+	layered := make(map[string]bool)
+	for _, name := range names {
+		err := addProfileLayers(config, name, nil, layered, &layers)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		envVariables := state.GetEnvVariableString(envViper)
-		envVarsMap = slice.AppendUniqueToMapFromSlice(envVarsMap, envVariables)
+	return layers, nil
+}
+
+// addProfileLayers appends the profile with name to layers after the profiles it extends. stack
+// holds the profiles being layered, to detect profiles extending each other.
+func addProfileLayers(config core.Config, name string, stack []string, layered map[string]bool, layers *[]core.Profile) error {
+	for i, resolving := range stack {
+		if resolving == name {
+			cycle := append(append([]string{}, stack[i:]...), name)
+			return exitcode.New(exitcode.ConfigInvalid, errors.Wrap(ErrProfileCycle, strings.Join(cycle, " -> ")), "Remove the cycle from the extends of the profiles in the config file")
+		}
+	}
+	if layered[name] {
+		return nil
+	}
+
+	var profile *core.Profile
+	for i := range config.Profiles {
+		if config.Profiles[i].Name == name {
+			profile = &config.Profiles[i]
+			break
+		}
+	}
+	if profile == nil {
+		detail := name
+		if len(stack) > 0 {
+			detail += " (extended by " + stack[len(stack)-1] + ")"
+		}
+		return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrNoSuchProfile, detail), "Check the profile names in the config file")
 	}
 
-	for k, v := range envVarsMap {
-		packageSpec.EnvironmentVariables = append(packageSpec.EnvironmentVariables, k+v)
+	stack = append(stack, name)
+	for _, parent := range profile.Extends {
+		err := addProfileLayers(config, parent, stack, layered, layers)
+		if err != nil {
+			return err
+		}
 	}
 
-	return &packageSpec, nil
+	layer := *profile
+	layer.Extends = nil
+	*layers = append(*layers, layer)
+	layered[name] = true
+
+	return nil
+}
+
+func mergeLayers(layers []core.Profile) core.Profile {
+	var merged core.Profile
+	for _, layer := range layers {
+		merged = mergeProfiles(merged, layer)
+	}
+
+	return merged
+}
+
+// mergeProfiles layers overlay onto base:
+//   - packages and env files are the union of those of both profiles, in order
+//   - env vars of overlay come after, and so override, those of base
+//   - dev and only are set if either profile sets them
+func mergeProfiles(base, overlay core.Profile) core.Profile {
+	merged := core.Profile{
+		Name: overlay.Name,
+		Dev:  base.Dev || overlay.Dev,
+		Only: base.Only || overlay.Only,
+	}
+
+	merged.Packages = appendUnique(append([]string{}, base.Packages...), overlay.Packages...)
+	merged.EnvFiles = appendUnique(append([]string{}, base.EnvFiles...), overlay.EnvFiles...)
+	merged.EnvVars = append(append([]string{}, base.EnvVars...), overlay.EnvVars...)
+
+	return merged
+}
+
+func appendUnique(s []string, elements ...string) []string {
+	for _, element := range elements {
+		if !slice.SliceContains(s, element) {
+			s = append(s, element)
+		}
+	}
+
+	return s
 }
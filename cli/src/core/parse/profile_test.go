@@ -2,11 +2,12 @@ package parse
 
 import (
 	"os"
-	"sort"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"cli/core"
+	"cli/core/env"
 
 	"github.com/luno/jettison/jtest"
 	"github.com/spf13/cobra"
@@ -24,14 +25,13 @@ func Test_getPackageSpecFromProfile(t *testing.T) {
 		hookFunc            func(cmd *cobra.Command)
 	}
 
-	// TODO: throw error if specifying non-existant profile
 	testCases := []cases{
-		// case: return error from non-existant env file directory
+		// case: return error from non-existant profile
 		{
 			configFilePath:      wd + "/../../features/unit-test-configs/config-case-5.yml",
-			expectedErrorString: ".env.none: no such file or directory",
+			expectedErrorString: ErrNoSuchProfile.Error(),
 			hookFunc: func(cmd *cobra.Command) {
-				err = cmd.Flags().Set("profile", "bad-env-file-path")
+				err = cmd.Flags().Set("profile", "none")
 				jtest.RequireNil(t, err)
 			},
 		},
@@ -39,9 +39,8 @@ func Test_getPackageSpecFromProfile(t *testing.T) {
 		{
 			configFilePath: wd + "/../../features/unit-test-configs/config-case-5.yml",
 			expectedConfig: &core.PackageSpec{
-				EnvironmentVariables: []string{"FIRST_ENV_VAR=number_one", "SECOND_ENV_VAR=number_two"},
-				Packages:             []string{"dashboard-visualiser-jsreport", "disi-on-platform"},
-				IsDev:                true,
+				Packages: []string{"dashboard-visualiser-jsreport", "disi-on-platform"},
+				IsDev:    true,
 			},
 			hookFunc: func(cmd *cobra.Command) {
 				err = cmd.Flags().Set("profile", "dev")
@@ -52,9 +51,8 @@ func Test_getPackageSpecFromProfile(t *testing.T) {
 		{
 			configFilePath: wd + "/../../features/unit-test-configs/config-case-5.yml",
 			expectedConfig: &core.PackageSpec{
-				EnvironmentVariables: []string{"FIRST_ENV_VAR=number_one", "SECOND_ENV_VAR=number_two"},
-				Packages:             []string{"dashboard-visualiser-jsreport", "disi-on-platform", "core"},
-				IsOnly:               true,
+				Packages: []string{"dashboard-visualiser-jsreport", "disi-on-platform", "core"},
+				IsOnly:   true,
 			},
 			hookFunc: func(cmd *cobra.Command) {
 				err = cmd.Flags().Set("profile", "only")
@@ -65,10 +63,9 @@ func Test_getPackageSpecFromProfile(t *testing.T) {
 		{
 			configFilePath: wd + "/../../features/unit-test-configs/config-case-5.yml",
 			expectedConfig: &core.PackageSpec{
-				EnvironmentVariables: []string{"FIRST_ENV_VAR=number_one", "SECOND_ENV_VAR=number_two"},
-				Packages:             []string{"core"},
-				IsDev:                true,
-				IsOnly:               true,
+				Packages: []string{"core"},
+				IsDev:    true,
+				IsOnly:   true,
 			},
 			hookFunc: func(cmd *cobra.Command) {
 				err = cmd.Flags().Set("profile", "dev-and-only")
@@ -88,11 +85,145 @@ func Test_getPackageSpecFromProfile(t *testing.T) {
 			
 			require.Equal(t, strings.Contains(err.Error(), tc.expectedErrorString), true)
 		} else if tc.expectedConfig != nil {
-			sort.Slice(pSpec.EnvironmentVariables, func(i, j int) bool {
-				return strings.Contains(pSpec.EnvironmentVariables[i], "FIRST_ENV_VAR")
-			})
-
 			require.Equal(t, tc.expectedConfig, pSpec)
 		}
 	}
 }
+
+func Test_resolveProfiles(t *testing.T) {
+	config := core.Config{
+		Profiles: []core.Profile{
+			{Name: "base", Packages: []string{"core"}, EnvVars: []string{"LOG_LEVEL=info", "REPLICAS=1"}, EnvFiles: []string{".env.base"}},
+			{Name: "dev", Extends: []string{"base"}, Packages: []string{"client"}, EnvVars: []string{"LOG_LEVEL=debug"}, EnvFiles: []string{".env.dev"}, Dev: true},
+			{Name: "monitoring", Packages: []string{"monitoring", "core"}, EnvVars: []string{"REPLICAS=2"}, Only: true},
+			{Name: "broken", Extends: []string{"missing"}},
+			{Name: "a", Extends: []string{"b"}},
+			{Name: "b", Extends: []string{"a"}},
+		},
+	}
+
+	type cases struct {
+		names               []string
+		expectedProfile     core.Profile
+		expectedErrorString string
+	}
+
+	testCases := []cases{
+		// case: a profile overrides the profile it extends
+		{
+			names: []string{"dev"},
+			expectedProfile: core.Profile{
+				Name:     "dev",
+				Packages: []string{"core", "client"},
+				EnvVars:  []string{"LOG_LEVEL=info", "REPLICAS=1", "LOG_LEVEL=debug"},
+				EnvFiles: []string{".env.base", ".env.dev"},
+				Dev:      true,
+			},
+		},
+		// case: a later --profile overrides an earlier one
+		{
+			names: []string{"dev", "monitoring"},
+			expectedProfile: core.Profile{
+				Name:     "dev,monitoring",
+				Packages: []string{"core", "client", "monitoring"},
+				EnvVars:  []string{"LOG_LEVEL=info", "REPLICAS=1", "LOG_LEVEL=debug", "REPLICAS=2"},
+				EnvFiles: []string{".env.base", ".env.dev"},
+				Dev:      true,
+				Only:     true,
+			},
+		},
+		// case: extending an undefined profile
+		{
+			names:               []string{"broken"},
+			expectedErrorString: "missing (extended by broken): " + ErrNoSuchProfile.Error(),
+		},
+		// case: profiles extending each other
+		{
+			names:               []string{"a"},
+			expectedErrorString: "a -> b -> a: " + ErrProfileCycle.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		profile, err := resolveProfiles(config, tc.names)
+		if tc.expectedErrorString != "" {
+			require.NotNil(t, err)
+			require.Equal(t, tc.expectedErrorString, err.Error())
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		require.Equal(t, tc.expectedProfile, profile)
+	}
+}
+
+func Test_addProfileEnvVars(t *testing.T) {
+	config := core.Config{
+		Profiles: []core.Profile{
+			{Name: "base", EnvVars: []string{"LOG_LEVEL=info", "REPLICAS=1"}},
+			{Name: "dev", Extends: []string{"base"}, EnvVars: []string{"LOG_LEVEL=debug"}},
+			{Name: "monitoring", Extends: []string{"base"}, EnvVars: []string{"REPLICAS=2"}},
+		},
+	}
+
+	layers, err := profileLayers(config, []string{"dev", "monitoring"})
+	jtest.RequireNil(t, err)
+
+	var names []string
+	for _, layer := range layers {
+		names = append(names, layer.Name)
+	}
+	require.Equal(t, []string{"base", "dev", "monitoring"}, names)
+
+	envVars := env.NewSet()
+	jtest.RequireNil(t, addProfileEnvVars(envVars, layers))
+
+	require.Equal(t, []env.Variable{
+		{
+			Name:       "LOG_LEVEL",
+			Value:      env.Value{Value: "debug", Source: "profile dev envVars"},
+			Overridden: []env.Value{{Value: "info", Source: "profile base envVars"}},
+		},
+		{
+			Name:       "REPLICAS",
+			Value:      env.Value{Value: "2", Source: "profile monitoring envVars"},
+			Overridden: []env.Value{{Value: "1", Source: "profile base envVars"}},
+		},
+	}, envVars.Variables())
+}
+
+func Test_addProfileEnvVarsLayerOrder(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, ".env.dev")
+	jtest.RequireNil(t, os.WriteFile(envFile, []byte("LOG_LEVEL=debug\nREPLICAS=3\n"), 0o644))
+
+	config := core.Config{
+		Profiles: []core.Profile{
+			{Name: "base", EnvVars: []string{"LOG_LEVEL=info", "REPLICAS=1"}},
+			{Name: "dev", Extends: []string{"base"}, EnvFiles: []string{envFile}, EnvVars: []string{"REPLICAS=2"}},
+		},
+	}
+
+	layers, err := profileLayers(config, []string{"dev"})
+	jtest.RequireNil(t, err)
+
+	envVars := env.NewSet()
+	jtest.RequireNil(t, addProfileEnvVars(envVars, layers))
+
+	// The env file of dev overrides the envVars of base, and the envVars of dev its env file
+	require.Equal(t, []env.Variable{
+		{
+			Name:       "LOG_LEVEL",
+			Value:      env.Value{Value: "debug", Source: "profile dev env file " + envFile},
+			Overridden: []env.Value{{Value: "info", Source: "profile base envVars"}},
+		},
+		{
+			Name:  "REPLICAS",
+			Value: env.Value{Value: "2", Source: "profile dev envVars"},
+			Overridden: []env.Value{
+				{Value: "3", Source: "profile dev env file " + envFile},
+				{Value: "1", Source: "profile base envVars"},
+			},
+		},
+	}, envVars.Variables())
+}
@@ -1,10 +1,8 @@
 package parse
 
 import (
-	"path"
-	"strings"
-
 	"cli/core"
+	"cli/core/exitcode"
 	"cli/util/slice"
 
 	"github.com/luno/jettison/errors"
@@ -17,9 +15,17 @@ var (
 	ErrUndefinedPackage         = errors.New("packages in command-line not in any of packages, custom-packages, or command-line custom-packages")
 	ErrUndefinedProfilePackages = errors.New("packages in profile not in any of packages or custom-packages")
 	ErrNoSuchProfile            = errors.New("no such profile")
+	ErrProfileCycle             = errors.New("profiles extend each other")
 	ErrNoPackagesInProfile      = errors.New("no packages in profile")
+	ErrInvalidPullPolicy        = errors.New("invalid pull policy, expected always, missing or never")
+	ErrImageNotPresent          = errors.New("image is not present locally and the pull policy is never")
+	ErrInvalidConcurrency       = errors.New("concurrency must be a positive number")
+	ErrInvalidEnvVar            = errors.New("env var must be in KEY=value form")
 )
 
+// ConcurrencyHint tells the user how to resolve ErrInvalidConcurrency
+const ConcurrencyHint = "Pass a number of at least 1 to --concurrency"
+
 func validate(cmd *cobra.Command, config *core.Config) error {
 	customPackagePaths, err := cmd.Flags().GetStringSlice("custom-path")
 	if err != nil {
@@ -27,9 +33,9 @@ func validate(cmd *cobra.Command, config *core.Config) error {
 	}
 
 	if config.Image == "" {
-		return errors.Wrap(ErrNoConfigImage, "")
+		return exitcode.New(exitcode.ConfigInvalid, errors.Wrap(ErrNoConfigImage, ""), "Set the image field in the config file")
 	} else if len(config.Packages) == 0 && len(config.CustomPackages) == 0 && len(customPackagePaths) == 0 {
-		return errors.Wrap(ErrNoPackages, "")
+		return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrNoPackages, ""), "Add packages or customPackages to the config file")
 	}
 
 	packages, err := cmd.Flags().GetStringSlice("name")
@@ -43,13 +49,13 @@ func validate(cmd *cobra.Command, config *core.Config) error {
 		}
 	}
 
-	profileName, err := cmd.Flags().GetString("profile")
+	profileNames, err := cmd.Flags().GetStringSlice("profile")
 	if err != nil {
 		return errors.Wrap(err, "")
 	}
 
-	if profileName != "" {
-		return validateProfile(cmd, profileName, config)
+	if len(profileNames) > 0 {
+		return validateProfile(cmd, profileNames, config)
 	}
 
 	return nil
@@ -86,7 +92,7 @@ func validateCommandLinePackages(cmd *cobra.Command, config *core.Config) error
 		}
 
 		for _, customPath := range customPackagePaths {
-			customPackName := strings.TrimSuffix(path.Base(path.Clean(customPath)), path.Ext(customPath))
+			customPackName := GetCustomPackageName(core.CustomPackage{Path: customPath})
 			if pack == customPackName {
 				delete(packagesMap, pack)
 				break
@@ -96,32 +102,25 @@ func validateCommandLinePackages(cmd *cobra.Command, config *core.Config) error
 
 	if len(packagesMap) > 0 {
 		for k := range packagesMap {
-			return errors.Wrap(ErrUndefinedPackage, k)
+			return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrUndefinedPackage, k), "Add the package to the packages or customPackages of the config file, or pass its path with --custom-path")
 		}
 	}
 
 	return nil
 }
 
-func validateProfile(cmd *cobra.Command, profileName string, config *core.Config) error {
-	profilePackagesMap := make(map[string]bool)
-	var profile core.Profile
-	for _, prof := range config.Profiles {
-		if prof.Name == profileName {
-			profile = prof
-			for _, p := range prof.Packages {
-				profilePackagesMap[p] = true
-			}
-
-			break
-		}
+func validateProfile(cmd *cobra.Command, profileNames []string, config *core.Config) error {
+	profile, err := resolveProfiles(*config, profileNames)
+	if err != nil {
+		return err
 	}
 
-	if profile.Name == "" {
-		return errors.Wrap(ErrNoSuchProfile, profileName)
+	profilePackagesMap := make(map[string]bool)
+	for _, p := range profile.Packages {
+		profilePackagesMap[p] = true
 	}
 	if len(profilePackagesMap) < 1 {
-		return errors.Wrap(ErrNoPackagesInProfile, profileName)
+		return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrNoPackagesInProfile, profile.Name), "Add packages to the profile in the config file")
 	}
 
 	for _, pack := range profile.Packages {
@@ -137,7 +136,7 @@ func validateProfile(cmd *cobra.Command, profileName string, config *core.Config
 
 	if len(profilePackagesMap) > 0 {
 		for k := range profilePackagesMap {
-			return errors.Wrap(ErrUndefinedProfilePackages, k)
+			return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrUndefinedProfilePackages, k), "Add the package to the packages or customPackages of the config file")
 		}
 	}
 
@@ -6,7 +6,6 @@ import (
 
 	"cli/cmd/flags"
 	"cli/core"
-	"cli/core/state"
 
 	"github.com/luno/jettison/jtest"
 	"github.com/spf13/cobra"
@@ -110,10 +109,7 @@ func Test_validate(t *testing.T) {
 }
 
 func initCommand(t *testing.T, configFilePath string, hook func(cmd *cobra.Command, config *core.Config)) (*cobra.Command, *core.Config) {
-	configViper, err := state.SetConfigViper(configFilePath)
-	jtest.RequireNil(t, err)
-
-	config, err := unmarshalConfig(configViper)
+	config, err := unmarshalConfig(readConfigViper(t, configFilePath))
 	jtest.RequireNil(t, err)
 
 	cmd := &cobra.Command{}
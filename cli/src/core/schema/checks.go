@@ -0,0 +1,146 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// checkUniqueness reports profiles and custom packages that reuse the name or id of an earlier one
+func checkUniqueness(root *yaml.Node, report func(*yaml.Node, string)) {
+	for _, list := range []struct {
+		key, field, description string
+	}{
+		{"profiles", "name", "profile name"},
+		{"customPackages", "id", "custom package id"},
+	} {
+		_, items := mappingEntry(root, list.key)
+		if items == nil || items.Kind != yaml.SequenceNode {
+			continue
+		}
+
+		seen := make(map[string]*yaml.Node)
+		for _, item := range items.Content {
+			_, value := mappingEntry(resolveAlias(item), list.field)
+			if value == nil || value.Kind != yaml.ScalarNode || value.Value == "" {
+				continue
+			}
+
+			if first, ok := seen[value.Value]; ok {
+				report(value, fmt.Sprintf("duplicate %s '%s', first defined at line %d", list.description, value.Value, first.Line))
+				continue
+			}
+			seen[value.Value] = value
+		}
+	}
+}
+
+// checkProfilePackages reports profile packages that are in neither packages nor customPackages
+func checkProfilePackages(root *yaml.Node, report func(*yaml.Node, string)) {
+	defined := make(map[string]bool)
+	if _, packages := mappingEntry(root, "packages"); packages != nil && packages.Kind == yaml.SequenceNode {
+		for _, pack := range packages.Content {
+			defined[resolveAlias(pack).Value] = true
+		}
+	}
+	if _, customPackages := mappingEntry(root, "customPackages"); customPackages != nil && customPackages.Kind == yaml.SequenceNode {
+		for _, customPackage := range customPackages.Content {
+			if _, id := mappingEntry(resolveAlias(customPackage), "id"); id != nil {
+				defined[id.Value] = true
+			}
+		}
+	}
+
+	_, profiles := mappingEntry(root, "profiles")
+	if profiles == nil || profiles.Kind != yaml.SequenceNode {
+		return
+	}
+
+	for _, profile := range profiles.Content {
+		profile = resolveAlias(profile)
+
+		var name string
+		if _, nameNode := mappingEntry(profile, "name"); nameNode != nil {
+			name = nameNode.Value
+		}
+
+		_, packages := mappingEntry(profile, "packages")
+		if packages == nil || packages.Kind != yaml.SequenceNode {
+			continue
+		}
+
+		for _, pack := range packages.Content {
+			pack = resolveAlias(pack)
+			if pack.Kind != yaml.ScalarNode || defined[pack.Value] {
+				continue
+			}
+			report(pack, fmt.Sprintf("package '%s' of profile '%s' is not in packages or customPackages", pack.Value, name))
+		}
+	}
+}
+
+// checkProfileExtends reports profiles extending profiles that aren't defined, and profiles
+// extending each other
+func checkProfileExtends(root *yaml.Node, report func(*yaml.Node, string)) {
+	_, profiles := mappingEntry(root, "profiles")
+	if profiles == nil || profiles.Kind != yaml.SequenceNode {
+		return
+	}
+
+	extends := make(map[string][]*yaml.Node)
+	var names []string
+	for _, profile := range profiles.Content {
+		profile = resolveAlias(profile)
+
+		_, nameNode := mappingEntry(profile, "name")
+		if nameNode == nil || nameNode.Kind != yaml.ScalarNode {
+			continue
+		}
+		if _, ok := extends[nameNode.Value]; !ok {
+			names = append(names, nameNode.Value)
+			extends[nameNode.Value] = nil
+		}
+
+		_, parents := mappingEntry(profile, "extends")
+		if parents == nil || parents.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, parent := range parents.Content {
+			extends[nameNode.Value] = append(extends[nameNode.Value], resolveAlias(parent))
+		}
+	}
+
+	for _, name := range names {
+		for _, parent := range extends[name] {
+			if _, ok := extends[parent.Value]; !ok {
+				report(parent, fmt.Sprintf("profile '%s' extends undefined profile '%s'", name, parent.Value))
+			}
+		}
+	}
+
+	// Report each cycle once, at the extends entry closing it
+	done := make(map[string]bool)
+	var visit func(name string, stack []string)
+	visit = func(name string, stack []string) {
+		stack = append(stack, name)
+		for _, parent := range extends[name] {
+			for i, visiting := range stack {
+				if visiting == parent.Value {
+					cycle := append(append([]string{}, stack[i:]...), parent.Value)
+					report(parent, fmt.Sprintf("profiles extend each other: %s", strings.Join(cycle, " -> ")))
+				}
+			}
+			if _, ok := extends[parent.Value]; ok && !done[parent.Value] && !slices.Contains(stack, parent.Value) {
+				visit(parent.Value, stack)
+			}
+		}
+		done[name] = true
+	}
+	for _, name := range names {
+		if !done[name] {
+			visit(name, nil)
+		}
+	}
+}
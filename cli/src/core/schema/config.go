@@ -0,0 +1,311 @@
+package schema
+
+import (
+	_ "embed"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/luno/jettison/errors"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// The schema is a copy of schema/config.schema.json at the root of the repository
+//
+//go:generate cp ../../../../schema/config.schema.json .
+//go:embed config.schema.json
+var configSchemaSource string
+
+var configSchema = jsonschema.MustCompileString("config.schema.json", configSchemaSource)
+
+var ErrInvalidConfig = errors.New("invalid config file")
+
+// ValidationHint tells the user how to resolve a *ValidationError
+const ValidationHint = "Fix the listed problems in the config file, as described by schema/config.schema.json"
+
+// Diagnostic is a problem found in a config file, at a 1-based line and column. Column is 0 when
+// only the line is known, and Line is 0 when neither is, eg. in TOML config files.
+type Diagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s", d.File, d.Message)
+	}
+	if d.Column == 0 {
+		return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+	}
+
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+}
+
+// ValidationError reports every problem found in a config file. It matches ErrInvalidConfig.
+type ValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *ValidationError) Error() string {
+	lines := []string{fmt.Sprintf("%d problem(s) in config file", len(e.Diagnostics))}
+	for _, diagnostic := range e.Diagnostics {
+		lines = append(lines, "  "+diagnostic.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+var yamlLinePattern = regexp.MustCompile(`line (\d+): `)
+
+// Parse parses the content of the config file at path into a YAML node, as TOML if path has a
+// .toml extension and as YAML otherwise, which JSON is a subset of
+func Parse(path string, data []byte) (*yaml.Node, *ValidationError) {
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		return ParseTOML(path, data)
+	}
+
+	return ParseYAML(path, data)
+}
+
+// ParseTOML parses the content of the TOML config file at path into a YAML node. TOML documents
+// don't keep the position of values, so the nodes have no line and column.
+func ParseTOML(path string, data []byte) (*yaml.Node, *ValidationError) {
+	var document map[string]interface{}
+	err := toml.Unmarshal(data, &document)
+	if err != nil {
+		diagnostic := Diagnostic{File: path, Line: 1, Message: err.Error()}
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			diagnostic.Line, diagnostic.Column = decodeErr.Position()
+		}
+		return nil, &ValidationError{Diagnostics: []Diagnostic{diagnostic}}
+	}
+
+	var root yaml.Node
+	err = root.Encode(document)
+	if err != nil {
+		return nil, &ValidationError{Diagnostics: []Diagnostic{{File: path, Line: 1, Message: err.Error()}}}
+	}
+
+	return &root, nil
+}
+
+// ParseYAML parses the content of the config file at path into its root node. An empty file is an
+// empty mapping.
+func ParseYAML(path string, data []byte) (*yaml.Node, *ValidationError) {
+	var document yaml.Node
+	err := yaml.Unmarshal(data, &document)
+	if err != nil {
+		line := 1
+		message := strings.TrimPrefix(err.Error(), "yaml: ")
+		if match := yamlLinePattern.FindStringSubmatch(message); match != nil {
+			line, _ = strconv.Atoi(match[1])
+			message = strings.Replace(message, match[0], "", 1)
+		}
+		return nil, &ValidationError{Diagnostics: []Diagnostic{{File: path, Line: line, Message: message}}}
+	}
+
+	if len(document.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: 1, Column: 1}, nil
+	}
+
+	return document.Content[0], nil
+}
+
+// CheckNode returns the problems found in the config document at root, sorted by their position.
+// fileOf returns the file each node was read from, for documents assembled from several files.
+func CheckNode(root *yaml.Node, fileOf func(*yaml.Node) string) []Diagnostic {
+	var diagnostics []Diagnostic
+	fileOrder := make(map[string]int)
+	report := func(node *yaml.Node, message string) {
+		file := fileOf(node)
+		if _, ok := fileOrder[file]; !ok {
+			fileOrder[file] = len(fileOrder)
+		}
+		diagnostics = append(diagnostics, Diagnostic{File: file, Line: node.Line, Column: node.Column, Message: message})
+	}
+
+	err := configSchema.Validate(toValue(root))
+	var validationErr *jsonschema.ValidationError
+	if errors.As(err, &validationErr) {
+		for _, cause := range leafErrors(validationErr) {
+			reportSchemaError(root, cause, report)
+		}
+	}
+
+	checkUniqueness(root, report)
+	checkProfilePackages(root, report)
+	checkProfileExtends(root, report)
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].File != diagnostics[j].File {
+			return fileOrder[diagnostics[i].File] < fileOrder[diagnostics[j].File]
+		}
+		if diagnostics[i].Line != diagnostics[j].Line {
+			return diagnostics[i].Line < diagnostics[j].Line
+		}
+		return diagnostics[i].Column < diagnostics[j].Column
+	})
+
+	return diagnostics
+}
+
+func leafErrors(validationErr *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(validationErr.Causes) == 0 {
+		return []*jsonschema.ValidationError{validationErr}
+	}
+
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range validationErr.Causes {
+		leaves = append(leaves, leafErrors(cause)...)
+	}
+
+	return leaves
+}
+
+var quotedPattern = regexp.MustCompile(`'([^']*)'`)
+
+// reportSchemaError reports a schema violation at the YAML node it refers to. Unknown keys are
+// reported at each key rather than at the mapping holding them.
+func reportSchemaError(root *yaml.Node, validationErr *jsonschema.ValidationError, report func(*yaml.Node, string)) {
+	node, location := lookup(root, validationErr.InstanceLocation)
+
+	if strings.HasSuffix(validationErr.KeywordLocation, "/additionalProperties") {
+		for _, match := range quotedPattern.FindAllStringSubmatch(validationErr.Message, -1) {
+			keyNode, _ := mappingEntry(node, match[1])
+			if keyNode == nil {
+				keyNode = node
+			}
+			report(keyNode, fmt.Sprintf("unknown key '%s'", joinLocation(location, match[1])))
+		}
+		return
+	}
+
+	if location == "" {
+		report(node, validationErr.Message)
+		return
+	}
+	report(node, location+": "+validationErr.Message)
+}
+
+// lookup returns the node at a JSON pointer, with the pointer formatted as a path like
+// profiles[0].name
+func lookup(root *yaml.Node, pointer string) (*yaml.Node, string) {
+	node := root
+	var location string
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		switch node.Kind {
+		case yaml.MappingNode:
+			_, value := mappingEntry(node, token)
+			if value == nil {
+				return node, location
+			}
+			node = value
+			location = joinLocation(location, token)
+
+		case yaml.SequenceNode:
+			index, err := strconv.Atoi(token)
+			if err != nil || index >= len(node.Content) {
+				return node, location
+			}
+			node = node.Content[index]
+			location += "[" + token + "]"
+
+		default:
+			return node, location
+		}
+	}
+
+	return node, location
+}
+
+func joinLocation(location, key string) string {
+	if location == "" {
+		return key
+	}
+
+	return location + "." + key
+}
+
+// mappingEntry returns the key and value nodes of key in a mapping node
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], resolveAlias(node.Content[i+1])
+		}
+	}
+
+	return nil, nil
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	return node
+}
+
+// toValue converts a YAML node into the JSON types the schema validator expects
+func toValue(node *yaml.Node) interface{} {
+	node = resolveAlias(node)
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil
+		}
+		return toValue(node.Content[0])
+
+	case yaml.MappingNode:
+		value := make(map[string]interface{})
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value[node.Content[i].Value] = toValue(node.Content[i+1])
+		}
+		return value
+
+	case yaml.SequenceNode:
+		value := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			value = append(value, toValue(item))
+		}
+		return value
+	}
+
+	switch node.ShortTag() {
+	case "!!null":
+		return nil
+	case "!!bool":
+		var value bool
+		if node.Decode(&value) == nil {
+			return value
+		}
+	case "!!int", "!!float":
+		var value float64
+		if node.Decode(&value) == nil {
+			return value
+		}
+	}
+
+	return node.Value
+}
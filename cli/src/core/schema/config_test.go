@@ -0,0 +1,208 @@
+package schema
+
+import (
+	"os"
+	"testing"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSchemaMatchesRepository(t *testing.T) {
+	repositorySchema, err := os.ReadFile("../../../../schema/config.schema.json")
+	jtest.RequireNil(t, err)
+
+	require.Equal(t, string(repositorySchema), configSchemaSource, "run go generate to update the embedded schema")
+}
+
+// checkConfig returns the problems found in the content of the config file at path, parsing it as
+// the config loader does and checking it as parse.ValidateConfigDocument does
+func checkConfig(path string, data []byte) []Diagnostic {
+	root, err := Parse(path, data)
+	if err != nil {
+		return err.Diagnostics
+	}
+
+	return CheckNode(root, func(*yaml.Node) string { return path })
+}
+
+func TestCheckConfig(t *testing.T) {
+	type cases struct {
+		config              string
+		expectedDiagnostics []string
+	}
+
+	testCases := []cases{
+		// case: valid config
+		{
+			config: `
+projectName: platform
+image: jembi/platform
+packages:
+  - core
+customPackages:
+  - id: disi-on-platform
+    path: git@github.com:jembi/disi-on-platform.git
+    ref: v1.0.0
+    sha256: 0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef
+profiles:
+  - name: dev
+    packages: [core, disi-on-platform]
+    envFiles: [.env.dev]
+    dev: true
+`,
+		},
+		// case: unknown keys at every level
+		{
+			config: `
+image: jembi/platform
+custmPackages:
+  - id: disi-on-platform
+customPackages:
+  - id: disi-on-platform
+    path: ./disi
+    auth:
+      token: abc
+`,
+			expectedDiagnostics: []string{
+				"config.yml:3:1: unknown key 'custmPackages'",
+				"config.yml:9:7: unknown key 'customPackages[0].auth.token'",
+			},
+		},
+		// case: wrong types and missing required fields
+		{
+			config: `
+packages:
+  - id: client
+profiles:
+  - name: dev
+    dev: "yes"
+`,
+			expectedDiagnostics: []string{
+				"config.yml:2:1: missing properties: 'image'",
+				"config.yml:3:5: packages[0]: expected string, but got object",
+				"config.yml:6:10: profiles[0].dev: expected boolean, but got string",
+			},
+		},
+		// case: duplicates and undefined profile packages
+		{
+			config: `
+image: jembi/platform
+packages: [core]
+customPackages:
+  - id: disi
+    path: ./disi
+  - id: disi
+    path: ./disi-v2
+profiles:
+  - name: dev
+    packages: [core, client]
+  - name: dev
+    packages: [disi]
+`,
+			expectedDiagnostics: []string{
+				"config.yml:7:9: duplicate custom package id 'disi', first defined at line 5",
+				"config.yml:11:22: package 'client' of profile 'dev' is not in packages or customPackages",
+				"config.yml:12:11: duplicate profile name 'dev', first defined at line 10",
+			},
+		},
+		// case: undefined and cyclic extended profiles
+		{
+			config: `
+image: jembi/platform
+packages: [core]
+profiles:
+  - name: base
+    packages: [core]
+  - name: dev
+    extends: [base, staging]
+  - name: a
+    extends: [b]
+  - name: b
+    extends: [a]
+`,
+			expectedDiagnostics: []string{
+				"config.yml:8:21: profile 'dev' extends undefined profile 'staging'",
+				"config.yml:12:15: profiles extend each other: a -> b -> a",
+			},
+		},
+		// case: YAML syntax error
+		{
+			config: "image: jembi/platform\npackages:\n\t- core\n",
+			expectedDiagnostics: []string{
+				"config.yml:3: found character that cannot start any token",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		var diagnostics []string
+		for _, diagnostic := range checkConfig("config.yml", []byte(tc.config)) {
+			diagnostics = append(diagnostics, diagnostic.String())
+		}
+
+		require.Equal(t, tc.expectedDiagnostics, diagnostics)
+	}
+}
+
+func TestParse(t *testing.T) {
+	type cases struct {
+		path                string
+		config              string
+		expectedDiagnostics []string
+	}
+
+	testCases := []cases{
+		// case: valid JSON config
+		{
+			path:   "config.json",
+			config: `{"image": "jembi/platform", "packages": ["core"], "profiles": [{"name": "dev", "packages": ["core"]}]}`,
+		},
+		// case: JSON config with positions
+		{
+			path:   "config.json",
+			config: "{\n  \"image\": \"jembi/platform\",\n  \"pakages\": [\"core\"]\n}\n",
+			expectedDiagnostics: []string{
+				"config.json:3:3: unknown key 'pakages'",
+			},
+		},
+		// case: valid TOML config
+		{
+			path: "config.toml",
+			config: `image = "jembi/platform"
+packages = ["core"]
+
+[[profiles]]
+name = "dev"
+packages = ["core"]
+dev = true
+`,
+		},
+		// case: TOML config without positions
+		{
+			path:   "config.toml",
+			config: "image = \"jembi/platform\"\n\n[[profiles]]\nname = \"dev\"\ndev = \"yes\"\n",
+			expectedDiagnostics: []string{
+				"config.toml: profiles[0].dev: expected boolean, but got string",
+			},
+		},
+		// case: TOML syntax error
+		{
+			path:   "config.toml",
+			config: "image = \"jembi/platform\"\npackages = [\"core\"\n",
+			expectedDiagnostics: []string{
+				"config.toml:3:1: toml: expected character ] but the document ended here",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		var diagnostics []string
+		for _, diagnostic := range checkConfig(tc.path, []byte(tc.config)) {
+			diagnostics = append(diagnostics, diagnostic.String())
+		}
+
+		require.Equal(t, tc.expectedDiagnostics, diagnostics)
+	}
+}
@@ -1,87 +1,78 @@
 package state
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
+
+	"cli/core/exitcode"
 
 	"github.com/luno/jettison/errors"
-	"github.com/spf13/viper"
 )
 
-// TODO: EnvFiles might not be necessary
-var (
-	ConfigFile  string
-	EnvFiles    []string
-	configViper *viper.Viper
-)
+// ConfigFileEnvVar is the env var selecting the config file when --config isn't given
+const ConfigFileEnvVar = "INSTANT_CONFIG"
 
-func SetConfigViper(configFile string) (*viper.Viper, error) {
-	configViper = viper.New()
-	if configFile != "" {
-		absFilePath, err := filepath.Abs(configFile)
-		if err != nil {
-			return nil, errors.Wrap(err, "")
-		}
+// ConfigFileNames are the names of the config files looked for, in order of preference
+var ConfigFileNames = []string{"config.yaml", "config.yml", "config.json", "config.toml"}
+
+var ErrConfigNotFound = errors.New("no config file found")
 
-		configViper.SetConfigFile(absFilePath)
-	} else {
-		wd, err := os.Getwd()
+// ConfigFilePath returns the absolute path of configFile, or of the config file found by
+// FindConfigFile if configFile is empty. The file itself is read by the config loader.
+func ConfigFilePath(configFile string) (string, error) {
+	if configFile == "" {
+		var err error
+		configFile, err = FindConfigFile()
 		if err != nil {
-			return nil, errors.Wrap(err, "")
+			return "", err
 		}
-
-		configViper.AddConfigPath(wd)
-		configViper.SetConfigType("yaml")
-		configViper.SetConfigName("config")
 	}
 
-	err := configViper.ReadInConfig()
+	absFilePath, err := filepath.Abs(configFile)
 	if err != nil {
-		return nil, errors.Wrap(err, "")
+		return "", errors.Wrap(err, "")
 	}
 
-	return configViper, nil
+	return absFilePath, nil
 }
 
-func GetEnvironmentVariableViper(envFiles []string) (*viper.Viper, error) {
-	envVarViper := viper.New()
-
-	for i, envFile := range envFiles {
-		if !filepath.IsAbs(envFile) {
-			envFile = filepath.Join(filepath.Dir(configViper.ConfigFileUsed()), envFile)
-		}
+// FindConfigFile returns the config file to use when --config isn't given: the file named by the
+// INSTANT_CONFIG env var, or else the first of ConfigFileNames found in the working directory or
+// its parents, up to the root of the git repository or of the file system
+func FindConfigFile() (string, error) {
+	if configFile := os.Getenv(ConfigFileEnvVar); configFile != "" {
+		return configFile, nil
+	}
 
-		_, err := os.Stat(envFile)
-		if err != nil {
-			return nil, errors.Wrap(err, "")
-		}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
 
-		envVarViper.SetConfigType("env")
-		envVarViper.SetConfigFile(envFile)
-		if i == 0 {
-			err = envVarViper.ReadInConfig()
-			if err != nil {
-				return nil, errors.Wrap(err, "")
-			}
-		} else {
-			err := envVarViper.MergeInConfig()
-			if err != nil {
-				return nil, errors.Wrap(err, "")
-			}
-		}
+	configFile, searched, found := FindFileUpward(wd, ConfigFileNames)
+	if !found {
+		return "", exitcode.New(exitcode.ConfigInvalid, errors.Wrap(ErrConfigNotFound, "searched "+wd+" up to "+searched), "Run the command from the project directory or one below it, or pass the config file with --config or INSTANT_CONFIG")
 	}
 
-	return envVarViper, nil
+	return configFile, nil
 }
 
-func GetEnvVariableString(envViper *viper.Viper) []string {
-	var envVariables []string
-	allEnvVars := envViper.AllSettings()
-	for key, element := range allEnvVars {
-		envVariables = append(envVariables, fmt.Sprintf("%v=%v", strings.ToUpper(key), element))
-	}
+// FindFileUpward returns the first file with one of names in dir or its parents, up to the root of
+// the git repository or of the file system. If none is found, it returns the last directory
+// searched.
+func FindFileUpward(dir string, names []string) (string, string, bool) {
+	for {
+		for _, name := range names {
+			file := filepath.Join(dir, name)
+			if info, err := os.Stat(file); err == nil && !info.IsDir() {
+				return file, dir, true
+			}
+		}
 
-	return envVariables
+		parent := filepath.Dir(dir)
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil || parent == dir {
+			return "", dir, false
+		}
+		dir = parent
+	}
 }
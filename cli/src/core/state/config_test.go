@@ -0,0 +1,78 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindConfigFile(t *testing.T) {
+	type cases struct {
+		files              []string
+		dir                string
+		instantConfig      string
+		expectedConfigFile string
+		expectedErr        error
+	}
+
+	testCases := []cases{
+		// case: config file in the working directory
+		{
+			files:              []string{"project/config.yml"},
+			dir:                "project",
+			expectedConfigFile: "project/config.yml",
+		},
+		// case: config file in a parent directory, preferring config.yaml
+		{
+			files:              []string{"project/config.toml", "project/config.yaml", "project/packages/database/.keep"},
+			dir:                "project/packages/database",
+			expectedConfigFile: "project/config.yaml",
+		},
+		// case: the search stops at the root of the git repository
+		{
+			files:       []string{"config.json", "project/.git/HEAD", "project/packages/.keep"},
+			dir:         "project/packages",
+			expectedErr: ErrConfigNotFound,
+		},
+		// case: INSTANT_CONFIG takes precedence
+		{
+			files:              []string{"project/config.yaml", "shared/platform.json"},
+			dir:                "project",
+			instantConfig:      "../shared/platform.json",
+			expectedConfigFile: "shared/platform.json",
+		},
+	}
+
+	wd, err := os.Getwd()
+	jtest.RequireNil(t, err)
+	defer os.Chdir(wd)
+
+	for _, tc := range testCases {
+		root := t.TempDir()
+		for _, file := range tc.files {
+			filePath := filepath.Join(root, file)
+			jtest.RequireNil(t, os.MkdirAll(filepath.Dir(filePath), os.ModePerm))
+			jtest.RequireNil(t, os.WriteFile(filePath, nil, 0o644))
+		}
+		jtest.RequireNil(t, os.Chdir(filepath.Join(root, tc.dir)))
+		t.Setenv(ConfigFileEnvVar, tc.instantConfig)
+
+		configFile, err := FindConfigFile()
+		if tc.expectedErr != nil {
+			jtest.Require(t, tc.expectedErr, err)
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		configFile, err = filepath.Abs(configFile)
+		jtest.RequireNil(t, err)
+		expectedConfigFile, err := filepath.EvalSymlinks(filepath.Join(root, tc.expectedConfigFile))
+		jtest.RequireNil(t, err)
+		configFile, err = filepath.EvalSymlinks(configFile)
+		jtest.RequireNil(t, err)
+		require.Equal(t, expectedConfigFile, configFile)
+	}
+}
@@ -0,0 +1,215 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"cli/core/exitcode"
+
+	"github.com/luno/jettison/errors"
+)
+
+var ErrInvalidEnvFile = errors.New("invalid env file")
+
+// ReadEnvFiles reads the env vars of envFiles in KEY=value form. Later files override the env vars
+// of earlier ones, and env vars are kept in the order they first appear in.
+func ReadEnvFiles(envFiles []string) ([]string, error) {
+	envVars := newOrderedEnvVars()
+	for _, envFile := range envFiles {
+		data, err := os.ReadFile(envFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+
+		fileEnvVars, err := ParseEnvFile(envFile, data)
+		if err != nil {
+			return nil, err
+		}
+		for _, envVar := range fileEnvVars {
+			name, value, _ := strings.Cut(envVar, "=")
+			envVars.set(name, value)
+		}
+	}
+
+	return envVars.strings(), nil
+}
+
+// ParseEnvFile parses the dotenv content of the env file at path into env vars in KEY=value form,
+// in the order they appear in. Key case is preserved, and a repeated key keeps its first position
+// with its last value. Lines may:
+//   - be empty or comments starting with #
+//   - start with export
+//   - hold unquoted values, ending at the end of the line or at a # preceded by whitespace
+//   - hold 'single quoted' values, taken literally
+//   - hold "double quoted" values, with the escapes \n, \r, \t, \", \\ and \$
+//
+// Quoted values may span several lines.
+func ParseEnvFile(path string, data []byte) ([]string, error) {
+	p := &envFileParser{
+		path:  path,
+		lines: strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"),
+	}
+
+	envVars := newOrderedEnvVars()
+	for ; p.index < len(p.lines); p.index++ {
+		line := strings.TrimSpace(p.lines[p.index])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, err := p.parseEnvVar(line)
+		if err != nil {
+			return nil, err
+		}
+		envVars.set(name, value)
+	}
+
+	return envVars.strings(), nil
+}
+
+type envFileParser struct {
+	path  string
+	lines []string
+	// index is the index of the line being parsed
+	index int
+}
+
+func (p *envFileParser) errorf(line int, format string, args ...interface{}) error {
+	err := errors.Wrap(ErrInvalidEnvFile, fmt.Sprintf("%s:%d: %s", p.path, line+1, fmt.Sprintf(format, args...)))
+
+	return exitcode.New(exitcode.ConfigInvalid, err, "Fix the env file at the reported line, values with spaces or # may be quoted")
+}
+
+func (p *envFileParser) parseEnvVar(line string) (string, string, error) {
+	if rest, ok := strings.CutPrefix(line, "export"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+		line = strings.TrimSpace(rest)
+	}
+
+	name, value, ok := strings.Cut(line, "=")
+	name = strings.TrimSpace(name)
+	if !ok {
+		return "", "", p.errorf(p.index, "expected KEY=value, got '%s'", line)
+	}
+	if !isEnvVarName(name) {
+		return "", "", p.errorf(p.index, "invalid env var name '%s'", name)
+	}
+	value = strings.TrimLeft(value, " \t")
+
+	if value == "" || (value[0] != '\'' && value[0] != '"') {
+		return name, unquotedValue(value), nil
+	}
+
+	value, err := p.quotedValue(value)
+	if err != nil {
+		return "", "", err
+	}
+
+	return name, value, nil
+}
+
+// unquotedValue strips an inline comment and the surrounding whitespace from an unquoted value
+func unquotedValue(value string) string {
+	for i := 1; i < len(value); i++ {
+		if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+			value = value[:i]
+			break
+		}
+	}
+
+	return strings.TrimSpace(value)
+}
+
+// quotedValue reads a quoted value starting at value, continuing on the following lines until the
+// closing quote
+func (p *envFileParser) quotedValue(value string) (string, error) {
+	quote := value[0]
+	start := p.index
+	text := value[1:]
+
+	var b strings.Builder
+	for {
+		i := 0
+		for ; i < len(text); i++ {
+			c := text[i]
+			if c == quote {
+				break
+			}
+			if c == '\\' && quote == '"' && i+1 < len(text) {
+				i++
+				switch text[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 'r':
+					b.WriteByte('\r')
+				case 't':
+					b.WriteByte('\t')
+				case '"', '\\', '$':
+					b.WriteByte(text[i])
+				default:
+					b.WriteByte('\\')
+					b.WriteByte(text[i])
+				}
+				continue
+			}
+			b.WriteByte(c)
+		}
+
+		if i < len(text) {
+			rest := strings.TrimSpace(text[i+1:])
+			if rest != "" && !strings.HasPrefix(rest, "#") {
+				return "", p.errorf(p.index, "unexpected '%s' after quoted value", rest)
+			}
+			return b.String(), nil
+		}
+
+		p.index++
+		if p.index == len(p.lines) {
+			return "", p.errorf(start, "unterminated quoted value")
+		}
+		b.WriteByte('\n')
+		text = p.lines[p.index]
+	}
+}
+
+func isEnvVarName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, c := range name {
+		isLetter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		isOther := c == '.' || c == '-' || (c >= '0' && c <= '9')
+		if !isLetter && (i == 0 || !isOther) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// orderedEnvVars holds env vars in the order they are first set in
+type orderedEnvVars struct {
+	names  []string
+	values map[string]string
+}
+
+func newOrderedEnvVars() *orderedEnvVars {
+	return &orderedEnvVars{values: make(map[string]string)}
+}
+
+func (e *orderedEnvVars) set(name, value string) {
+	if _, ok := e.values[name]; !ok {
+		e.names = append(e.names, name)
+	}
+	e.values[name] = value
+}
+
+func (e *orderedEnvVars) strings() []string {
+	var envVars []string
+	for _, name := range e.names {
+		envVars = append(envVars, name+"="+e.values[name])
+	}
+
+	return envVars
+}
@@ -0,0 +1,111 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	type cases struct {
+		content             string
+		expectedEnvVars     []string
+		expectedErrorString string
+	}
+
+	testCases := []cases{
+		// case: key case and order are preserved, repeated keys keep their last value
+		{
+			content:         "Mixed_Case=1\nFIRST=one\nmixed_case=2\nFIRST=uno\n",
+			expectedEnvVars: []string{"Mixed_Case=1", "FIRST=uno", "mixed_case=2"},
+		},
+		// case: comments, export prefixes and unquoted values
+		{
+			content: `# Database
+export DB_HOST = postgres   # inline comment
+DB_URL=postgres://user@host/db?sslmode=disable&a=b
+PORT=05432
+ENABLED=TRUE
+EMPTY=
+HASH=abc#def
+`,
+			expectedEnvVars: []string{
+				"DB_HOST=postgres",
+				"DB_URL=postgres://user@host/db?sslmode=disable&a=b",
+				"PORT=05432",
+				"ENABLED=TRUE",
+				"EMPTY=",
+				"HASH=abc#def",
+			},
+		},
+		// case: single and double quoted values
+		{
+			content: `SINGLE='no $escapes\n # or comments' # a comment
+DOUBLE="say \"hi\"\t$HOME \$HOME \\ \q"
+EQUALS="a=b=c"
+`,
+			expectedEnvVars: []string{
+				"SINGLE=no $escapes\\n # or comments",
+				"DOUBLE=say \"hi\"\t$HOME $HOME \\ \\q",
+				"EQUALS=a=b=c",
+			},
+		},
+		// case: text after a quoted value
+		{
+			content:             "QUOTED='one' two\n",
+			expectedErrorString: "env:1: unexpected 'two' after quoted value: invalid env file",
+		},
+		// case: multiline quoted values and CRLF line endings
+		{
+			content: "CERT=\"-----BEGIN-----\r\nabc\r\n-----END-----\"\r\nKEY='line one\nline two'\nNEXT=1\n",
+			expectedEnvVars: []string{
+				"CERT=-----BEGIN-----\nabc\n-----END-----",
+				"KEY=line one\nline two",
+				"NEXT=1",
+			},
+		},
+		// case: unterminated quoted value
+		{
+			content:             "FIRST=one\nSECOND=\"two\nTHIRD=three\n",
+			expectedErrorString: "env:2: unterminated quoted value: invalid env file",
+		},
+		// case: line without =
+		{
+			content:             "FIRST=one\n\nSECOND\n",
+			expectedErrorString: "env:3: expected KEY=value, got 'SECOND': invalid env file",
+		},
+		// case: invalid name
+		{
+			content:             "1ST=one\n",
+			expectedErrorString: "env:1: invalid env var name '1ST': invalid env file",
+		},
+	}
+
+	for _, tc := range testCases {
+		envVars, err := ParseEnvFile("env", []byte(tc.content))
+		if tc.expectedErrorString != "" {
+			require.NotNil(t, err)
+			require.Equal(t, tc.expectedErrorString, err.Error())
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		require.Equal(t, tc.expectedEnvVars, envVars)
+	}
+}
+
+func TestReadEnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	jtest.RequireNil(t, os.WriteFile(filepath.Join(dir, ".env.one"), []byte("FIRST=one\nSECOND=two\n"), 0o644))
+	jtest.RequireNil(t, os.WriteFile(filepath.Join(dir, ".env.two"), []byte("THIRD=three\nFIRST=uno\n"), 0o644))
+
+	envVars, err := ReadEnvFiles([]string{filepath.Join(dir, ".env.one"), filepath.Join(dir, ".env.two")})
+	jtest.RequireNil(t, err)
+	require.Equal(t, []string{"FIRST=uno", "SECOND=two", "THIRD=three"}, envVars)
+
+	_, err = ReadEnvFiles([]string{filepath.Join(dir, ".env.none")})
+	require.NotNil(t, err)
+}
@@ -0,0 +1,220 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cli/core/exitcode"
+	"cli/util/docker"
+
+	"github.com/luno/jettison/errors"
+)
+
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+)
+
+// States of a package
+const (
+	StateRunning     = "running"
+	StateDegraded    = "degraded"
+	StateNotDeployed = "not deployed"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown status output format, use table or json")
+	ErrDegraded      = errors.New("packages are degraded or not deployed")
+)
+
+// DegradedHint tells the user how to look into ErrDegraded
+const DegradedHint = "Check the problems listed above, eg. with 'docker service ps --no-trunc'"
+
+// Package is the state of the stack of a package and its services
+type Package struct {
+	Id       string                `json:"id"`
+	State    string                `json:"state"`
+	Services []docker.ServiceState `json:"services"`
+}
+
+// Report writes the state of the stacks of the packages with ids to w in format, read from the
+// Docker daemon of the environment, and returns ErrDegraded if any package isn't running as it
+// should
+func Report(ctx context.Context, w io.Writer, ids []string, format string) error {
+	if format != FormatTable && format != FormatJSON {
+		return unknownFormat(format)
+	}
+
+	cli, err := docker.NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	packages, err := Packages(ctx, cli, ids)
+	if err != nil {
+		return err
+	}
+
+	err = Render(w, packages, format)
+	if err != nil {
+		return err
+	}
+
+	return Check(packages)
+}
+
+// Packages returns the state of the stacks of the packages with ids, in the same order
+func Packages(ctx context.Context, cli docker.StackAPIClient, ids []string) ([]Package, error) {
+	var packages []Package
+	for _, id := range ids {
+		services, err := docker.StackServices(ctx, cli, id)
+		if err != nil {
+			return nil, err
+		}
+
+		pack := Package{Id: id, State: StateRunning, Services: services}
+		if len(services) == 0 {
+			pack.State = StateNotDeployed
+			pack.Services = []docker.ServiceState{}
+		}
+		for _, service := range services {
+			if service.Degraded() {
+				pack.State = StateDegraded
+			}
+		}
+
+		packages = append(packages, pack)
+	}
+
+	return packages, nil
+}
+
+// Check returns ErrDegraded listing the packages that are not running as they should
+func Check(packages []Package) error {
+	var degraded []string
+	for _, pack := range packages {
+		if pack.State != StateRunning {
+			degraded = append(degraded, pack.Id+" ("+pack.State+")")
+		}
+	}
+
+	if len(degraded) > 0 {
+		return exitcode.New(exitcode.Degraded, errors.Wrap(ErrDegraded, strings.Join(degraded, ", ")), DegradedHint)
+	}
+
+	return nil
+}
+
+func unknownFormat(format string) error {
+	return exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrUnknownFormat, format), "")
+}
+
+// Render writes the state of packages in format
+func Render(w io.Writer, packages []Package, format string) error {
+	switch format {
+	case FormatTable:
+		return renderTable(w, packages)
+	case FormatJSON:
+		return renderJSON(w, packages)
+	}
+
+	return unknownFormat(format)
+}
+
+func renderTable(w io.Writer, packages []Package) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tSTATE\tSERVICE\tREPLICAS\tIMAGE\tDIGEST\tUPDATED")
+
+	var problems []string
+	for _, pack := range packages {
+		if len(pack.Services) == 0 {
+			fmt.Fprintf(tw, "%s\t%s\t-\t-\t-\t-\t-\n", pack.Id, pack.State)
+			continue
+		}
+
+		for _, service := range pack.Services {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", pack.Id, pack.State, service.Name, replicas(service), service.Image, shortDigest(service.Digest), updated(service))
+
+			for _, problem := range service.Problems {
+				line := fmt.Sprintf("  %s  %s", problem.Task, problem.State)
+				if problem.Error != "" {
+					line += ": " + problem.Error
+				}
+				problems = append(problems, line)
+			}
+		}
+	}
+
+	err := tw.Flush()
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	if len(problems) > 0 {
+		fmt.Fprintln(w, "\nProblems:")
+		for _, problem := range problems {
+			fmt.Fprintln(w, problem)
+		}
+	}
+
+	return nil
+}
+
+// replicas returns the running and desired tasks of service as docker service ls shows them
+func replicas(service docker.ServiceState) string {
+	if service.IsJob() {
+		return fmt.Sprintf("%d/%d (%d completed)", service.RunningTasks, service.DesiredTasks, service.CompletedTasks)
+	}
+
+	return fmt.Sprintf("%d/%d", service.RunningTasks, service.DesiredTasks)
+}
+
+// shortDigest abbreviates a digest to 12 hex characters, as Docker shows image ids
+func shortDigest(digest string) string {
+	if digest == "" {
+		return "-"
+	}
+
+	algorithm, hex, _ := strings.Cut(digest, ":")
+	if len(hex) > 12 {
+		hex = hex[:12]
+	}
+
+	return algorithm + ":" + hex
+}
+
+func updated(service docker.ServiceState) string {
+	if service.UpdatedAt.IsZero() {
+		return "-"
+	}
+
+	updatedAt := service.UpdatedAt.Local().Format(time.DateTime)
+	if service.UpdateState != "" {
+		updatedAt += " (" + service.UpdateState + ")"
+	}
+
+	return updatedAt
+}
+
+func renderJSON(w io.Writer, packages []Package) error {
+	if packages == nil {
+		packages = []Package{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(struct {
+		Packages []Package `json:"packages"`
+	}{packages})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	return nil
+}
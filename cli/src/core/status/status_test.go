@@ -0,0 +1,71 @@
+package status
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"cli/util/docker"
+	"cli/util/docker/dockertest"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func service(id, stack, name, image string, desired, running uint64, updatedAt time.Time) swarm.Service {
+	return swarm.Service{
+		ID:   id,
+		Meta: swarm.Meta{UpdatedAt: updatedAt},
+		Spec: swarm.ServiceSpec{
+			Annotations:  swarm.Annotations{Name: name, Labels: map[string]string{docker.StackNamespaceLabel: stack}},
+			TaskTemplate: swarm.TaskSpec{ContainerSpec: &swarm.ContainerSpec{Image: image}},
+			Mode:         swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &desired}},
+		},
+		ServiceStatus: &swarm.ServiceStatus{DesiredTasks: desired, RunningTasks: running},
+	}
+}
+
+func TestPackages(t *testing.T) {
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	time.Local = time.UTC
+	updatedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	server := dockertest.NewServer(t)
+	server.Services = []swarm.Service{
+		service("s1", "openhim-core", "openhim-core_openhim-core", "jembi/openhim-core:v8.4.0@sha256:4f2a9c1b7e3d5a6f8e9d0c1b2a3f", 1, 1, updatedAt),
+		service("s2", "mongo", "mongo_mongo-1", "mongo:4.2", 1, 0, updatedAt),
+	}
+	server.Tasks = []swarm.Task{
+		{ID: "t1", ServiceID: "s1", Slot: 1, DesiredState: swarm.TaskStateRunning, Status: swarm.TaskStatus{State: swarm.TaskStateRunning}},
+		{ID: "t2", ServiceID: "s2", Slot: 1, DesiredState: swarm.TaskStateRunning, Status: swarm.TaskStatus{State: swarm.TaskStateFailed, Err: "task: non-zero exit (14)"}},
+	}
+
+	packages, err := Packages(context.Background(), server.Client(t), []string{"mongo", "openhim-core", "hapi-fhir"})
+	jtest.RequireNil(t, err)
+
+	var states []string
+	for _, pack := range packages {
+		states = append(states, pack.State)
+	}
+	require.Equal(t, []string{StateDegraded, StateRunning, StateNotDeployed}, states)
+
+	var b bytes.Buffer
+	jtest.RequireNil(t, Render(&b, packages, FormatTable))
+	require.Equal(t, `PACKAGE       STATE         SERVICE                    REPLICAS  IMAGE                      DIGEST               UPDATED
+mongo         degraded      mongo_mongo-1              0/1       mongo:4.2                  -                    2026-10-01 12:00:00
+openhim-core  running       openhim-core_openhim-core  1/1       jembi/openhim-core:v8.4.0  sha256:4f2a9c1b7e3d  2026-10-01 12:00:00
+hapi-fhir     not deployed  -                          -         -                          -                    -
+
+Problems:
+  mongo_mongo-1.1  failed: task: non-zero exit (14)
+`, b.String())
+
+	err = Check(packages)
+	jtest.Require(t, ErrDegraded, err)
+	require.Equal(t, "mongo (degraded), hapi-fhir (not deployed): "+ErrDegraded.Error(), err.Error())
+
+	jtest.RequireNil(t, Check(packages[1:2]))
+	jtest.Require(t, ErrUnknownFormat, Render(&b, packages, "yaml"))
+}
@@ -2,6 +2,7 @@ package core
 
 type Profile struct {
 	Name     string   `yaml:"name"`
+	Extends  []string `yaml:"extends,omitempty"`
 	Packages []string `yaml:"packages"`
 	EnvVars  []string `yaml:"envVars,omitempty"`
 	EnvFiles []string `yaml:"envFiles,omitempty"`
@@ -9,12 +10,25 @@ type Profile struct {
 	Only     bool     `yaml:"only,omitempty"`
 }
 
+type CustomPackageAuth struct {
+	SSHKey           string `yaml:"sshKey,omitempty"`
+	SSHPassphraseEnv string `yaml:"sshPassphraseEnv,omitempty"`
+	Username         string `yaml:"username,omitempty"`
+	TokenEnv         string `yaml:"tokenEnv,omitempty"`
+}
+
 type CustomPackage struct {
-	Id   string `yaml:"id"`
-	Path string `yaml:"path"`
+	Id        string             `yaml:"id"`
+	Path      string             `yaml:"path"`
+	Ref       string             `yaml:"ref,omitempty"`
+	Subdir    string             `yaml:"subdir,omitempty"`
+	Auth      *CustomPackageAuth `yaml:"auth,omitempty"`
+	Sha256    string             `yaml:"sha256,omitempty"`
+	Integrity string             `yaml:"integrity,omitempty"`
 }
 
 type Config struct {
+	Extends        []string        `yaml:"extends,omitempty"`
 	ProjectName    string          `yaml:"projectName,omitempty"`
 	Image          string          `yaml:"image,omitempty"`
 	LogPath        string          `yaml:"logPath,omitempty"`
@@ -33,6 +47,12 @@ type PackageSpec struct {
 	ImageVersion         string
 	TargetLauncher       string
 	Concurrency          string
+	StrictEnv            bool
+	// Resolve reads the package metadata of the config image and custom packages to print the
+	// deployment order of a plan
+	Resolve bool
+	// DryRun prints the deployment plan instead of launching the deployment container
+	DryRun bool
 }
 
 type GeneratePackageSpec struct {
@@ -0,0 +1,186 @@
+package workspace
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cli/core/exitcode"
+	"cli/core/state"
+
+	"github.com/luno/jettison/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnvVar is the env var selecting the workspace file when --workspace isn't given
+const FileEnvVar = "INSTANT_WORKSPACE"
+
+// FileNames are the names of the workspace files looked for, in order of preference
+var FileNames = []string{"workspace.yaml", "workspace.yml"}
+
+var (
+	ErrWorkspaceNotFound = errors.New("no workspace file found")
+	ErrInvalidWorkspace  = errors.New("invalid workspace file")
+	ErrUnknownProject    = errors.New("no such project in the workspace")
+	ErrProjectsFailed    = errors.New("workspace projects failed")
+)
+
+// Project is a member project of a workspace
+type Project struct {
+	Name string `yaml:"name"`
+	// Path is the directory of the project or its config file, relative to the workspace file
+	Path string `yaml:"path"`
+	// Config are the config files of the project, merged in order, relative to Path
+	Config []string `yaml:"config,omitempty"`
+}
+
+// Workspace is the content of a workspace file, listing projects that are operated on together
+type Workspace struct {
+	Name     string    `yaml:"name"`
+	Projects []Project `yaml:"projects"`
+	// ContinueOnError runs the command for the remaining projects when it fails for one
+	ContinueOnError bool `yaml:"continueOnError"`
+
+	// File is the path of the workspace file
+	File string `yaml:"-"`
+}
+
+// Find returns the workspace file to use when --workspace isn't given: the file named by the
+// INSTANT_WORKSPACE env var, or else the first of FileNames found in the working directory or its
+// parents, up to the root of the git repository or of the file system
+func Find() (string, error) {
+	if file := os.Getenv(FileEnvVar); file != "" {
+		return file, nil
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+
+	file, searched, found := state.FindFileUpward(wd, FileNames)
+	if !found {
+		return "", exitcode.New(exitcode.ConfigInvalid, errors.Wrap(ErrWorkspaceNotFound, "searched "+wd+" up to "+searched), "Run the command from the workspace directory or one below it, or pass the workspace file with --workspace or INSTANT_WORKSPACE")
+	}
+
+	return file, nil
+}
+
+func invalidWorkspace(detail string) error {
+	return exitcode.New(exitcode.ConfigInvalid, errors.Wrap(ErrInvalidWorkspace, detail), "Fix the workspace file, every project needs a unique name and the path of its directory or config file")
+}
+
+// Load reads and checks the workspace file at path
+func Load(path string) (*Workspace, error) {
+	absFilePath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	data, err := os.ReadFile(absFilePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	var workspace Workspace
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err = decoder.Decode(&workspace)
+	if err != nil {
+		return nil, invalidWorkspace(path + ": " + strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+	workspace.File = absFilePath
+
+	if len(workspace.Projects) == 0 {
+		return nil, invalidWorkspace(path + ": no projects")
+	}
+	seen := make(map[string]bool)
+	for i, project := range workspace.Projects {
+		switch {
+		case project.Name == "":
+			return nil, invalidWorkspace(fmt.Sprintf("%s: projects[%d] has no name", path, i))
+		case project.Path == "":
+			return nil, invalidWorkspace(fmt.Sprintf("%s: project %s has no path", path, project.Name))
+		case seen[project.Name]:
+			return nil, invalidWorkspace(fmt.Sprintf("%s: duplicate project name %s", path, project.Name))
+		}
+		seen[project.Name] = true
+	}
+
+	return &workspace, nil
+}
+
+// Select returns the projects with names in the order they are declared in, or every project if
+// names is empty
+func (w *Workspace) Select(names []string) ([]Project, error) {
+	if len(names) == 0 {
+		return w.Projects, nil
+	}
+
+	selected := make(map[string]bool)
+	for _, name := range names {
+		found := false
+		for _, project := range w.Projects {
+			if project.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrUnknownProject, name), "Check the names passed with --project against the projects of the workspace file")
+		}
+		selected[name] = true
+	}
+
+	var projects []Project
+	for _, project := range w.Projects {
+		if selected[project.Name] {
+			projects = append(projects, project)
+		}
+	}
+
+	return projects, nil
+}
+
+// ConfigFiles returns the absolute paths of the config files of project. A project without config
+// files uses the first of state.ConfigFileNames in its directory, or its path if that is a file.
+func (w *Workspace) ConfigFiles(project Project) ([]string, error) {
+	projectPath := project.Path
+	if !filepath.IsAbs(projectPath) {
+		projectPath = filepath.Join(filepath.Dir(w.File), projectPath)
+	}
+
+	info, err := os.Stat(projectPath)
+	if err != nil {
+		return nil, invalidWorkspace("project " + project.Name + ": " + err.Error())
+	}
+
+	if !info.IsDir() {
+		if len(project.Config) > 0 {
+			return nil, invalidWorkspace("project " + project.Name + ": config is only supported for project directories")
+		}
+		return []string{projectPath}, nil
+	}
+
+	if len(project.Config) == 0 {
+		for _, name := range state.ConfigFileNames {
+			configFile := filepath.Join(projectPath, name)
+			if _, err := os.Stat(configFile); err == nil {
+				return []string{configFile}, nil
+			}
+		}
+		return nil, exitcode.New(exitcode.ConfigInvalid, errors.Wrap(state.ErrConfigNotFound, "project "+project.Name+": "+projectPath), "Add a config file to the project directory, or list its config files in the workspace file")
+	}
+
+	var configFiles []string
+	for _, configFile := range project.Config {
+		if !filepath.IsAbs(configFile) {
+			configFile = filepath.Join(projectPath, configFile)
+		}
+		configFiles = append(configFiles, configFile)
+	}
+
+	return configFiles, nil
+}
@@ -0,0 +1,193 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cli/core/exitcode"
+	"cli/core/state"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		filePath := filepath.Join(dir, name)
+		jtest.RequireNil(t, os.MkdirAll(filepath.Dir(filePath), os.ModePerm))
+		jtest.RequireNil(t, os.WriteFile(filePath, []byte(content), 0o644))
+	}
+}
+
+func TestLoad(t *testing.T) {
+	type cases struct {
+		workspace         string
+		expectedWorkspace Workspace
+		expectedErr       error
+	}
+
+	testCases := []cases{
+		// case: projects in declared order
+		{
+			workspace: `name: moh
+continueOnError: true
+projects:
+  - name: hie
+    path: national-hie
+  - name: lab
+    path: lab-network
+    config: [config.yml, prod.yml]
+`,
+			expectedWorkspace: Workspace{
+				Name: "moh",
+				Projects: []Project{
+					{Name: "hie", Path: "national-hie"},
+					{Name: "lab", Path: "lab-network", Config: []string{"config.yml", "prod.yml"}},
+				},
+				ContinueOnError: true,
+			},
+		},
+		// case: unknown key
+		{
+			workspace:   "projects:\n  - name: hie\n    dir: national-hie\n",
+			expectedErr: ErrInvalidWorkspace,
+		},
+		// case: no projects
+		{
+			workspace:   "name: moh\n",
+			expectedErr: ErrInvalidWorkspace,
+		},
+		// case: project without a path
+		{
+			workspace:   "projects:\n  - name: hie\n",
+			expectedErr: ErrInvalidWorkspace,
+		},
+		// case: duplicate project name
+		{
+			workspace:   "projects:\n  - name: hie\n    path: a\n  - name: hie\n    path: b\n",
+			expectedErr: ErrInvalidWorkspace,
+		},
+	}
+
+	for _, tc := range testCases {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{"workspace.yaml": tc.workspace})
+
+		workspace, err := Load(filepath.Join(dir, "workspace.yaml"))
+		if tc.expectedErr != nil {
+			jtest.Require(t, tc.expectedErr, err)
+			require.Equal(t, exitcode.ConfigInvalid, exitcode.Classify(err).Code)
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		tc.expectedWorkspace.File = filepath.Join(dir, "workspace.yaml")
+		require.Equal(t, tc.expectedWorkspace, *workspace)
+	}
+}
+
+func TestWorkspace_Select(t *testing.T) {
+	workspace := &Workspace{
+		Projects: []Project{{Name: "hie"}, {Name: "lab"}, {Name: "training"}},
+	}
+
+	type cases struct {
+		names            []string
+		expectedProjects []string
+		expectedErr      error
+	}
+
+	testCases := []cases{
+		// case: every project by default
+		{
+			expectedProjects: []string{"hie", "lab", "training"},
+		},
+		// case: selected projects keep the declared order
+		{
+			names:            []string{"training", "hie"},
+			expectedProjects: []string{"hie", "training"},
+		},
+		// case: unknown project
+		{
+			names:       []string{"sandbox"},
+			expectedErr: ErrUnknownProject,
+		},
+	}
+
+	for _, tc := range testCases {
+		projects, err := workspace.Select(tc.names)
+		if tc.expectedErr != nil {
+			jtest.Require(t, tc.expectedErr, err)
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		var names []string
+		for _, project := range projects {
+			names = append(names, project.Name)
+		}
+		require.Equal(t, tc.expectedProjects, names)
+	}
+}
+
+func TestWorkspace_ConfigFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"hie/config.yml":       "",
+		"hie/config.toml":      "",
+		"lab/platform.yml":     "",
+		"training/config.json": "",
+		"sandbox/.keep":        "",
+	})
+	workspace := &Workspace{File: filepath.Join(dir, "workspace.yaml")}
+
+	type cases struct {
+		project             Project
+		expectedConfigFiles []string
+		expectedErr         error
+	}
+
+	testCases := []cases{
+		// case: first config file found in the project directory
+		{
+			project:             Project{Name: "hie", Path: "hie"},
+			expectedConfigFiles: []string{"hie/config.yml"},
+		},
+		// case: path to the config file
+		{
+			project:             Project{Name: "lab", Path: "lab/platform.yml"},
+			expectedConfigFiles: []string{"lab/platform.yml"},
+		},
+		// case: config files relative to the project directory
+		{
+			project:             Project{Name: "training", Path: "training", Config: []string{"config.json", "local.yml"}},
+			expectedConfigFiles: []string{"training/config.json", "training/local.yml"},
+		},
+		// case: no config file in the project directory
+		{
+			project:     Project{Name: "sandbox", Path: "sandbox"},
+			expectedErr: state.ErrConfigNotFound,
+		},
+		// case: missing project directory
+		{
+			project:     Project{Name: "missing", Path: "missing"},
+			expectedErr: ErrInvalidWorkspace,
+		},
+	}
+
+	for _, tc := range testCases {
+		configFiles, err := workspace.ConfigFiles(tc.project)
+		if tc.expectedErr != nil {
+			jtest.Require(t, tc.expectedErr, err)
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		var expectedConfigFiles []string
+		for _, configFile := range tc.expectedConfigFiles {
+			expectedConfigFiles = append(expectedConfigFiles, filepath.Join(dir, configFile))
+		}
+		require.Equal(t, expectedConfigFiles, configFiles)
+	}
+}
@@ -5,31 +5,36 @@ import (
 	"os"
 
 	"cli/cmd"
+	"cli/core/deploy"
 	"cli/util/docker"
 
 	"github.com/luno/jettison/log"
 )
 
 func main() {
-	defer handleExit()
+	code := cmd.Execute()
 
-	cmd.Execute()
+	removeDeploymentContainer()
+
+	os.Exit(code)
 }
 
-func handleExit() {
+// removeDeploymentContainer removes the deployment container and volume. Only commands that
+// launched the deployment container are cleaned up after, so that commands like --dry-run never
+// touch Docker.
+func removeDeploymentContainer() {
+	if !deploy.DeploymentContainerCreated() {
+		return
+	}
+
 	ctx := context.Background()
 
 	cli, err := docker.NewDockerClient()
 	if err != nil {
 		log.Error(ctx, err)
+		return
 	}
 
 	docker.RemoveStaleInstantContainer(cli, ctx)
 	docker.RemoveStaleInstantVolume(cli, ctx)
-
-	if recover() != nil {
-		os.Exit(1)
-	}
-
-	os.Exit(0)
 }
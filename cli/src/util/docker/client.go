@@ -24,17 +24,20 @@ func NewDockerClient() (*client.Client, error) {
 			return nil, errors.Wrap(err, "")
 		}
 
-		httpClient := &http.Client{
-			Transport: &http.Transport{
-				DialContext: helper.Dialer,
-			},
+		// Only ssh:// hosts need a helper, the client connects to the others itself
+		if helper != nil {
+			httpClient := &http.Client{
+				Transport: &http.Transport{
+					DialContext: helper.Dialer,
+				},
+			}
+
+			clientOpts = append(clientOpts,
+				client.WithHTTPClient(httpClient),
+				client.WithHost(helper.Host),
+				client.WithDialContext(helper.Dialer),
+			)
 		}
-
-		clientOpts = append(clientOpts,
-			client.WithHTTPClient(httpClient),
-			client.WithHost(helper.Host),
-			client.WithDialContext(helper.Dialer),
-		)
 	}
 
 	cli, err := client.NewClientWithOpts(clientOpts...)
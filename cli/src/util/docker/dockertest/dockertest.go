@@ -0,0 +1,423 @@
+// Package dockertest provides a fake Docker Engine API for tests, serving the swarm services, tasks
+// and containers it is given
+package dockertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/luno/jettison/jtest"
+)
+
+// APIVersion is the Docker API version the fake server reports
+const APIVersion = "1.47"
+
+var versionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+
+// Container is a container of the fake server, with the health reported when it is inspected
+type Container struct {
+	container.Summary
+	Health *container.Health
+}
+
+// LogLine is a log line of a task of a service
+type LogLine struct {
+	ServiceID string
+	TaskID    string
+	Time      time.Time
+	// Stderr writes the line to stderr instead of stdout
+	Stderr  bool
+	Message string
+}
+
+// ExecFunc runs a command in a container of the fake server, returning its exit code. stdin is
+// empty unless the exec attaches stdin.
+type ExecFunc func(containerID string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) int
+
+// UpdateFunc plays the swarm orchestrator for an update of a service of the fake server, eg. setting
+// its update status. rollback is set for rollbacks to the previous spec of the service.
+type UpdateFunc func(service *swarm.Service, rollback bool)
+
+// Server is a fake Docker Engine API, serving the objects in its fields
+type Server struct {
+	*httptest.Server
+
+	// NodeID is the id of the swarm node of the daemon
+	NodeID     string
+	Services   []swarm.Service
+	Tasks      []swarm.Task
+	Containers []Container
+	Logs       []LogLine
+	Exec       ExecFunc
+	Update     UpdateFunc
+
+	// mu guards execs, which are run and inspected by concurrent requests, and Services once they
+	// are updated
+	mu    sync.Mutex
+	execs map[string]*execInstance
+}
+
+type execInstance struct {
+	containerID string
+	options     container.ExecOptions
+	exitCode    int
+	running     bool
+}
+
+// NewServer starts a fake Docker Engine API, closed when the test ends
+func NewServer(t testing.TB) *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Client returns a Docker client of the fake server
+func (s *Server) Client(t testing.TB) *client.Client {
+	cli, err := client.NewClientWithOpts(
+		client.WithHost("tcp://"+strings.TrimPrefix(s.URL, "http://")),
+		client.WithHTTPClient(s.Server.Client()),
+		client.WithVersion(APIVersion),
+	)
+	jtest.RequireNil(t, err)
+	t.Cleanup(func() { cli.Close() })
+
+	return cli
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Api-Version", APIVersion)
+	path := versionPrefix.ReplaceAllString(r.URL.Path, "")
+
+	args, err := filters.FromJSON(r.URL.Query().Get("filters"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case path == "/_ping":
+		w.Write([]byte("OK"))
+
+	case r.Method == http.MethodGet && path == "/info":
+		writeJSON(w, system.Info{Swarm: swarm.Info{NodeID: s.NodeID, LocalNodeState: swarm.LocalNodeStateActive}})
+
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/containers/") && strings.HasSuffix(path, "/exec"):
+		s.createExec(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/containers/"), "/exec"))
+
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/exec/") && strings.HasSuffix(path, "/start"):
+		s.startExec(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/exec/"), "/start"))
+
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/exec/") && strings.HasSuffix(path, "/resize"):
+		w.WriteHeader(http.StatusOK)
+
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/exec/") && strings.HasSuffix(path, "/json"):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/exec/"), "/json")
+		s.mu.Lock()
+		exec, ok := s.execs[id]
+		var inspect container.ExecInspect
+		if ok {
+			inspect = container.ExecInspect{ExecID: id, ContainerID: exec.containerID, Running: exec.running, ExitCode: exec.exitCode}
+		}
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, "No such exec instance: "+id)
+			return
+		}
+		writeJSON(w, inspect)
+
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/services/") && strings.HasSuffix(path, "/update"):
+		s.updateService(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/services/"), "/update"))
+
+	case r.Method == http.MethodGet && path == "/services":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		services := []swarm.Service{}
+		for _, service := range s.Services {
+			if args.MatchKVList("label", service.Spec.Labels) && matchName(args, "name", service.ID, service.Spec.Name) {
+				if r.URL.Query().Get("status") != "true" {
+					service.ServiceStatus = nil
+				}
+				services = append(services, service)
+			}
+		}
+		writeJSON(w, services)
+
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/services/") && !strings.Contains(strings.TrimPrefix(path, "/services/"), "/"):
+		id := strings.TrimPrefix(path, "/services/")
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for _, service := range s.Services {
+			if service.ID == id || service.Spec.Name == id {
+				writeJSON(w, service)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "service "+id+" not found")
+
+	case r.Method == http.MethodGet && path == "/tasks":
+		tasks := []swarm.Task{}
+		for _, task := range s.Tasks {
+			if matchName(args, "service", task.ServiceID, s.serviceName(task.ServiceID)) &&
+				(!args.Contains("desired-state") || args.ExactMatch("desired-state", string(task.DesiredState))) &&
+				(!args.Contains("node") || args.ExactMatch("node", task.NodeID)) {
+				tasks = append(tasks, task)
+			}
+		}
+		writeJSON(w, tasks)
+
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/tasks/"):
+		id := strings.TrimPrefix(path, "/tasks/")
+		for _, task := range s.Tasks {
+			if task.ID == id {
+				writeJSON(w, task)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "task "+id+" not found")
+
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/services/") && strings.HasSuffix(path, "/logs"):
+		s.serveServiceLogs(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/services/"), "/logs"))
+
+	case r.Method == http.MethodGet && path == "/containers/json":
+		containers := []container.Summary{}
+		for _, c := range s.Containers {
+			health := string(container.NoHealthcheck)
+			if c.Health != nil {
+				health = string(c.Health.Status)
+			}
+			if args.MatchKVList("label", c.Labels) && (!args.Contains("health") || args.ExactMatch("health", health)) {
+				containers = append(containers, c.Summary)
+			}
+		}
+		writeJSON(w, containers)
+
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/containers/") && strings.HasSuffix(path, "/json"):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/containers/"), "/json")
+		for _, c := range s.Containers {
+			if c.ID == id {
+				writeJSON(w, container.InspectResponse{
+					ContainerJSONBase: &container.ContainerJSONBase{
+						ID:    c.ID,
+						State: &container.State{Status: c.State, Running: c.State == "running", Health: c.Health},
+					},
+					Config: &container.Config{Labels: c.Labels},
+				})
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "No such container: "+id)
+
+	default:
+		writeError(w, http.StatusNotFound, "page not found")
+	}
+}
+
+// updateService replaces the spec of a service, or restores its previous spec for rollbacks, and
+// runs s.Update
+func (s *Server) updateService(w http.ResponseWriter, r *http.Request, id string) {
+	var spec swarm.ServiceSpec
+	err := json.NewDecoder(r.Body).Decode(&spec)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.Services {
+		service := &s.Services[i]
+		if service.ID != id && service.Spec.Name != id {
+			continue
+		}
+
+		if r.URL.Query().Get("version") != fmt.Sprint(service.Version.Index) {
+			writeError(w, http.StatusBadRequest, "update out of sequence")
+			return
+		}
+
+		rollback := r.URL.Query().Get("rollback") == "previous"
+		if rollback {
+			if service.PreviousSpec == nil {
+				writeError(w, http.StatusBadRequest, "service "+id+" has no previous spec")
+				return
+			}
+			spec = *service.PreviousSpec
+		}
+
+		previous := service.Spec
+		service.PreviousSpec = &previous
+		service.Spec = spec
+		service.Version.Index++
+		if s.Update != nil {
+			s.Update(service, rollback)
+		}
+
+		writeJSON(w, swarm.ServiceUpdateResponse{})
+		return
+	}
+
+	writeError(w, http.StatusNotFound, "service "+id+" not found")
+}
+
+func (s *Server) createExec(w http.ResponseWriter, r *http.Request, containerID string) {
+	found := false
+	for _, c := range s.Containers {
+		found = found || c.ID == containerID
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "No such container: "+containerID)
+		return
+	}
+
+	var options container.ExecOptions
+	err := json.NewDecoder(r.Body).Decode(&options)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	if s.execs == nil {
+		s.execs = make(map[string]*execInstance)
+	}
+	id := fmt.Sprintf("exec-%d", len(s.execs)+1)
+	s.execs[id] = &execInstance{containerID: containerID, options: options, running: true}
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, container.ExecCreateResponse{ID: id})
+}
+
+// startExec hijacks the connection as the Docker API does, and runs the exec with s.Exec
+func (s *Server) startExec(w http.ResponseWriter, r *http.Request, id string) {
+	s.mu.Lock()
+	exec, ok := s.execs[id]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such exec instance: "+id)
+		return
+	}
+
+	// The start options are read before hijacking so that only stdin is left on the connection
+	var options container.ExecStartOptions
+	if err := json.NewDecoder(r.Body).Decode(&options); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	mediaType := "application/vnd.docker.multiplexed-stream"
+	if exec.options.Tty {
+		mediaType = "application/vnd.docker.raw-stream"
+	}
+	fmt.Fprintf(buf, "HTTP/1.1 101 UPGRADED\r\nContent-Type: %s\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n", mediaType)
+	buf.Flush()
+
+	var stdin io.Reader = strings.NewReader("")
+	if exec.options.AttachStdin {
+		stdin = buf
+	}
+	var stdout, stderr io.Writer = conn, conn
+	if !exec.options.Tty {
+		stdout = stdcopy.NewStdWriter(conn, stdcopy.Stdout)
+		stderr = stdcopy.NewStdWriter(conn, stdcopy.Stderr)
+	}
+
+	exitCode := 0
+	if s.Exec != nil {
+		exitCode = s.Exec(exec.containerID, exec.options.Cmd, stdin, stdout, stderr)
+	}
+
+	// The exec is done before the connection is closed, as the client inspects it once its output
+	// ends
+	s.mu.Lock()
+	exec.exitCode, exec.running = exitCode, false
+	s.mu.Unlock()
+	conn.Close()
+}
+
+// serveServiceLogs writes the log lines of a service multiplexed as the Docker API does, with the
+// timestamps and details of lines if they are requested
+func (s *Server) serveServiceLogs(w http.ResponseWriter, r *http.Request, id string) {
+	if s.serviceName(id) == "" {
+		writeError(w, http.StatusNotFound, "service "+id+" not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.docker.multiplexed-stream")
+	stdout := stdcopy.NewStdWriter(w, stdcopy.Stdout)
+	stderr := stdcopy.NewStdWriter(w, stdcopy.Stderr)
+	query := r.URL.Query()
+
+	for _, line := range s.Logs {
+		if line.ServiceID != id {
+			continue
+		}
+
+		message := line.Message
+		if query.Get("details") == "1" {
+			message = "com.docker.swarm.node.id=node1,com.docker.swarm.service.id=" + line.ServiceID + ",com.docker.swarm.task.id=" + line.TaskID + " " + message
+		}
+		if query.Get("timestamps") == "1" {
+			message = line.Time.Format(time.RFC3339Nano) + " " + message
+		}
+
+		if line.Stderr {
+			stderr.Write([]byte(message + "\n"))
+		} else {
+			stdout.Write([]byte(message + "\n"))
+		}
+	}
+}
+
+func (s *Server) serviceName(id string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, service := range s.Services {
+		if service.ID == id {
+			return service.Spec.Name
+		}
+	}
+
+	return ""
+}
+
+// matchName reports whether the id or name of an object matches the values of the filter field,
+// if it has any
+func matchName(args filters.Args, field, id, name string) bool {
+	return !args.Contains(field) || args.ExactMatch(field, id) || args.ExactMatch(field, name)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"message": message})
+}
@@ -0,0 +1,207 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+
+	"cli/core/exitcode"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/api/types/system"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/luno/jettison/errors"
+	"github.com/moby/term"
+)
+
+var (
+	ErrServiceNotFound  = errors.New("no such service in the package")
+	ErrAmbiguousService = errors.New("the package has several services, select one")
+	ErrNoRunningTask    = errors.New("no running task of the service on the node of the Docker daemon")
+)
+
+const serviceHint = "Pass one of the listed services with --service"
+
+// ExecAPIClient is the part of the Docker API commands are run in the task containers of services
+// with
+type ExecAPIClient interface {
+	Info(ctx context.Context) (system.Info, error)
+	ServiceList(ctx context.Context, options swarm.ServiceListOptions) ([]swarm.Service, error)
+	TaskList(ctx context.Context, options swarm.TaskListOptions) ([]swarm.Task, error)
+	ContainerExecCreate(ctx context.Context, container string, options container.ExecOptions) (container.ExecCreateResponse, error)
+	ContainerExecAttach(ctx context.Context, execID string, options container.ExecAttachOptions) (types.HijackedResponse, error)
+	ContainerExecInspect(ctx context.Context, execID string) (container.ExecInspect, error)
+	ContainerExecResize(ctx context.Context, execID string, options container.ResizeOptions) error
+}
+
+// ServiceNames returns the names of the services of stack without the stack prefix, eg. postgres-1
+// for the service database-postgres_postgres-1 of the stack database-postgres
+func ServiceNames(services []swarm.Service, stack string) []string {
+	var names []string
+	for _, service := range services {
+		names = append(names, strings.TrimPrefix(service.Spec.Name, stack+"_"))
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// FindService returns the service of stack named name, with or without the stack prefix, or the
+// only service of stack if name is empty
+func FindService(ctx context.Context, cli serviceLister, stack, name string) (swarm.Service, error) {
+	services, err := ListStackServices(ctx, cli, stack, false)
+	if err != nil {
+		return swarm.Service{}, err
+	}
+	if len(services) == 0 {
+		return swarm.Service{}, exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrStackNotFound, stack), StackNotFoundHint)
+	}
+
+	if name == "" {
+		if len(services) > 1 {
+			err := errors.Wrap(ErrAmbiguousService, stack+" has "+strings.Join(ServiceNames(services, stack), ", "))
+			return swarm.Service{}, exitcode.New(exitcode.ValidationFailed, err, serviceHint)
+		}
+		return services[0], nil
+	}
+
+	for _, service := range services {
+		if service.Spec.Name == name || service.Spec.Name == stack+"_"+name {
+			return service, nil
+		}
+	}
+
+	err = errors.Wrap(ErrServiceNotFound, name+" (services of "+stack+": "+strings.Join(ServiceNames(services, stack), ", ")+")")
+	return swarm.Service{}, exitcode.New(exitcode.ValidationFailed, err, serviceHint)
+}
+
+// LocalTaskContainer returns the id of the container of a running task of service on the node of
+// the Docker daemon
+func LocalTaskContainer(ctx context.Context, cli ExecAPIClient, service swarm.Service) (string, error) {
+	info, err := cli.Info(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+
+	tasks, err := cli.TaskList(ctx, swarm.TaskListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("service", service.ID),
+			filters.Arg("desired-state", string(swarm.TaskStateRunning)),
+			filters.Arg("node", info.Swarm.NodeID),
+		),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Slot < tasks[j].Slot })
+	for _, task := range tasks {
+		if task.Status.State == swarm.TaskStateRunning && task.Status.ContainerStatus != nil && task.Status.ContainerStatus.ContainerID != "" {
+			return task.Status.ContainerStatus.ContainerID, nil
+		}
+	}
+
+	return "", exitcode.New(exitcode.ValidationFailed, errors.Wrap(ErrNoRunningTask, service.Spec.Name), "Point DOCKER_HOST at the node running the task, eg. ssh://user@node, as listed by 'docker service ps'")
+}
+
+// ExecOptions are the command run by Exec and the streams it is attached to
+type ExecOptions struct {
+	Cmd  []string
+	User string
+	// Interactive attaches Stdin to the command
+	Interactive bool
+	// TTY runs the command in a pseudo-terminal, putting Stdin in raw mode if it is a terminal
+	TTY    bool
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Exec runs a command in a container and returns its exit code
+func Exec(ctx context.Context, cli ExecAPIClient, containerID string, opts ExecOptions) (int, error) {
+	execOptions := container.ExecOptions{
+		Cmd:          opts.Cmd,
+		User:         opts.User,
+		Tty:          opts.TTY,
+		AttachStdin:  opts.Interactive,
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+
+	outFd, outIsTerminal := term.GetFdInfo(opts.Stdout)
+	if opts.TTY && outIsTerminal {
+		if size, err := term.GetWinsize(outFd); err == nil {
+			execOptions.ConsoleSize = &[2]uint{uint(size.Height), uint(size.Width)}
+		}
+	}
+
+	exec, err := cli.ContainerExecCreate(ctx, containerID, execOptions)
+	if err != nil {
+		return 0, errors.Wrap(err, "")
+	}
+
+	attach, err := cli.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{Tty: opts.TTY, ConsoleSize: execOptions.ConsoleSize})
+	if err != nil {
+		return 0, errors.Wrap(err, "")
+	}
+	defer attach.Close()
+
+	if opts.TTY && opts.Interactive {
+		if inFd, isTerminal := term.GetFdInfo(opts.Stdin); isTerminal {
+			state, err := term.SetRawTerminal(inFd)
+			if err != nil {
+				return 0, errors.Wrap(err, "")
+			}
+			defer term.RestoreTerminal(inFd, state)
+		}
+	}
+
+	if opts.TTY && outIsTerminal {
+		resize := func() {
+			size, err := term.GetWinsize(outFd)
+			if err == nil {
+				cli.ContainerExecResize(ctx, exec.ID, container.ResizeOptions{Height: uint(size.Height), Width: uint(size.Width)})
+			}
+		}
+		stop := monitorTerminalSize(resize)
+		defer stop()
+	}
+
+	if opts.Interactive {
+		go func() {
+			io.Copy(attach.Conn, opts.Stdin)
+			attach.CloseWrite()
+		}()
+	}
+
+	outputDone := make(chan error, 1)
+	go func() {
+		var err error
+		if opts.TTY {
+			_, err = io.Copy(opts.Stdout, attach.Reader)
+		} else {
+			_, err = stdcopy.StdCopy(opts.Stdout, opts.Stderr, attach.Reader)
+		}
+		outputDone <- err
+	}()
+
+	select {
+	case err := <-outputDone:
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, errors.Wrap(err, "")
+		}
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+
+	inspect, err := cli.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return 0, errors.Wrap(err, "")
+	}
+
+	return inspect.ExitCode, nil
+}
@@ -0,0 +1,154 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"cli/util/docker/dockertest"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func stackService(id, stack, name string) swarm.Service {
+	return swarm.Service{
+		ID: id,
+		Spec: swarm.ServiceSpec{
+			Annotations: swarm.Annotations{Name: stack + "_" + name, Labels: map[string]string{StackNamespaceLabel: stack}},
+			Mode:        replicas(1),
+		},
+	}
+}
+
+func TestFindService(t *testing.T) {
+	server := dockertest.NewServer(t)
+	server.Services = []swarm.Service{
+		stackService("svc-pg-1", "database-postgres", "postgres-1"),
+		stackService("svc-pg-2", "database-postgres", "postgres-2"),
+		stackService("svc-mongo", "mongo", "mongo-1"),
+	}
+
+	type cases struct {
+		stack             string
+		name              string
+		expectedServiceID string
+		expectedErr       error
+	}
+
+	testCases := []cases{
+		// case: name without the stack prefix
+		{
+			stack:             "database-postgres",
+			name:              "postgres-2",
+			expectedServiceID: "svc-pg-2",
+		},
+		// case: full name
+		{
+			stack:             "database-postgres",
+			name:              "database-postgres_postgres-1",
+			expectedServiceID: "svc-pg-1",
+		},
+		// case: only service of the stack
+		{
+			stack:             "mongo",
+			expectedServiceID: "svc-mongo",
+		},
+		// case: several services to choose from
+		{
+			stack:       "database-postgres",
+			expectedErr: ErrAmbiguousService,
+		},
+		// case: unknown service
+		{
+			stack:       "database-postgres",
+			name:        "pgpool",
+			expectedErr: ErrServiceNotFound,
+		},
+		// case: package not deployed
+		{
+			stack:       "hapi-fhir",
+			expectedErr: ErrStackNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		service, err := FindService(context.Background(), server.Client(t), tc.stack, tc.name)
+		if tc.expectedErr != nil {
+			jtest.Require(t, tc.expectedErr, err)
+			continue
+		}
+		jtest.RequireNil(t, err)
+		require.Equal(t, tc.expectedServiceID, service.ID)
+	}
+}
+
+func TestExec(t *testing.T) {
+	server := dockertest.NewServer(t)
+	server.NodeID = "node-1"
+	server.Services = []swarm.Service{stackService("svc-pg", "database-postgres", "postgres-1")}
+	server.Tasks = []swarm.Task{
+		// Tasks of other nodes or that aren't running are skipped
+		{ID: "t1", ServiceID: "svc-pg", NodeID: "node-2", DesiredState: swarm.TaskStateRunning, Status: swarm.TaskStatus{State: swarm.TaskStateRunning, ContainerStatus: &swarm.ContainerStatus{ContainerID: "c1"}}},
+		{ID: "t2", ServiceID: "svc-pg", NodeID: "node-1", DesiredState: swarm.TaskStateShutdown, Status: swarm.TaskStatus{State: swarm.TaskStateFailed, ContainerStatus: &swarm.ContainerStatus{ContainerID: "c2"}}},
+		{ID: "t3", ServiceID: "svc-pg", NodeID: "node-1", DesiredState: swarm.TaskStateRunning, Status: swarm.TaskStatus{State: swarm.TaskStateRunning, ContainerStatus: &swarm.ContainerStatus{ContainerID: "c3"}}},
+	}
+	server.Containers = []dockertest.Container{{Summary: container.Summary{ID: "c3"}}}
+	server.Exec = func(containerID string, cmd []string, stdin io.Reader, stdout, stderr io.Writer) int {
+		input, _ := io.ReadAll(stdin)
+		fmt.Fprintf(stdout, "%s: %s\n", containerID, strings.Join(cmd, " "))
+		fmt.Fprintf(stderr, "input: %s\n", input)
+		if len(input) == 0 {
+			return 2
+		}
+		return 0
+	}
+	cli := server.Client(t)
+
+	containerID, err := LocalTaskContainer(context.Background(), cli, server.Services[0])
+	jtest.RequireNil(t, err)
+	require.Equal(t, "c3", containerID)
+
+	type cases struct {
+		opts             ExecOptions
+		expectedStdout   string
+		expectedStderr   string
+		expectedExitCode int
+	}
+
+	testCases := []cases{
+		// case: one-off command returning its exit code
+		{
+			opts:             ExecOptions{Cmd: []string{"psql", "-c", "select 1"}},
+			expectedStdout:   "c3: psql -c select 1\n",
+			expectedStderr:   "input: \n",
+			expectedExitCode: 2,
+		},
+		// case: interactive command reading stdin
+		{
+			opts:           ExecOptions{Cmd: []string{"psql"}, Interactive: true, Stdin: strings.NewReader("select 1;")},
+			expectedStdout: "c3: psql\n",
+			expectedStderr: "input: select 1;\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		var stdout, stderr bytes.Buffer
+		tc.opts.Stdout, tc.opts.Stderr = &stdout, &stderr
+
+		exitCode, err := Exec(context.Background(), cli, containerID, tc.opts)
+		jtest.RequireNil(t, err)
+		require.Equal(t, tc.expectedExitCode, exitCode)
+		require.Equal(t, tc.expectedStdout, stdout.String())
+		require.Equal(t, tc.expectedStderr, stderr.String())
+	}
+
+	server.NodeID = "node-3"
+	_, err = LocalTaskContainer(context.Background(), cli, server.Services[0])
+	jtest.Require(t, ErrNoRunningTask, err)
+}
@@ -0,0 +1,166 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/distribution/reference"
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/go-units"
+	"github.com/luno/jettison/errors"
+	"github.com/moby/term"
+)
+
+// The Docker config stores the credentials of Docker Hub under its legacy index address
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+const pullSummaryInterval = 5 * time.Second
+
+// PullImage pulls imageName with the credentials of its registry from the Docker config and
+// credential helpers. Progress is rendered as per-layer progress bars when out is a terminal, and
+// as periodic summary lines otherwise.
+func PullImage(ctx context.Context, cli client.ImageAPIClient, imageName string, out io.Writer) error {
+	auth, err := registryAuth(config.LoadDefaultConfigFile(io.Discard), imageName)
+	if err != nil {
+		return err
+	}
+
+	reader, err := cli.ImagePull(ctx, imageName, image.PullOptions{RegistryAuth: auth})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer reader.Close()
+
+	fd, isTerminal := term.GetFdInfo(out)
+	if isTerminal {
+		err = jsonmessage.DisplayJSONMessagesStream(reader, out, fd, true, nil)
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+		return nil
+	}
+
+	return summarisePull(reader, out, imageName, pullSummaryInterval)
+}
+
+// registryAuth returns the encoded credentials of the registry of imageName, or an empty string
+// if there are none
+func registryAuth(configFile *configfile.ConfigFile, imageName string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(imageName)
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+
+	hostname := reference.Domain(named)
+	if hostname == "docker.io" {
+		hostname = dockerHubAuthKey
+	}
+
+	authConfig, err := configFile.GetAuthConfig(hostname)
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	if authConfig.Username == "" && authConfig.IdentityToken == "" && authConfig.RegistryToken == "" {
+		return "", nil
+	}
+
+	auth, err := registry.EncodeAuthConfig(registry.AuthConfig{
+		Username:      authConfig.Username,
+		Password:      authConfig.Password,
+		ServerAddress: authConfig.ServerAddress,
+		IdentityToken: authConfig.IdentityToken,
+		RegistryToken: authConfig.RegistryToken,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+
+	return auth, nil
+}
+
+type layerProgress struct {
+	current, total int64
+	complete       bool
+}
+
+// summarisePull reads the JSON message stream of an image pull, writing a summary of the layers
+// and bytes pulled at most every interval, and once the pull is complete
+func summarisePull(r io.Reader, w io.Writer, imageName string, interval time.Duration) error {
+	layers := make(map[string]*layerProgress)
+	var layerIds []string
+
+	printSummary := func() {
+		var complete int
+		var current, total int64
+		for _, id := range layerIds {
+			layer := layers[id]
+			if layer.complete {
+				complete++
+			}
+			current += layer.current
+			total += layer.total
+		}
+
+		fmt.Fprintf(w, "> Pulling %s: %d/%d layers complete, %s/%s downloaded\n",
+			imageName, complete, len(layerIds), units.HumanSize(float64(current)), units.HumanSize(float64(total)))
+	}
+
+	decoder := json.NewDecoder(r)
+	lastSummary := time.Now()
+	for {
+		var message jsonmessage.JSONMessage
+		err := decoder.Decode(&message)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.Wrap(err, "")
+		}
+
+		if message.Error != nil {
+			return errors.Wrap(message.Error, imageName)
+		}
+
+		switch message.Status {
+		case "Pulling fs layer", "Waiting", "Already exists":
+			if _, ok := layers[message.ID]; !ok {
+				layers[message.ID] = &layerProgress{}
+				layerIds = append(layerIds, message.ID)
+			}
+		}
+
+		layer, ok := layers[message.ID]
+		if !ok {
+			continue
+		}
+
+		switch message.Status {
+		case "Downloading":
+			if message.Progress != nil {
+				layer.current = message.Progress.Current
+				layer.total = message.Progress.Total
+			}
+		case "Download complete":
+			layer.current = layer.total
+		case "Pull complete", "Already exists":
+			layer.current = layer.total
+			layer.complete = true
+		}
+
+		if time.Since(lastSummary) >= interval {
+			printSummary()
+			lastSummary = time.Now()
+		}
+	}
+
+	printSummary()
+
+	return nil
+}
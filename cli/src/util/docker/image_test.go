@@ -0,0 +1,92 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/docker/api/types/registry"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_registryAuth(t *testing.T) {
+	configFile, err := config.LoadFromReader(strings.NewReader(`{
+		"auths": {
+			"https://index.docker.io/v1/": {"auth": "` + base64.StdEncoding.EncodeToString([]byte("hub-user:hub-pass")) + `"},
+			"ghcr.io": {"auth": "` + base64.StdEncoding.EncodeToString([]byte("ghcr-user:ghcr-pass")) + `"}
+		}
+	}`))
+	jtest.RequireNil(t, err)
+
+	type cases struct {
+		image            string
+		expectedUsername string
+		expectedPassword string
+	}
+
+	testCases := []cases{
+		// case: Docker Hub image
+		{
+			image:            "openhie/package-base:latest",
+			expectedUsername: "hub-user",
+			expectedPassword: "hub-pass",
+		},
+		// case: image of another registry
+		{
+			image:            "ghcr.io/openhie/package-base:2.0.0",
+			expectedUsername: "ghcr-user",
+			expectedPassword: "ghcr-pass",
+		},
+		// case: registry without credentials
+		{
+			image: "quay.io/openhie/package-base",
+		},
+	}
+
+	for _, tc := range testCases {
+		auth, err := registryAuth(configFile, tc.image)
+		jtest.RequireNil(t, err)
+
+		if tc.expectedUsername == "" {
+			require.Empty(t, auth)
+			continue
+		}
+
+		data, err := base64.URLEncoding.DecodeString(auth)
+		jtest.RequireNil(t, err)
+
+		var authConfig registry.AuthConfig
+		err = json.Unmarshal(data, &authConfig)
+		jtest.RequireNil(t, err)
+		require.Equal(t, tc.expectedUsername, authConfig.Username)
+		require.Equal(t, tc.expectedPassword, authConfig.Password)
+	}
+}
+
+func Test_summarisePull(t *testing.T) {
+	stream := strings.Join([]string{
+		`{"status":"Pulling from openhie/package-base","id":"latest"}`,
+		`{"status":"Already exists","id":"a1"}`,
+		`{"status":"Pulling fs layer","id":"b2"}`,
+		`{"status":"Downloading","progressDetail":{"current":1000,"total":2000},"id":"b2"}`,
+		`{"status":"Download complete","id":"b2"}`,
+		`{"status":"Pull complete","id":"b2"}`,
+		`{"status":"Digest: sha256:abc"}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	err := summarisePull(strings.NewReader(stream), &out, "openhie/package-base:latest", 0)
+	jtest.RequireNil(t, err)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Equal(t, "> Pulling openhie/package-base:latest: 1/2 layers complete, 1kB/2kB downloaded", lines[2])
+	require.Equal(t, "> Pulling openhie/package-base:latest: 2/2 layers complete, 2kB/2kB downloaded", lines[len(lines)-1])
+
+	// case: errors in the stream fail the pull
+	err = summarisePull(strings.NewReader(`{"errorDetail":{"message":"pull access denied"},"error":"pull access denied"}`), &out, "private/image", 0)
+	require.ErrorContains(t, err, "pull access denied")
+}
@@ -0,0 +1,235 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/docker/cli/service/logs"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/luno/jettison/errors"
+)
+
+// prefixColours are the ANSI colours of the log prefixes of tasks, assigned in turn
+var prefixColours = []int{36, 33, 32, 35, 34, 96, 93, 92, 95, 94}
+
+// LogsAPIClient is the part of the Docker API the logs of services are read from
+type LogsAPIClient interface {
+	ServiceList(ctx context.Context, options swarm.ServiceListOptions) ([]swarm.Service, error)
+	ServiceLogs(ctx context.Context, serviceID string, options container.LogsOptions) (io.ReadCloser, error)
+	TaskInspectWithRaw(ctx context.Context, taskID string) (swarm.Task, []byte, error)
+}
+
+// LogsOptions select the log lines of services and how they are written
+type LogsOptions struct {
+	Follow bool
+	// Since is a timestamp or a duration relative to now, eg. 10m
+	Since string
+	// Tail is the number of lines to show from the end of the logs of each service, or all
+	Tail string
+	// Grep selects the lines with messages matching it, if set
+	Grep *regexp.Regexp
+	// Colour colours the task prefixes of lines
+	Colour bool
+}
+
+// ServiceLogs writes the logs of services to w, each line prefixed with the name of its task, eg.
+// openhim-core_openhim-core.1, and its timestamp. The logs of the services are read concurrently
+// and interleaved line by line.
+func ServiceLogs(ctx context.Context, cli LogsAPIClient, services []swarm.Service, w io.Writer, opts LogsOptions) error {
+	lw := &logWriter{
+		ctx:      ctx,
+		cli:      cli,
+		w:        w,
+		opts:     opts,
+		services: make(map[string]swarm.Service),
+		tasks:    make(map[string]string),
+		colours:  make(map[string]int),
+	}
+	for _, service := range services {
+		lw.services[service.ID] = service
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for _, service := range services {
+		wg.Add(1)
+		go func(service swarm.Service) {
+			defer wg.Done()
+
+			err := lw.copyServiceLogs(service)
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(service)
+	}
+	wg.Wait()
+
+	return firstErr
+}
+
+// logWriter writes the log lines of services with the prefixes of their tasks
+type logWriter struct {
+	ctx      context.Context
+	cli      LogsAPIClient
+	w        io.Writer
+	opts     LogsOptions
+	services map[string]swarm.Service
+
+	// mu guards w, tasks and colours
+	mu sync.Mutex
+	// tasks are the names of tasks by id
+	tasks   map[string]string
+	colours map[string]int
+}
+
+func (lw *logWriter) copyServiceLogs(service swarm.Service) error {
+	reader, err := lw.cli.ServiceLogs(lw.ctx, service.ID, container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Since:      lw.opts.Since,
+		Tail:       lw.opts.Tail,
+		Follow:     lw.opts.Follow,
+		Timestamps: true,
+		Details:    true,
+	})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer reader.Close()
+
+	stdout := &lineWriter{write: lw.writeLine}
+	stderr := &lineWriter{write: lw.writeLine}
+
+	// Services with a TTY don't multiplex their logs
+	if spec := service.Spec.TaskTemplate.ContainerSpec; spec != nil && spec.TTY {
+		_, err = io.Copy(stdout, reader)
+	} else {
+		_, err = stdcopy.StdCopy(stdout, stderr, reader)
+	}
+	if err != nil && lw.ctx.Err() == nil {
+		return errors.Wrap(err, "")
+	}
+	stdout.flush()
+	stderr.flush()
+
+	return lw.ctx.Err()
+}
+
+// writeLine writes a log line of the form "timestamp details message"
+func (lw *logWriter) writeLine(line []byte) {
+	parts := bytes.SplitN(line, []byte(" "), 3)
+	if len(parts) != 3 {
+		lw.write("", string(line))
+		return
+	}
+	timestamp, message := string(parts[0]), string(parts[2])
+
+	if lw.opts.Grep != nil && !lw.opts.Grep.MatchString(message) {
+		return
+	}
+
+	details, err := logs.ParseLogDetails(string(parts[1]))
+	if err != nil {
+		lw.write("", string(line))
+		return
+	}
+	task := lw.taskName(details["com.docker.swarm.task.id"], details["com.docker.swarm.service.id"])
+
+	lw.write(task, timestamp+" "+message)
+}
+
+func (lw *logWriter) write(task, line string) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	if task == "" {
+		fmt.Fprintln(lw.w, line)
+		return
+	}
+
+	prefix := task
+	if lw.opts.Colour {
+		colour, ok := lw.colours[task]
+		if !ok {
+			colour = prefixColours[len(lw.colours)%len(prefixColours)]
+			lw.colours[task] = colour
+		}
+		prefix = fmt.Sprintf("\x1b[%dm%s\x1b[0m", colour, task)
+	}
+
+	fmt.Fprintf(lw.w, "%s | %s\n", prefix, line)
+}
+
+// taskName returns the name of the task with taskID as docker service ps shows it, inspecting
+// tasks the first time their logs are seen
+func (lw *logWriter) taskName(taskID, serviceID string) string {
+	lw.mu.Lock()
+	name, ok := lw.tasks[taskID]
+	lw.mu.Unlock()
+	if ok {
+		return name
+	}
+
+	service := lw.services[serviceID]
+	name = service.Spec.Name + "." + shortID(taskID)
+	task, _, err := lw.cli.TaskInspectWithRaw(lw.ctx, taskID)
+	if err == nil {
+		name = taskName(service, task)
+	}
+
+	lw.mu.Lock()
+	lw.tasks[taskID] = name
+	lw.mu.Unlock()
+
+	return name
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+
+	return id
+}
+
+// lineWriter calls write with every complete line written to it
+type lineWriter struct {
+	buf   []byte
+	write func(line []byte)
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.write(bytes.TrimSuffix(w.buf[:i], []byte("\r")))
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// flush writes the last line if it doesn't end with a newline
+func (w *lineWriter) flush() {
+	if len(strings.TrimSpace(string(w.buf))) > 0 {
+		w.write(w.buf)
+	}
+	w.buf = nil
+}
@@ -0,0 +1,61 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"cli/util/docker/dockertest"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceLogs(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	server := dockertest.NewServer(t)
+	server.Services = []swarm.Service{
+		{ID: "svc-core", Spec: swarm.ServiceSpec{Annotations: swarm.Annotations{Name: "openhim-core_openhim-core"}, Mode: replicas(2)}},
+	}
+	server.Tasks = []swarm.Task{
+		{ID: "task-1", ServiceID: "svc-core", Slot: 1},
+		{ID: "task-2", ServiceID: "svc-core", Slot: 2},
+	}
+	server.Logs = []dockertest.LogLine{
+		{ServiceID: "svc-core", TaskID: "task-1", Time: start, Message: "Starting OpenHIM core"},
+		{ServiceID: "svc-core", TaskID: "task-2", Time: start.Add(time.Second), Stderr: true, Message: "MongoError: connection refused"},
+		// Tasks that were removed since are named after their id
+		{ServiceID: "svc-core", TaskID: "removedtask12345", Time: start.Add(2 * time.Second), Message: "Shutting down"},
+	}
+
+	type cases struct {
+		opts           LogsOptions
+		expectedOutput string
+	}
+
+	testCases := []cases{
+		// case: every line, prefixed with its task and timestamp
+		{
+			expectedOutput: `openhim-core_openhim-core.1 | 2026-10-01T12:00:00Z Starting OpenHIM core
+openhim-core_openhim-core.2 | 2026-10-01T12:00:01Z MongoError: connection refused
+openhim-core_openhim-core.removedtask1 | 2026-10-01T12:00:02Z Shutting down
+`,
+		},
+		// case: lines matching grep, with coloured prefixes
+		{
+			opts:           LogsOptions{Grep: regexp.MustCompile(`(?i)error`), Colour: true},
+			expectedOutput: "\x1b[36mopenhim-core_openhim-core.2\x1b[0m | 2026-10-01T12:00:01Z MongoError: connection refused\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		var b bytes.Buffer
+		err := ServiceLogs(context.Background(), server.Client(t), server.Services, &b, tc.opts)
+		jtest.RequireNil(t, err)
+		require.Equal(t, tc.expectedOutput, b.String())
+	}
+}
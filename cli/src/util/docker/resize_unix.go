@@ -0,0 +1,33 @@
+//go:build !windows
+
+package docker
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// monitorTerminalSize calls resize now and whenever the terminal is resized, until stop is called
+func monitorTerminalSize(resize func()) (stop func()) {
+	resize()
+
+	sigwinch := make(chan os.Signal, 1)
+	signal.Notify(sigwinch, syscall.SIGWINCH)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-sigwinch:
+				resize()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(sigwinch)
+		close(done)
+	}
+}
@@ -0,0 +1,29 @@
+package docker
+
+import (
+	"time"
+)
+
+// monitorTerminalSize calls resize now and whenever the terminal may have been resized, until stop
+// is called. Windows has no signal for terminal resizes, so the size is polled.
+func monitorTerminalSize(resize func()) (stop func()) {
+	resize()
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				resize()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(done)
+	}
+}
@@ -0,0 +1,287 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/luno/jettison/errors"
+)
+
+// StackNamespaceLabel is the label docker stack deploy sets on the services of a stack, holding the
+// name of the stack. Packages are deployed as stacks named after their id.
+const StackNamespaceLabel = "com.docker.stack.namespace"
+
+var ErrStackNotFound = errors.New("no services are deployed for the package")
+
+// StackNotFoundHint tells the user how to look into ErrStackNotFound
+const StackNotFoundHint = "Check that the package is deployed with 'project status' or 'package status'"
+
+// Service modes of a swarm service
+const (
+	ModeReplicated    = "replicated"
+	ModeGlobal        = "global"
+	ModeReplicatedJob = "replicated-job"
+	ModeGlobalJob     = "global-job"
+)
+
+// StackAPIClient is the part of the Docker API the state of stacks is read from
+type StackAPIClient interface {
+	ServiceList(ctx context.Context, options swarm.ServiceListOptions) ([]swarm.Service, error)
+	TaskList(ctx context.Context, options swarm.TaskListOptions) ([]swarm.Task, error)
+	ContainerList(ctx context.Context, options container.ListOptions) ([]container.Summary, error)
+	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
+}
+
+// ServiceState is the state of a swarm service of a stack
+type ServiceState struct {
+	Name   string `json:"name"`
+	Mode   string `json:"mode"`
+	Image  string `json:"image"`
+	Digest string `json:"digest,omitempty"`
+	// DesiredTasks is the number of replicas of replicated services, and the number of nodes
+	// global services run on
+	DesiredTasks   uint64    `json:"desiredTasks"`
+	RunningTasks   uint64    `json:"runningTasks"`
+	CompletedTasks uint64    `json:"completedTasks,omitempty"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+	// UpdateState is the state of the last rolling update of the service, if any
+	UpdateState string `json:"updateState,omitempty"`
+	// Problems are the tasks that are not running as they should, and the unhealthy task
+	// containers of the current node
+	Problems []TaskProblem `json:"problems,omitempty"`
+}
+
+// TaskProblem is a task of a service that failed, is not running yet, or is unhealthy
+type TaskProblem struct {
+	Task  string `json:"task"`
+	State string `json:"state"`
+	Error string `json:"error,omitempty"`
+}
+
+// IsJob reports whether the service runs tasks to completion rather than keeping them running
+func (s ServiceState) IsJob() bool {
+	return s.Mode == ModeReplicatedJob || s.Mode == ModeGlobalJob
+}
+
+// Degraded reports whether the service runs fewer tasks than desired, has problem tasks, or has a
+// paused rolling update
+func (s ServiceState) Degraded() bool {
+	if !s.IsJob() && s.RunningTasks < s.DesiredTasks {
+		return true
+	}
+
+	return len(s.Problems) > 0 ||
+		s.UpdateState == string(swarm.UpdateStatePaused) ||
+		s.UpdateState == string(swarm.UpdateStateRollbackPaused)
+}
+
+// serviceLister is the part of the Docker API services are listed with
+type serviceLister interface {
+	ServiceList(ctx context.Context, options swarm.ServiceListOptions) ([]swarm.Service, error)
+}
+
+// ListStackServices returns the services of stack, sorted by name. With status, the services hold
+// their number of running and desired tasks.
+func ListStackServices(ctx context.Context, cli serviceLister, stack string, status bool) ([]swarm.Service, error) {
+	services, err := cli.ServiceList(ctx, swarm.ServiceListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", StackNamespaceLabel+"="+stack)),
+		Status:  status,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	sort.Slice(services, func(i, j int) bool { return services[i].Spec.Name < services[j].Spec.Name })
+
+	return services, nil
+}
+
+// StackServices returns the state of the services of stack, sorted by name
+func StackServices(ctx context.Context, cli StackAPIClient, stack string) ([]ServiceState, error) {
+	services, err := ListStackServices(ctx, cli, stack, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(services) == 0 {
+		return nil, nil
+	}
+
+	serviceFilters := filters.NewArgs()
+	for _, service := range services {
+		serviceFilters.Add("service", service.Spec.Name)
+	}
+	tasks, err := cli.TaskList(ctx, swarm.TaskListOptions{Filters: serviceFilters})
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	unhealthy, err := unhealthyContainers(ctx, cli, stack)
+	if err != nil {
+		return nil, err
+	}
+
+	var states []ServiceState
+	for _, service := range services {
+		state := serviceState(service)
+
+		var serviceTasks []swarm.Task
+		for _, task := range tasks {
+			if task.ServiceID == service.ID {
+				serviceTasks = append(serviceTasks, task)
+			}
+		}
+		state.Problems = append(taskProblems(service, serviceTasks), unhealthy[service.Spec.Name]...)
+
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+func serviceState(service swarm.Service) ServiceState {
+	state := ServiceState{
+		Name:      service.Spec.Name,
+		Mode:      serviceMode(service.Spec.Mode),
+		UpdatedAt: service.UpdatedAt,
+	}
+
+	if containerSpec := service.Spec.TaskTemplate.ContainerSpec; containerSpec != nil {
+		state.Image, state.Digest, _ = strings.Cut(containerSpec.Image, "@")
+	}
+	if service.ServiceStatus != nil {
+		state.DesiredTasks = service.ServiceStatus.DesiredTasks
+		state.RunningTasks = service.ServiceStatus.RunningTasks
+		state.CompletedTasks = service.ServiceStatus.CompletedTasks
+	}
+	if service.UpdateStatus != nil {
+		state.UpdateState = string(service.UpdateStatus.State)
+		if service.UpdateStatus.CompletedAt != nil && service.UpdateStatus.CompletedAt.After(state.UpdatedAt) {
+			state.UpdatedAt = *service.UpdateStatus.CompletedAt
+		}
+	}
+
+	return state
+}
+
+func serviceMode(mode swarm.ServiceMode) string {
+	switch {
+	case mode.Global != nil:
+		return ModeGlobal
+	case mode.ReplicatedJob != nil:
+		return ModeReplicatedJob
+	case mode.GlobalJob != nil:
+		return ModeGlobalJob
+	}
+
+	return ModeReplicated
+}
+
+// taskProblems returns the latest task of each slot of a replicated service, or of each node of a
+// global service, if it isn't running, or complete for jobs
+func taskProblems(service swarm.Service, tasks []swarm.Task) []TaskProblem {
+	latest := make(map[string]swarm.Task)
+	for _, task := range tasks {
+		key := task.NodeID
+		if service.Spec.Mode.Global == nil && service.Spec.Mode.GlobalJob == nil {
+			key = fmt.Sprint(task.Slot)
+		}
+
+		current, ok := latest[key]
+		if !ok || isNewerTask(task, current) {
+			latest[key] = task
+		}
+	}
+
+	var keys []string
+	for key := range latest {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	isJob := service.Spec.Mode.ReplicatedJob != nil || service.Spec.Mode.GlobalJob != nil
+	var problems []TaskProblem
+	for _, key := range keys {
+		task := latest[key]
+		switch {
+		case task.Status.State == swarm.TaskStateRunning:
+			continue
+		case task.Status.State == swarm.TaskStateComplete && (isJob || task.DesiredState != swarm.TaskStateRunning):
+			continue
+		case task.DesiredState == swarm.TaskStateShutdown && task.Status.State == swarm.TaskStateShutdown:
+			continue
+		}
+
+		problems = append(problems, TaskProblem{
+			Task:  taskName(service, task),
+			State: string(task.Status.State),
+			Error: task.Status.Err,
+		})
+	}
+
+	return problems
+}
+
+// isNewerTask reports whether task supersedes current in its slot, preferring tasks that are meant
+// to be running
+func isNewerTask(task, current swarm.Task) bool {
+	taskDesired := task.DesiredState == swarm.TaskStateRunning
+	currentDesired := current.DesiredState == swarm.TaskStateRunning
+	if taskDesired != currentDesired {
+		return taskDesired
+	}
+
+	return task.CreatedAt.After(current.CreatedAt)
+}
+
+// taskName returns the name docker service ps shows for task, eg. openhim-core_api.1
+func taskName(service swarm.Service, task swarm.Task) string {
+	if service.Spec.Mode.Global != nil || service.Spec.Mode.GlobalJob != nil {
+		return service.Spec.Name + "." + task.NodeID
+	}
+
+	return fmt.Sprintf("%s.%d", service.Spec.Name, task.Slot)
+}
+
+// unhealthyContainers returns the unhealthy task containers of stack on the current node by service
+// name, with the output of their last health check
+func unhealthyContainers(ctx context.Context, cli StackAPIClient, stack string) (map[string][]TaskProblem, error) {
+	containers, err := cli.ContainerList(ctx, container.ListOptions{
+		Filters: filters.NewArgs(
+			filters.Arg("label", StackNamespaceLabel+"="+stack),
+			filters.Arg("health", "unhealthy"),
+		),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	problems := make(map[string][]TaskProblem)
+	for _, c := range containers {
+		problem := TaskProblem{
+			Task:  c.Labels["com.docker.swarm.task.name"],
+			State: "unhealthy",
+		}
+		if problem.Task == "" && len(c.Names) > 0 {
+			problem.Task = strings.TrimPrefix(c.Names[0], "/")
+		}
+
+		inspect, err := cli.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+		if inspect.State != nil && inspect.State.Health != nil && len(inspect.State.Health.Log) > 0 {
+			problem.Error = strings.TrimSpace(inspect.State.Health.Log[len(inspect.State.Health.Log)-1].Output)
+		}
+
+		service := c.Labels["com.docker.swarm.service.name"]
+		problems[service] = append(problems[service], problem)
+	}
+
+	return problems, nil
+}
@@ -0,0 +1,148 @@
+package docker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cli/util/docker/dockertest"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func replicas(n uint64) swarm.ServiceMode {
+	return swarm.ServiceMode{Replicated: &swarm.ReplicatedService{Replicas: &n}}
+}
+
+func TestStackServices(t *testing.T) {
+	updatedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	stackLabels := map[string]string{StackNamespaceLabel: "openhim-core"}
+
+	server := dockertest.NewServer(t)
+	server.Services = []swarm.Service{
+		{
+			ID:   "svc-core",
+			Meta: swarm.Meta{UpdatedAt: updatedAt},
+			Spec: swarm.ServiceSpec{
+				Annotations:  swarm.Annotations{Name: "openhim-core_openhim-core", Labels: stackLabels},
+				TaskTemplate: swarm.TaskSpec{ContainerSpec: &swarm.ContainerSpec{Image: "jembi/openhim-core:v8.4.0@sha256:4f2a"}},
+				Mode:         replicas(2),
+			},
+			ServiceStatus: &swarm.ServiceStatus{DesiredTasks: 2, RunningTasks: 1},
+		},
+		{
+			ID:   "svc-console",
+			Meta: swarm.Meta{UpdatedAt: updatedAt},
+			Spec: swarm.ServiceSpec{
+				Annotations:  swarm.Annotations{Name: "openhim-core_openhim-console", Labels: stackLabels},
+				TaskTemplate: swarm.TaskSpec{ContainerSpec: &swarm.ContainerSpec{Image: "jembi/openhim-console:v1.18.2"}},
+				Mode:         replicas(1),
+			},
+			ServiceStatus: &swarm.ServiceStatus{DesiredTasks: 1, RunningTasks: 1},
+		},
+		{
+			ID: "svc-mongo",
+			Spec: swarm.ServiceSpec{
+				Annotations: swarm.Annotations{Name: "mongo_mongo-1", Labels: map[string]string{StackNamespaceLabel: "mongo"}},
+				Mode:        replicas(1),
+			},
+		},
+	}
+	server.Tasks = []swarm.Task{
+		// The first replica failed and was replaced by a running task
+		{ID: "t1", ServiceID: "svc-core", Slot: 1, Meta: swarm.Meta{CreatedAt: updatedAt}, DesiredState: swarm.TaskStateShutdown, Status: swarm.TaskStatus{State: swarm.TaskStateFailed, Err: "task: non-zero exit (1)"}},
+		{ID: "t2", ServiceID: "svc-core", Slot: 1, Meta: swarm.Meta{CreatedAt: updatedAt.Add(time.Minute)}, DesiredState: swarm.TaskStateRunning, Status: swarm.TaskStatus{State: swarm.TaskStateRunning}},
+		// The second replica can't be scheduled
+		{ID: "t3", ServiceID: "svc-core", Slot: 2, Meta: swarm.Meta{CreatedAt: updatedAt}, DesiredState: swarm.TaskStateRunning, Status: swarm.TaskStatus{State: swarm.TaskStatePending, Err: "no suitable node (insufficient resources on 1 node)"}},
+		{ID: "t4", ServiceID: "svc-console", Slot: 1, Meta: swarm.Meta{CreatedAt: updatedAt}, DesiredState: swarm.TaskStateRunning, Status: swarm.TaskStatus{State: swarm.TaskStateRunning}},
+	}
+	server.Containers = []dockertest.Container{
+		{
+			Summary: container.Summary{
+				ID: "c4",
+				Labels: map[string]string{
+					StackNamespaceLabel:             "openhim-core",
+					"com.docker.swarm.service.name": "openhim-core_openhim-console",
+					"com.docker.swarm.task.name":    "openhim-core_openhim-console.1.t4",
+				},
+				State: "running",
+			},
+			Health: &container.Health{
+				Status: container.Unhealthy,
+				Log:    []*container.HealthcheckResult{{ExitCode: 1, Output: "curl: (7) Failed to connect to localhost port 80\n"}},
+			},
+		},
+	}
+
+	services, err := StackServices(context.Background(), server.Client(t), "openhim-core")
+	jtest.RequireNil(t, err)
+
+	require.Equal(t, []ServiceState{
+		{
+			Name:         "openhim-core_openhim-console",
+			Mode:         ModeReplicated,
+			Image:        "jembi/openhim-console:v1.18.2",
+			DesiredTasks: 1,
+			RunningTasks: 1,
+			UpdatedAt:    updatedAt,
+			Problems: []TaskProblem{
+				{Task: "openhim-core_openhim-console.1.t4", State: "unhealthy", Error: "curl: (7) Failed to connect to localhost port 80"},
+			},
+		},
+		{
+			Name:         "openhim-core_openhim-core",
+			Mode:         ModeReplicated,
+			Image:        "jembi/openhim-core:v8.4.0",
+			Digest:       "sha256:4f2a",
+			DesiredTasks: 2,
+			RunningTasks: 1,
+			UpdatedAt:    updatedAt,
+			Problems: []TaskProblem{
+				{Task: "openhim-core_openhim-core.2", State: "pending", Error: "no suitable node (insufficient resources on 1 node)"},
+			},
+		},
+	}, services)
+
+	for _, service := range services {
+		require.True(t, service.Degraded())
+	}
+
+	services, err = StackServices(context.Background(), server.Client(t), "hapi-fhir")
+	jtest.RequireNil(t, err)
+	require.Empty(t, services)
+}
+
+func TestServiceState_Degraded(t *testing.T) {
+	type cases struct {
+		state    ServiceState
+		expected bool
+	}
+
+	testCases := []cases{
+		// case: all replicas running
+		{
+			state: ServiceState{Mode: ModeReplicated, DesiredTasks: 3, RunningTasks: 3},
+		},
+		// case: missing replicas
+		{
+			state:    ServiceState{Mode: ModeReplicated, DesiredTasks: 3, RunningTasks: 2},
+			expected: true,
+		},
+		// case: completed job
+		{
+			state: ServiceState{Mode: ModeReplicatedJob, DesiredTasks: 1, CompletedTasks: 1},
+		},
+		// case: paused update
+		{
+			state:    ServiceState{Mode: ModeGlobal, DesiredTasks: 1, RunningTasks: 1, UpdateState: "paused"},
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, tc.state.Degraded())
+	}
+}
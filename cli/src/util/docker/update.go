@@ -0,0 +1,209 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"cli/core/exitcode"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/luno/jettison/errors"
+)
+
+var ErrUpdateFailed = errors.New("service update failed")
+
+// updatePollInterval is how often the state of a service update is checked
+var updatePollInterval = time.Second
+
+// UpdateAPIClient is the part of the Docker API services are updated with
+type UpdateAPIClient interface {
+	ServiceInspectWithRaw(ctx context.Context, serviceID string, options swarm.ServiceInspectOptions) (swarm.Service, []byte, error)
+	ServiceUpdate(ctx context.Context, serviceID string, version swarm.Version, service swarm.ServiceSpec, options swarm.ServiceUpdateOptions) (swarm.ServiceUpdateResponse, error)
+}
+
+// UpdateOptions control how a service update is rolled out and waited for
+type UpdateOptions struct {
+	// Timeout is how long to wait for the update of a service to complete
+	Timeout time.Duration
+	// Rollback rolls a service back to its previous spec if its update is paused by failing tasks
+	// or doesn't complete within Timeout
+	Rollback bool
+	// ResolveImage pins the image of the service to its digest in the registry, so that every node
+	// runs the same image, as docker service update does
+	ResolveImage bool
+	// Out is where the progress of the update is written
+	Out io.Writer
+}
+
+// UpdateService applies change to the spec of service and waits for swarm to roll it out, following
+// the update config of the service (parallelism, delay, failure action and order). An error wrapping
+// ErrUpdateFailed is returned if the update is paused, rolled back or times out.
+func UpdateService(ctx context.Context, cli UpdateAPIClient, service swarm.Service, change func(spec *swarm.ServiceSpec), opts UpdateOptions) error {
+	current, _, err := cli.ServiceInspectWithRaw(ctx, service.ID, swarm.ServiceInspectOptions{})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	name := current.Spec.Name
+
+	spec := current.Spec
+	change(&spec)
+
+	updateOptions := swarm.ServiceUpdateOptions{QueryRegistry: opts.ResolveImage}
+	if opts.ResolveImage && spec.TaskTemplate.ContainerSpec != nil {
+		updateOptions.EncodedRegistryAuth, err = registryAuth(config.LoadDefaultConfigFile(io.Discard), spec.TaskTemplate.ContainerSpec.Image)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(opts.Out, "> Updating %s (%s)\n", name, describeUpdateConfig(spec.UpdateConfig))
+	previous := updateStartedAt(current)
+	response, err := cli.ServiceUpdate(ctx, current.ID, current.Version, spec, updateOptions)
+	if err != nil {
+		return errors.Wrap(err, name)
+	}
+	for _, warning := range response.Warnings {
+		fmt.Fprintln(opts.Out, "> Warning:", strings.TrimSpace(warning))
+	}
+
+	// Jobs run their tasks again with the new spec, without a rolling update
+	if spec.Mode.ReplicatedJob != nil || spec.Mode.GlobalJob != nil {
+		fmt.Fprintf(opts.Out, "> Updated job %s, its tasks run again\n", name)
+		return nil
+	}
+
+	updated, err := waitForUpdate(ctx, cli, current.ID, previous, opts.Timeout)
+	timedOut := errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil
+	if err != nil && !timedOut {
+		return err
+	}
+
+	reason := "update timed out after " + opts.Timeout.String()
+	if !timedOut {
+		reason = updateMessage(updated)
+		switch updated.UpdateStatus.State {
+		case swarm.UpdateStateCompleted:
+			fmt.Fprintf(opts.Out, "> Updated %s\n", name)
+			return nil
+		case swarm.UpdateStateRollbackCompleted:
+			return updateFailed(name + " was rolled back by its failure action: " + reason)
+		case swarm.UpdateStateRollbackPaused:
+			return updateFailed("the rollback of " + name + " by its failure action is paused: " + reason)
+		}
+	}
+
+	if !opts.Rollback {
+		return updateFailed(name + ": " + reason)
+	}
+
+	fmt.Fprintf(opts.Out, "> Rolling back %s: %s\n", name, reason)
+	err = rollbackService(ctx, cli, current.ID, opts.Timeout)
+	if err != nil {
+		return errors.Wrap(err, name+": "+reason)
+	}
+	fmt.Fprintf(opts.Out, "> Rolled back %s\n", name)
+
+	return updateFailed(name + " was rolled back: " + reason)
+}
+
+func updateFailed(detail string) error {
+	return exitcode.New(exitcode.DeploymentFailed, errors.Wrap(ErrUpdateFailed, detail), "Check the failed tasks of the service with 'package status' or 'docker service ps --no-trunc'")
+}
+
+// rollbackService rolls service back to its previous spec and waits for the rollback to complete
+func rollbackService(ctx context.Context, cli UpdateAPIClient, serviceID string, timeout time.Duration) error {
+	current, _, err := cli.ServiceInspectWithRaw(ctx, serviceID, swarm.ServiceInspectOptions{})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	_, err = cli.ServiceUpdate(ctx, current.ID, current.Version, current.Spec, swarm.ServiceUpdateOptions{Rollback: "previous"})
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	rolledBack, err := waitForUpdate(ctx, cli, current.ID, updateStartedAt(current), timeout)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return updateFailed("rollback timed out after " + timeout.String())
+	} else if err != nil {
+		return err
+	}
+	if rolledBack.UpdateStatus.State != swarm.UpdateStateRollbackCompleted {
+		return updateFailed("rollback " + updateMessage(rolledBack))
+	}
+
+	return nil
+}
+
+// waitForUpdate polls a service until the update started after previous completes or is paused,
+// returning context.DeadlineExceeded if that takes longer than timeout
+func waitForUpdate(ctx context.Context, cli UpdateAPIClient, serviceID string, previous time.Time, timeout time.Duration) (swarm.Service, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		service, _, err := cli.ServiceInspectWithRaw(ctx, serviceID, swarm.ServiceInspectOptions{})
+		if err != nil && ctx.Err() == nil {
+			return service, errors.Wrap(err, "")
+		}
+
+		if err == nil && updateStartedAt(service).After(previous) {
+			switch service.UpdateStatus.State {
+			case swarm.UpdateStateCompleted, swarm.UpdateStatePaused,
+				swarm.UpdateStateRollbackCompleted, swarm.UpdateStateRollbackPaused:
+				return service, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return service, ctx.Err()
+		case <-time.After(updatePollInterval):
+		}
+	}
+}
+
+// updateStartedAt returns when the last update of service started, or the zero time if it was never
+// updated
+func updateStartedAt(service swarm.Service) time.Time {
+	if service.UpdateStatus == nil || service.UpdateStatus.StartedAt == nil {
+		return time.Time{}
+	}
+
+	return *service.UpdateStatus.StartedAt
+}
+
+func updateMessage(service swarm.Service) string {
+	if service.UpdateStatus.Message != "" {
+		return service.UpdateStatus.Message
+	}
+
+	return string(service.UpdateStatus.State)
+}
+
+// describeUpdateConfig describes how swarm rolls out an update, with the defaults of docker stack
+// deploy for an unset update config
+func describeUpdateConfig(updateConfig *swarm.UpdateConfig) string {
+	if updateConfig == nil {
+		updateConfig = &swarm.UpdateConfig{Parallelism: 1}
+	}
+
+	parallelism := fmt.Sprint(updateConfig.Parallelism)
+	if updateConfig.Parallelism == 0 {
+		parallelism = "all"
+	}
+	failureAction := updateConfig.FailureAction
+	if failureAction == "" {
+		failureAction = swarm.UpdateFailureActionPause
+	}
+	order := updateConfig.Order
+	if order == "" {
+		order = swarm.UpdateOrderStopFirst
+	}
+
+	return fmt.Sprintf("parallelism %s, delay %s, failure action %s, order %s", parallelism, updateConfig.Delay, failureAction, order)
+}
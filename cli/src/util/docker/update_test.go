@@ -0,0 +1,115 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"cli/util/docker/dockertest"
+
+	"github.com/docker/docker/api/types/swarm"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateService(t *testing.T) {
+	updatePollInterval = 10 * time.Millisecond
+
+	// setState returns an update func that sets the update state of services, and the state of
+	// rollbacks if rollbackState is set
+	setState := func(state, rollbackState swarm.UpdateState, message string) dockertest.UpdateFunc {
+		startedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+		return func(service *swarm.Service, rollback bool) {
+			startedAt = startedAt.Add(time.Minute)
+			status := &swarm.UpdateStatus{State: state, StartedAt: &startedAt, Message: message}
+			if rollback {
+				status = &swarm.UpdateStatus{State: rollbackState, StartedAt: &startedAt}
+			}
+			if status.State != "" {
+				service.UpdateStatus = status
+			}
+		}
+	}
+
+	type cases struct {
+		update         dockertest.UpdateFunc
+		rollback       bool
+		expectedImage  string
+		expectedOutput string
+		expectedErr    error
+	}
+
+	testCases := []cases{
+		// case: completed update
+		{
+			update:        setState(swarm.UpdateStateCompleted, "", "update completed"),
+			rollback:      true,
+			expectedImage: "postgres:16.4",
+			expectedOutput: `> Updating database-postgres_postgres-1 (parallelism 1, delay 10s, failure action pause, order stop-first)
+> Updated database-postgres_postgres-1
+`,
+		},
+		// case: paused update rolled back
+		{
+			update:        setState(swarm.UpdateStatePaused, swarm.UpdateStateRollbackCompleted, "update paused due to failure or early termination of task t1"),
+			rollback:      true,
+			expectedImage: "postgres:16.3",
+			expectedOutput: `> Updating database-postgres_postgres-1 (parallelism 1, delay 10s, failure action pause, order stop-first)
+> Rolling back database-postgres_postgres-1: update paused due to failure or early termination of task t1
+> Rolled back database-postgres_postgres-1
+`,
+			expectedErr: ErrUpdateFailed,
+		},
+		// case: paused update left as it is
+		{
+			update:        setState(swarm.UpdateStatePaused, "", "update paused due to failure or early termination of task t1"),
+			expectedImage: "postgres:16.4",
+			expectedErr:   ErrUpdateFailed,
+		},
+		// case: update rolled back by its failure action
+		{
+			update:        setState(swarm.UpdateStateRollbackCompleted, "", "rollback completed"),
+			rollback:      true,
+			expectedImage: "postgres:16.4",
+			expectedErr:   ErrUpdateFailed,
+		},
+		// case: update timing out, then rolled back
+		{
+			update:        setState("", swarm.UpdateStateRollbackCompleted, ""),
+			rollback:      true,
+			expectedImage: "postgres:16.3",
+			expectedOutput: `> Updating database-postgres_postgres-1 (parallelism 1, delay 10s, failure action pause, order stop-first)
+> Rolling back database-postgres_postgres-1: update timed out after 100ms
+> Rolled back database-postgres_postgres-1
+`,
+			expectedErr: ErrUpdateFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		server := dockertest.NewServer(t)
+		server.Services = []swarm.Service{stackService("svc-pg", "database-postgres", "postgres-1")}
+		server.Services[0].Spec.TaskTemplate.ContainerSpec = &swarm.ContainerSpec{Image: "postgres:16.3"}
+		server.Services[0].Spec.UpdateConfig = &swarm.UpdateConfig{Parallelism: 1, Delay: 10 * time.Second}
+		server.Update = tc.update
+		cli := server.Client(t)
+
+		var out bytes.Buffer
+		err := UpdateService(context.Background(), cli, server.Services[0], func(spec *swarm.ServiceSpec) {
+			spec.TaskTemplate.ContainerSpec.Image = "postgres:16.4"
+		}, UpdateOptions{Timeout: 100 * time.Millisecond, Rollback: tc.rollback, Out: &out})
+		if tc.expectedErr != nil {
+			jtest.Require(t, tc.expectedErr, err)
+		} else {
+			jtest.RequireNil(t, err)
+		}
+		if tc.expectedOutput != "" {
+			require.Equal(t, tc.expectedOutput, out.String())
+		}
+
+		service, _, err := cli.ServiceInspectWithRaw(context.Background(), "svc-pg", swarm.ServiceInspectOptions{})
+		jtest.RequireNil(t, err)
+		require.Equal(t, tc.expectedImage, service.Spec.TaskTemplate.ContainerSpec.Image)
+	}
+}
@@ -0,0 +1,97 @@
+package file
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/luno/jettison/errors"
+)
+
+var (
+	ErrUnsupportedIntegrity = errors.New("unsupported integrity value, expected a sha256 hex digest or a sha256, sha384 or sha512 subresource integrity value")
+	ErrIntegrityMismatch    = errors.New("integrity mismatch")
+)
+
+var integrityAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+func digestFile(path string, newHash func() hash.Hash) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+	defer file.Close()
+
+	h := newHash()
+	_, err = io.Copy(h, file)
+	if err != nil {
+		return nil, errors.Wrap(err, "")
+	}
+
+	return h.Sum(nil), nil
+}
+
+// Sha256 returns the hex encoded sha256 digest of the file at path
+func Sha256(path string) (string, error) {
+	digest, err := digestFile(path, sha256.New)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(digest), nil
+}
+
+// Integrity returns the subresource integrity value (eg. sha256-<base64 digest>) of the file at
+// path using the given algorithm
+func Integrity(path, algorithm string) (string, error) {
+	newHash, ok := integrityAlgorithms[algorithm]
+	if !ok {
+		return "", errors.Wrap(ErrUnsupportedIntegrity, algorithm)
+	}
+
+	digest, err := digestFile(path, newHash)
+	if err != nil {
+		return "", err
+	}
+
+	return algorithm + "-" + base64.StdEncoding.EncodeToString(digest), nil
+}
+
+// VerifyIntegrity checks the file at path against a hex encoded sha256 digest or a
+// subresource integrity value
+func VerifyIntegrity(path, expected string) error {
+	var actual string
+	var err error
+
+	algorithm, _, isIntegrity := strings.Cut(expected, "-")
+	switch {
+	case isIntegrity:
+		actual, err = Integrity(path, algorithm)
+
+	case len(expected) == sha256.Size*2:
+		expected = strings.ToLower(expected)
+		actual, err = Sha256(path)
+
+	default:
+		return errors.Wrap(ErrUnsupportedIntegrity, expected)
+	}
+	if err != nil {
+		return err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) != 1 {
+		return errors.Wrap(ErrIntegrityMismatch, "expected "+expected+", got "+actual)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_VerifyIntegrity(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "package.tar")
+	err := os.WriteFile(path, []byte("test data"), 0644)
+	jtest.RequireNil(t, err)
+
+	sha256Hex, err := Sha256(path)
+	jtest.RequireNil(t, err)
+	require.Equal(t, "916f0027a575074ce72a331777c3478d6513f786a591bd892da1a577bf2335f9", sha256Hex)
+
+	integrity, err := Integrity(path, "sha256")
+	jtest.RequireNil(t, err)
+	require.Equal(t, "sha256-kW8AJ6V1B0znKjMXd8NHjWUT94alkb2JLaGld78jNfk=", integrity)
+
+	type cases struct {
+		expected      string
+		expectedError error
+	}
+
+	testCases := []cases{
+		// case: matching sha256 hex digest
+		{expected: sha256Hex},
+		// case: matching upper case sha256 hex digest
+		{expected: "916F0027A575074CE72A331777C3478D6513F786A591BD892DA1A577BF2335F9"},
+		// case: matching sha256 integrity
+		{expected: integrity},
+		// case: matching sha512 integrity
+		{expected: "sha512-Dh4h7PEF7IU9JNcohnrXBhPCFmOkaTB0sqNhnBvTnWa1iMM3I7tGbHJCToDjymPCSQeKs0e6uUKFAOfuQwWdDQ=="},
+		// case: mismatching sha256 hex digest
+		{expected: "0000000000000000000000000000000000000000000000000000000000000000", expectedError: ErrIntegrityMismatch},
+		// case: mismatching integrity
+		{expected: "sha384-AAAA", expectedError: ErrIntegrityMismatch},
+		// case: unsupported algorithm
+		{expected: "md5-AAAA", expectedError: ErrUnsupportedIntegrity},
+		// case: malformed digest
+		{expected: "abc", expectedError: ErrUnsupportedIntegrity},
+	}
+
+	for _, tc := range testCases {
+		err := VerifyIntegrity(path, tc.expected)
+		if tc.expectedError != nil {
+			jtest.Require(t, tc.expectedError, err)
+		} else {
+			jtest.RequireNil(t, err)
+		}
+	}
+}
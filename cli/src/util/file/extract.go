@@ -0,0 +1,432 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/luno/jettison/errors"
+)
+
+var (
+	ErrUnknownArchiveFormat = errors.New("unrecognised archive format, expected zip, tar, tar.gz, tar.bz2 or tar.zst")
+	ErrIllegalPath          = errors.New("archive entry escapes the destination directory")
+)
+
+type archiveFormat int
+
+const (
+	formatUnknown archiveFormat = iota
+	formatZip
+	formatTar
+	formatGzip
+	formatBzip2
+	formatZstd
+)
+
+// The header of a tar entry holds its magic at this offset
+const tarMagicOffset = 257
+
+var archiveMagics = []struct {
+	format archiveFormat
+	offset int
+	magic  []byte
+}{
+	{formatZip, 0, []byte("PK\x03\x04")},
+	{formatZip, 0, []byte("PK\x05\x06")},
+	{formatGzip, 0, []byte{0x1f, 0x8b}},
+	{formatBzip2, 0, []byte("BZh")},
+	{formatZstd, 0, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{formatTar, tarMagicOffset, []byte("ustar")},
+}
+
+// ExtractOptions configures how an archive is extracted
+type ExtractOptions struct {
+	// StripTopLevelDir moves the contents of the archive's top-level directory into the
+	// destination when it is the only entry of the archive, as in GitHub release archives
+	StripTopLevelDir bool
+}
+
+// Extract extracts the zip, tar, tar.gz, tar.bz2 or tar.zst archive at source into destination,
+// detecting the format from the content of the archive. Directory trees, file modes, symlinks
+// and hardlinks are preserved, and entries that would be written outside of destination are
+// rejected.
+func Extract(source, destination string, options ExtractOptions) error {
+	destination, err := filepath.Abs(destination)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	err = os.MkdirAll(destination, os.ModePerm)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	if !options.StripTopLevelDir {
+		return extract(source, destination)
+	}
+
+	// Extract next to destination so that the stripped contents can be moved with a rename
+	tmpDir, err := os.MkdirTemp(filepath.Dir(destination), ".extract-*")
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = extract(source, tmpDir)
+	if err != nil {
+		return err
+	}
+
+	root := tmpDir
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	if len(entries) == 1 && entries[0].IsDir() {
+		root = filepath.Join(tmpDir, entries[0].Name())
+	}
+
+	return moveContents(root, destination)
+}
+
+func extract(source, destination string) error {
+	archiveFile, err := os.Open(source)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer archiveFile.Close()
+
+	reader := bufio.NewReader(archiveFile)
+	format, err := detectFormat(reader)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case formatZip:
+		return extractZip(source, destination)
+
+	case formatTar:
+		return extractTar(reader, destination)
+
+	case formatGzip:
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+		defer gzipReader.Close()
+
+		return extractTar(gzipReader, destination)
+
+	case formatBzip2:
+		return extractTar(bzip2.NewReader(reader), destination)
+
+	case formatZstd:
+		zstdReader, err := zstd.NewReader(reader)
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+		defer zstdReader.Close()
+
+		return extractTar(zstdReader, destination)
+	}
+
+	return errors.Wrap(ErrUnknownArchiveFormat, source)
+}
+
+func detectFormat(reader *bufio.Reader) (archiveFormat, error) {
+	header, err := reader.Peek(tarMagicOffset + len("ustar"))
+	if err != nil && err != io.EOF {
+		return formatUnknown, errors.Wrap(err, "")
+	}
+
+	for _, archiveMagic := range archiveMagics {
+		end := archiveMagic.offset + len(archiveMagic.magic)
+		if len(header) >= end && bytes.Equal(header[archiveMagic.offset:end], archiveMagic.magic) {
+			return archiveMagic.format, nil
+		}
+	}
+
+	return formatUnknown, nil
+}
+
+func extractTar(reader io.Reader, destination string) error {
+	tarReader := tar.NewReader(reader)
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.Wrap(err, "")
+		}
+
+		target, err := securePath(destination, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = makeDir(target, header.FileInfo().Mode())
+
+		case tar.TypeReg:
+			err = writeFile(target, tarReader, header.FileInfo().Mode())
+
+		case tar.TypeSymlink:
+			err = writeSymlink(destination, target, header.Linkname)
+
+		case tar.TypeLink:
+			err = writeHardlink(destination, target, header.Linkname)
+
+		default:
+			// Devices, FIFOs and the like have no place in a package
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractZip(source, destination string) error {
+	reader, err := zip.OpenReader(source)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer reader.Close()
+
+	for _, f := range reader.File {
+		err := unzipFile(f, destination)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// securePath joins name onto destination, rejecting names that escape destination either
+// lexically or through a symlink extracted earlier
+func securePath(destination, name string) (string, error) {
+	target := filepath.Join(destination, name)
+	if !within(destination, target) {
+		return "", errors.Wrap(ErrIllegalPath, name)
+	}
+
+	// Resolve the deepest existing parent, as the rest of the path will be created as directories
+	parent := filepath.Dir(target)
+	for {
+		if _, err := os.Lstat(parent); err == nil {
+			break
+		}
+		parent = filepath.Dir(parent)
+	}
+
+	resolvedParent, err := filepath.EvalSymlinks(parent)
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	resolvedDestination, err := filepath.EvalSymlinks(destination)
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+	if !within(resolvedDestination, resolvedParent) {
+		return "", errors.Wrap(ErrIllegalPath, name)
+	}
+
+	return target, nil
+}
+
+// within reports whether path is dir or inside of it
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
+
+func makeDir(target string, mode os.FileMode) error {
+	// Keep directories writable by the owner so that their entries can be extracted
+	err := os.MkdirAll(target, mode.Perm()|0700)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	err = os.Chmod(target, mode.Perm()|0700)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	return nil
+}
+
+func writeFile(target string, content io.Reader, mode os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(target), os.ModePerm)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	// Never write through an existing symlink
+	err = removeExisting(target)
+	if err != nil {
+		return err
+	}
+
+	destinationFile, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	defer destinationFile.Close()
+
+	_, err = io.Copy(destinationFile, content)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	// The mode passed to OpenFile is subject to the umask
+	err = destinationFile.Chmod(mode.Perm())
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	return nil
+}
+
+// writeSymlink creates a symlink at target, rejecting absolute links and links that point
+// outside of destination
+func writeSymlink(destination, target, linkname string) error {
+	if filepath.IsAbs(linkname) {
+		return errors.Wrap(ErrIllegalPath, target+" -> "+linkname)
+	}
+
+	err := os.MkdirAll(filepath.Dir(target), os.ModePerm)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	err = checkLink(destination, target, linkname)
+	if err != nil {
+		return err
+	}
+
+	err = removeExisting(target)
+	if err != nil {
+		return err
+	}
+
+	err = os.Symlink(linkname, target)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	return nil
+}
+
+// checkLink rejects a symlink at target to linkname that resolves outside of destination. The
+// leading ".." of linkname are resolved from the real directory of target, and a ".." after any
+// other element is rejected, as that element may be a symlink extracted earlier or later, eg.
+// s -> . followed by p -> s/.. points p at the parent of destination.
+func checkLink(destination, target, linkname string) error {
+	dir, err := filepath.EvalSymlinks(filepath.Dir(target))
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+	resolvedDestination, err := filepath.EvalSymlinks(destination)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	descended := false
+	for _, element := range strings.Split(filepath.ToSlash(linkname), "/") {
+		switch {
+		case element == "" || element == ".":
+		case element == "..":
+			if descended {
+				return errors.Wrap(ErrIllegalPath, target+" -> "+linkname)
+			}
+			dir = filepath.Dir(dir)
+		default:
+			descended = true
+		}
+	}
+
+	if !within(resolvedDestination, dir) {
+		return errors.Wrap(ErrIllegalPath, target+" -> "+linkname)
+	}
+
+	return nil
+}
+
+// writeHardlink links target to the previously extracted entry linkname
+func writeHardlink(destination, target, linkname string) error {
+	source, err := securePath(destination, linkname)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(target), os.ModePerm)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	err = removeExisting(target)
+	if err != nil {
+		return err
+	}
+
+	err = os.Link(source, target)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	return nil
+}
+
+func removeExisting(target string) error {
+	info, err := os.Lstat(target)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	if info.IsDir() {
+		return nil
+	}
+
+	err = os.Remove(target)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	return nil
+}
+
+// moveContents renames every entry of source into destination
+func moveContents(source, destination string) error {
+	entries, err := os.ReadDir(source)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	for _, entry := range entries {
+		err = os.Rename(filepath.Join(source, entry.Name()), filepath.Join(destination, entry.Name()))
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,288 @@
+package file
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/luno/jettison/jtest"
+	"github.com/stretchr/testify/require"
+)
+
+type testEntry struct {
+	name     string
+	content  string
+	linkname string
+	typeflag byte
+	mode     int64
+}
+
+var packageEntries = []testEntry{
+	{name: "package/", typeflag: tar.TypeDir, mode: 0755},
+	{name: "package/package-metadata.json", content: "{}", typeflag: tar.TypeReg, mode: 0644},
+	{name: "package/swarm.sh", content: "#!/bin/bash", typeflag: tar.TypeReg, mode: 0755},
+	{name: "package/docker/compose.yml", content: "services: {}", typeflag: tar.TypeReg, mode: 0600},
+	{name: "package/compose.yml", linkname: "docker/compose.yml", typeflag: tar.TypeSymlink, mode: 0777},
+	{name: "package/swarm-link.sh", linkname: "package/swarm.sh", typeflag: tar.TypeLink, mode: 0755},
+}
+
+func writeTestTar(t *testing.T, w io.Writer, entries []testEntry) {
+	tarWriter := tar.NewWriter(w)
+	for _, entry := range entries {
+		err := tarWriter.WriteHeader(&tar.Header{
+			Name:     entry.name,
+			Linkname: entry.linkname,
+			Typeflag: entry.typeflag,
+			Mode:     entry.mode,
+			Size:     int64(len(entry.content)),
+		})
+		jtest.RequireNil(t, err)
+
+		_, err = tarWriter.Write([]byte(entry.content))
+		jtest.RequireNil(t, err)
+	}
+	jtest.RequireNil(t, tarWriter.Close())
+}
+
+func createTestArchive(t *testing.T, format archiveFormat, entries []testEntry) string {
+	var buf bytes.Buffer
+
+	switch format {
+	case formatTar:
+		writeTestTar(t, &buf, entries)
+
+	case formatGzip:
+		gzipWriter := gzip.NewWriter(&buf)
+		writeTestTar(t, gzipWriter, entries)
+		jtest.RequireNil(t, gzipWriter.Close())
+
+	case formatZstd:
+		zstdWriter, err := zstd.NewWriter(&buf)
+		jtest.RequireNil(t, err)
+		writeTestTar(t, zstdWriter, entries)
+		jtest.RequireNil(t, zstdWriter.Close())
+
+	case formatZip:
+		zipWriter := zip.NewWriter(&buf)
+		for _, entry := range entries {
+			if entry.typeflag == tar.TypeLink {
+				continue
+			}
+
+			header := &zip.FileHeader{Name: entry.name}
+			mode := os.FileMode(entry.mode)
+			switch entry.typeflag {
+			case tar.TypeDir:
+				mode |= os.ModeDir
+			case tar.TypeSymlink:
+				mode |= os.ModeSymlink
+				entry.content = entry.linkname
+			}
+			header.SetMode(mode)
+
+			writer, err := zipWriter.CreateHeader(header)
+			jtest.RequireNil(t, err)
+			_, err = writer.Write([]byte(entry.content))
+			jtest.RequireNil(t, err)
+		}
+		jtest.RequireNil(t, zipWriter.Close())
+
+	default:
+		buf.WriteString("not an archive")
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "archive")
+	err := os.WriteFile(archivePath, buf.Bytes(), 0644)
+	jtest.RequireNil(t, err)
+
+	return archivePath
+}
+
+func TestExtract(t *testing.T) {
+	type cases struct {
+		format              archiveFormat
+		entries             []testEntry
+		options             ExtractOptions
+		expectedFiles       map[string]os.FileMode
+		expectedSymlinks    map[string]string
+		expectedErrorString string
+	}
+
+	testCases := []cases{
+		// case: tar archive
+		{
+			format:  formatTar,
+			entries: packageEntries,
+			expectedFiles: map[string]os.FileMode{
+				"package/package-metadata.json": 0644,
+				"package/swarm.sh":              0755,
+				"package/swarm-link.sh":         0755,
+				"package/docker/compose.yml":    0600,
+			},
+			expectedSymlinks: map[string]string{"package/compose.yml": "docker/compose.yml"},
+		},
+		// case: gzip compressed tar archive with the top-level directory stripped
+		{
+			format:  formatGzip,
+			entries: packageEntries,
+			options: ExtractOptions{StripTopLevelDir: true},
+			expectedFiles: map[string]os.FileMode{
+				"package-metadata.json": 0644,
+				"swarm.sh":              0755,
+				"swarm-link.sh":         0755,
+				"docker/compose.yml":    0600,
+			},
+			expectedSymlinks: map[string]string{"compose.yml": "docker/compose.yml"},
+		},
+		// case: zstd compressed tar archive
+		{
+			format:        formatZstd,
+			entries:       packageEntries,
+			expectedFiles: map[string]os.FileMode{"package/swarm.sh": 0755},
+		},
+		// case: zip archive
+		{
+			format:  formatZip,
+			entries: packageEntries,
+			options: ExtractOptions{StripTopLevelDir: true},
+			expectedFiles: map[string]os.FileMode{
+				"swarm.sh":           0755,
+				"docker/compose.yml": 0600,
+			},
+			expectedSymlinks: map[string]string{"compose.yml": "docker/compose.yml"},
+		},
+		// case: several top-level entries are not stripped
+		{
+			format: formatTar,
+			entries: []testEntry{
+				{name: "package/swarm.sh", typeflag: tar.TypeReg, mode: 0755},
+				{name: "README.md", typeflag: tar.TypeReg, mode: 0644},
+			},
+			options: ExtractOptions{StripTopLevelDir: true},
+			expectedFiles: map[string]os.FileMode{
+				"package/swarm.sh": 0755,
+				"README.md":        0644,
+			},
+		},
+		// case: reject path traversal
+		{
+			format:              formatTar,
+			entries:             []testEntry{{name: "../evil.sh", typeflag: tar.TypeReg, mode: 0755}},
+			expectedErrorString: ErrIllegalPath.Error(),
+		},
+		// case: reject path traversal in zip archives
+		{
+			format:              formatZip,
+			entries:             []testEntry{{name: "../evil.sh", typeflag: tar.TypeReg, mode: 0755}},
+			expectedErrorString: ErrIllegalPath.Error(),
+		},
+		// case: reject absolute symlinks
+		{
+			format:              formatTar,
+			entries:             []testEntry{{name: "passwd", linkname: "/etc/passwd", typeflag: tar.TypeSymlink}},
+			expectedErrorString: ErrIllegalPath.Error(),
+		},
+		// case: reject symlinks out of the destination
+		{
+			format:              formatTar,
+			entries:             []testEntry{{name: "package/parent", linkname: "../..", typeflag: tar.TypeSymlink}},
+			expectedErrorString: ErrIllegalPath.Error(),
+		},
+		// case: reject symlinks out of the destination through a symlink extracted earlier
+		{
+			format: formatTar,
+			entries: []testEntry{
+				{name: "s", linkname: ".", typeflag: tar.TypeSymlink},
+				{name: "p", linkname: "s/..", typeflag: tar.TypeSymlink},
+			},
+			expectedErrorString: ErrIllegalPath.Error(),
+		},
+		// case: reject symlinks out of the destination through a symlink extracted later
+		{
+			format: formatTar,
+			entries: []testEntry{
+				{name: "p", linkname: "s/..", typeflag: tar.TypeSymlink},
+				{name: "s", linkname: ".", typeflag: tar.TypeSymlink},
+			},
+			expectedErrorString: ErrIllegalPath.Error(),
+		},
+		// case: reject symlinks out of the destination in zip archives
+		{
+			format: formatZip,
+			entries: []testEntry{
+				{name: "s", linkname: ".", typeflag: tar.TypeSymlink},
+				{name: "p", linkname: "s/..", typeflag: tar.TypeSymlink},
+			},
+			expectedErrorString: ErrIllegalPath.Error(),
+		},
+		// case: symlinks up and out of their directory within the destination
+		{
+			format: formatTar,
+			entries: []testEntry{
+				{name: "package/common/env.sh", typeflag: tar.TypeReg, mode: 0644},
+				{name: "package/app/env.sh", linkname: "../common/env.sh", typeflag: tar.TypeSymlink},
+			},
+			expectedSymlinks: map[string]string{"package/app/env.sh": "../common/env.sh"},
+		},
+		// case: reject hardlinks out of the destination
+		{
+			format:              formatTar,
+			entries:             []testEntry{{name: "passwd", linkname: "../../etc/passwd", typeflag: tar.TypeLink}},
+			expectedErrorString: ErrIllegalPath.Error(),
+		},
+		// case: unknown archive format
+		{
+			format:              formatUnknown,
+			expectedErrorString: ErrUnknownArchiveFormat.Error(),
+		},
+	}
+
+	for _, tc := range testCases {
+		archivePath := createTestArchive(t, tc.format, tc.entries)
+		destination := filepath.Join(t.TempDir(), "destination")
+
+		err := Extract(archivePath, destination, tc.options)
+		if tc.expectedErrorString != "" {
+			require.ErrorContains(t, err, tc.expectedErrorString)
+			continue
+		}
+		jtest.RequireNil(t, err)
+
+		for name, mode := range tc.expectedFiles {
+			info, err := os.Stat(filepath.Join(destination, name))
+			jtest.RequireNil(t, err)
+			require.Equal(t, mode, info.Mode().Perm(), name)
+		}
+
+		for name, linkname := range tc.expectedSymlinks {
+			target, err := os.Readlink(filepath.Join(destination, name))
+			jtest.RequireNil(t, err)
+			require.Equal(t, linkname, target)
+		}
+	}
+}
+
+func TestExtract_symlinkTraversal(t *testing.T) {
+	destination := t.TempDir()
+	outside := t.TempDir()
+
+	// A symlink planted in the destination must not be written through
+	err := os.Symlink(outside, filepath.Join(destination, "link"))
+	jtest.RequireNil(t, err)
+
+	archivePath := createTestArchive(t, formatTar, []testEntry{
+		{name: "link/evil.sh", content: "evil", typeflag: tar.TypeReg, mode: 0755},
+	})
+
+	err = Extract(archivePath, destination, ExtractOptions{})
+	require.ErrorContains(t, err, ErrIllegalPath.Error())
+
+	_, err = os.Stat(filepath.Join(outside, "evil.sh"))
+	require.True(t, os.IsNotExist(err))
+}
@@ -2,9 +2,9 @@ package file
 
 import (
 	"archive/tar"
-	"bufio"
 	"io/fs"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/luno/jettison/errors"
@@ -38,7 +38,7 @@ func createTestTarFile(t *testing.T, tarFileName string, contentFile *os.File) *
 	return tarFile
 }
 
-func Test_untarSource(t *testing.T) {
+func TestExtract_tarFile(t *testing.T) {
 	type cases struct {
 		source          string
 		destination     string
@@ -46,23 +46,18 @@ func Test_untarSource(t *testing.T) {
 	}
 
 	testCases := []cases{
-		// case: untar file into current directory
+		// case: untar file into a directory
 		{
 			source:          "test_tar.tar",
-			destination:     "test.txt",
+			destination:     "untarred",
 			contentFileName: "test.txt",
 		},
 		// case: return error from not specifying source file
 		{},
-		// case: return error from not specifying destination file
-		{
-			source:          "test_tar.tar",
-			contentFileName: "test.txt",
-		},
 		// case: untar file into nested directory,
 		{
 			source:          "test_tar.tar",
-			destination:     "./testDir/test.txt",
+			destination:     "./testDir/untarred",
 			contentFileName: "test.txt",
 		},
 	}
@@ -71,6 +66,7 @@ func Test_untarSource(t *testing.T) {
 		// Ensure removal on panic
 		defer os.RemoveAll(tc.source)
 		defer os.RemoveAll(tc.destination)
+		defer os.RemoveAll(tc.contentFileName)
 
 		var contentFile, tarFile *os.File
 		if tc.source != "" {
@@ -78,7 +74,7 @@ func Test_untarSource(t *testing.T) {
 			tarFile = createTestTarFile(t, tc.source, contentFile)
 		}
 
-		err := UntarSource(tc.source, tc.destination)
+		err := Extract(tc.source, tc.destination, ExtractOptions{})
 		if err != nil {
 			expectedErr := fs.PathError{
 				Op:  "open",
@@ -87,8 +83,9 @@ func Test_untarSource(t *testing.T) {
 
 			require.Equal(t, errors.New(expectedErr.Error()).Error(), err.Error())
 		} else {
-			_, err = os.Stat(tc.destination)
+			data, err := os.ReadFile(filepath.Join(tc.destination, tc.contentFileName))
 			jtest.RequireNil(t, err)
+			require.Equal(t, "test data", string(data))
 		}
 		contentFile.Close()
 		tarFile.Close()
@@ -96,6 +93,7 @@ func Test_untarSource(t *testing.T) {
 		// Ensure removal per test case
 		os.RemoveAll(tc.source)
 		os.RemoveAll(tc.destination)
+		os.RemoveAll(tc.contentFileName)
 	}
 
 	os.RemoveAll("testDir")
@@ -120,7 +118,7 @@ func Test_tarSource(t *testing.T) {
 	for _, tc := range testCases {
 		defer os.Remove(tc.contentFileName)
 		defer os.Remove(tc.source)
-		defer os.Remove("untarred")
+		defer os.RemoveAll("untarred")
 
 		if tc.contentFileName != "" {
 			testFile := createTestFile(t, tc.contentFileName)
@@ -146,19 +144,16 @@ func Test_tarSource(t *testing.T) {
 			_, err = tarFile.ReadFrom(re)
 			jtest.RequireNil(t, err)
 
-			untarFile := createTestFile(t, "untarred")
-			err = UntarSource(tc.source, "untarred")
+			err = Extract(tc.source, "untarred", ExtractOptions{})
 			jtest.RequireNil(t, err)
 
-			scanner := bufio.NewScanner(untarFile)
-			scanner.Scan()
-			if scanner.Text() != "test data" {
-				t.FailNow()
-			}
+			data, err := os.ReadFile(filepath.Join("untarred", tc.contentFileName))
+			jtest.RequireNil(t, err)
+			require.Equal(t, "test data", string(data))
 		}
 
 		os.Remove(tc.contentFileName)
-		os.Remove("untarred")
+		os.RemoveAll("untarred")
 		os.Remove(tc.source)
 	}
 }
@@ -12,7 +12,7 @@ import (
 	"golang.org/x/sys/unix"
 )
 
-func TestUnzipSource(t *testing.T) {
+func TestExtract_zipFile(t *testing.T) {
 	type cases struct {
 		source          string
 		destination     string
@@ -44,7 +44,7 @@ func TestUnzipSource(t *testing.T) {
 		defer os.RemoveAll(tc.destination)
 		defer os.RemoveAll("testDir")
 
-		err := UnzipSource(tc.source, tc.destination)
+		err := Extract(tc.source, tc.destination, ExtractOptions{})
 		if err != nil {
 			expectedErr := fs.PathError{
 				Op:   "open",
@@ -1,19 +1,201 @@
 package git
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/luno/jettison/errors"
 )
 
-func CloneRepo(url, dest string) error {
+var ErrUnknownRef = errors.New("ref is not a branch, tag or commit in the repository")
+
+// Auth holds the credentials used to access a private repository. SSH URLs without a key
+// fall back to the SSH agent.
+type Auth struct {
+	SSHKeyPath       string
+	SSHKeyPassphrase string
+	Username         string
+	Token            string
+}
+
+func (a Auth) method(url string) (transport.AuthMethod, error) {
+	switch {
+	case a.SSHKeyPath != "":
+		keyPath := a.SSHKeyPath
+		if strings.HasPrefix(keyPath, "~/") {
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				return nil, errors.Wrap(err, "")
+			}
+			keyPath = filepath.Join(homeDir, keyPath[2:])
+		}
+
+		user := "git"
+		if endpoint, err := transport.NewEndpoint(url); err == nil && endpoint.User != "" {
+			user = endpoint.User
+		}
+
+		publicKeys, err := ssh.NewPublicKeysFromFile(user, keyPath, a.SSHKeyPassphrase)
+		if err != nil {
+			return nil, errors.Wrap(err, "")
+		}
+
+		return publicKeys, nil
+
+	case a.Token != "":
+		username := a.Username
+		if username == "" {
+			username = "x-access-token"
+		}
+
+		return &http.BasicAuth{Username: username, Password: a.Token}, nil
+	}
+
+	return nil, nil
+}
+
+// Clone clones url into dest and checks out ref, which may be a branch, tag or commit. The
+// default branch is checked out if ref is empty.
+func Clone(url, dest, ref string, auth Auth) error {
+	authMethod, err := auth.method(url)
+	if err != nil {
+		return err
+	}
+
 	cloneOptions := &git.CloneOptions{
-		URL: url,
+		URL:  url,
+		Auth: authMethod,
+	}
+
+	repo, err := git.PlainClone(dest, false, cloneOptions)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	if ref != "" {
+		return checkout(repo, ref)
+	}
+
+	return nil
+}
+
+// UpdateRepo fetches the latest changes from origin into the clone at dest, and hard resets the
+// worktree to ref, or to the remote tracking branch of the checked out branch if ref is empty
+func UpdateRepo(dest, ref string, auth Auth) error {
+	repo, err := git.PlainOpen(dest)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	// A commit can't move, so there is nothing to fetch if it is already checked out
+	if plumbing.IsHash(ref) {
+		head, err := repo.Head()
+		if err == nil && head.Hash().String() == ref {
+			return nil
+		}
+	}
+
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	authMethod, err := auth.method(remote.Config().URLs[0])
+	if err != nil {
+		return err
+	}
+
+	err = repo.Fetch(&git.FetchOptions{
+		RemoteName: git.DefaultRemoteName,
+		Auth:       authMethod,
+		Tags:       git.AllTags,
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return errors.Wrap(err, "")
+	}
+
+	if ref != "" {
+		return checkout(repo, ref)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, head.Name().Short()), true)
+	if err != nil {
+		return errors.Wrap(err, "")
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return errors.Wrap(err, "")
 	}
 
-	_, err := git.PlainClone(dest, false, cloneOptions)
+	err = worktree.Reset(&git.ResetOptions{
+		Commit: remoteRef.Hash(),
+		Mode:   git.HardReset,
+	})
 	if err != nil {
 		return errors.Wrap(err, "")
 	}
 
 	return nil
 }
+
+// HeadCommit returns the hash of the commit checked out in the repository at dest
+func HeadCommit(dest string) (string, error) {
+	repo, err := git.PlainOpen(dest)
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return "", errors.Wrap(err, "")
+	}
+
+	return head.Hash().String(), nil
+}
+
+// checkout detaches the worktree at ref, resolving it as a remote branch, then a tag, then a
+// (possibly abbreviated) commit
+func checkout(repo *git.Repository, ref string) error {
+	candidates := []plumbing.Revision{
+		plumbing.Revision(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, ref)),
+		plumbing.Revision(plumbing.NewTagReferenceName(ref)),
+		plumbing.Revision(ref),
+	}
+
+	for _, candidate := range candidates {
+		hash, err := repo.ResolveRevision(candidate)
+		if err != nil {
+			continue
+		}
+
+		worktree, err := repo.Worktree()
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+
+		err = worktree.Checkout(&git.CheckoutOptions{
+			Hash:  *hash,
+			Force: true,
+		})
+		if err != nil {
+			return errors.Wrap(err, "")
+		}
+
+		return nil
+	}
+
+	return errors.Wrap(ErrUnknownRef, ref)
+}
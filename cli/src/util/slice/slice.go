@@ -1,7 +1,5 @@
 package slice
 
-import "strings"
-
 func SliceContains[Type comparable](slice []Type, element Type) bool {
 	for _, s := range slice {
 		if element == s {
@@ -11,15 +9,3 @@ func SliceContains[Type comparable](slice []Type, element Type) bool {
 
 	return false
 }
-
-func AppendUniqueToMapFromSlice(m map[string]string, sl []string) map[string]string {
-	for _, s := range sl {
-		splitEnvVar := strings.SplitAfterN(s, "=", 2)
-
-		if _, ok := m[splitEnvVar[0]]; !ok {
-			m[splitEnvVar[0]] = splitEnvVar[1]
-		}
-	}
-
-	return m
-}
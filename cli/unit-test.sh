@@ -14,8 +14,44 @@ go test .
 cd "$FILE_PATH"/src/core/deploy || exit
 go test .
 
+cd "$FILE_PATH"/src/core/cache || exit
+go test .
+
 cd "$FILE_PATH"/src/util/file || exit
 go test .
 
 cd "$FILE_PATH"/src/core/generate || exit
 go test .
+
+cd "$FILE_PATH"/src/core/exitcode || exit
+go test .
+
+cd "$FILE_PATH"/src/util/docker || exit
+go test .
+
+cd "$FILE_PATH"/src/core/dependency || exit
+go test .
+
+cd "$FILE_PATH"/src/core/schema || exit
+go test .
+
+cd "$FILE_PATH"/src/core/configfile || exit
+go test .
+
+cd "$FILE_PATH"/src/core/interpolate || exit
+go test .
+
+cd "$FILE_PATH"/src/core/env || exit
+go test .
+
+cd "$FILE_PATH"/src/core/state || exit
+go test .
+
+cd "$FILE_PATH"/src/core/workspace || exit
+go test .
+
+cd "$FILE_PATH"/src/core/status || exit
+go test .
+
+cd "$FILE_PATH"/src/core/drift || exit
+go test .
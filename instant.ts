@@ -392,6 +392,7 @@ const main = async () => {
 
     if (error) {
       console.log('\n❌ Some scripts returned errors')
+      process.exitCode = 1
     } else {
       console.log('\n🟢 Success!')
     }